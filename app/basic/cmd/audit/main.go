@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/ardanlabs/smartcontract/business/basic/audit"
+	"github.com/ardanlabs/smartcontract/business/basic/config"
+	"github.com/ardanlabs/smartcontract/business/basic/registry"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func main() {
+	block := flag.Uint64("block", 0, "block to audit, zero for the current head")
+	deployBlock := flag.Uint64("deploy-block", 0, "block the contract was deployed in, zero for the registry's block")
+	keys := flag.String("keys", "", "comma separated keys to check even without events")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	cfg := audit.Config{
+		DeployBlock: *deployBlock,
+	}
+
+	if *keys != "" {
+		cfg.Keys = strings.Split(*keys, ",")
+	}
+
+	consistent, err := run(cfg, *block, *asJSON)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if !consistent {
+		os.Exit(2)
+	}
+}
+
+func run(cfg audit.Config, block uint64, asJSON bool) (bool, error) {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cfg.Log = log.NewLogger(log.NewTerminalHandlerWithLevel(os.Stderr, log.LevelInfo, true))
+
+	settings, err := config.Load(config.Options{File: config.DefaultFile, FileOptional: true})
+	if err != nil {
+		return false, err
+	}
+
+	backend, err := settings.Dial(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer backend.Close()
+
+	// =========================================================================
+
+	reg, err := registry.Open(settings.Registry)
+	if err != nil {
+		return false, err
+	}
+
+	deployment, err := reg.Latest(backend.ChainID(), registry.ContractBasic)
+	if err != nil {
+		return false, err
+	}
+	contractID := deployment.Address.Hex()
+
+	if cfg.DeployBlock == 0 {
+		cfg.DeployBlock = deployment.Block
+	}
+
+	if block == 0 {
+		header, err := backend.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return false, fmt.Errorf("head block: %w", err)
+		}
+		block = header.Number.Uint64()
+	}
+
+	a, err := audit.New(backend, common.HexToAddress(contractID), cfg)
+	if err != nil {
+		return false, err
+	}
+
+	report, err := a.Audit(ctx, block)
+	if err != nil {
+		return false, err
+	}
+
+	// =========================================================================
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return report.Consistent(), enc.Encode(report)
+	}
+
+	fmt.Println("\nAudit Report")
+	fmt.Println("----------------------------------------------------")
+	fmt.Println("contractID :", contractID)
+	fmt.Println("block      :", report.Block)
+	fmt.Println("events     :", report.Events)
+	fmt.Println("keys       :", report.Keys)
+	fmt.Println("divergences:", len(report.Divergences))
+
+	for _, div := range report.Divergences {
+		fmt.Printf("\nKey %q\n", div.Key)
+		fmt.Println("----------------------------------------------------")
+		fmt.Println("replayed:", div.Replayed)
+		fmt.Println("on chain:", div.OnChain)
+
+		for _, change := range div.History {
+			fmt.Printf("event   : block[%d] tx[%s] value[%v]\n", change.Block, change.TxHash.Hex(), change.Value)
+		}
+
+		for _, hash := range div.Transactions {
+			fmt.Println("missed  :", hash.Hex())
+		}
+	}
+
+	return report.Consistent(), nil
+}
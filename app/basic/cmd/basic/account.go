@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	geth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/urfave/cli/v2"
+)
+
+// balance represents the balance of an account for JSON output.
+type balance struct {
+	Address common.Address `json:"address"`
+	Wei     string         `json:"wei"`
+	GWei    string         `json:"gwei"`
+	USD     string         `json:"usd"`
+}
+
+// transaction represents a transaction and its receipt for JSON output.
+type transaction struct {
+	Status      string                      `json:"status"`
+	From        common.Address              `json:"from"`
+	To          *common.Address             `json:"to"`
+	Transaction currency.TransactionDetails `json:"transaction"`
+	Receipt     *currency.ReceiptDetails    `json:"receipt,omitempty"`
+	BlockNumber uint64                      `json:"block_number,omitempty"`
+	Logs        []currency.LogData          `json:"logs,omitempty"`
+}
+
+// =============================================================================
+
+func balanceCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "balance",
+		Usage:     "show the balance of an account, the keystore account by default",
+		ArgsUsage: "[ADDRESS]",
+		Action:    showBalance,
+	}
+}
+
+func txCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "tx",
+		Usage:     "show a transaction and its receipt",
+		ArgsUsage: "HASH",
+		Action:    showTx,
+	}
+}
+
+// =============================================================================
+
+func showBalance(c *cli.Context) error {
+	if c.NArg() > 1 {
+		return errors.New("expected at most one ADDRESS")
+	}
+
+	backend, err := dial(c)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	var address common.Address
+	switch c.NArg() {
+	case 0:
+		clt, err := client(c, backend)
+		if err != nil {
+			return err
+		}
+		address = clt.Address()
+
+	default:
+		if !common.IsHexAddress(c.Args().First()) {
+			return fmt.Errorf("invalid address %q", c.Args().First())
+		}
+		address = common.HexToAddress(c.Args().First())
+	}
+
+	wei, err := backend.BalanceAt(c.Context, address, nil)
+	if err != nil {
+		return fmt.Errorf("balance: %w", err)
+	}
+
+	b := balance{
+		Address: address,
+		Wei:     wei.String(),
+		GWei:    currency.Wei2GWei(wei).Text('f', 9),
+		USD:     converter(c).Wei2USD(wei),
+	}
+
+	if jsonOutput(c) {
+		return printJSON(b)
+	}
+
+	fmt.Println("\nBalance")
+	fmt.Println("----------------------------------------------------")
+	fmt.Println("address:", b.Address)
+	fmt.Println("wei    :", b.Wei)
+	fmt.Println("gwei   :", b.GWei)
+	fmt.Println("usd    :", b.USD)
+
+	return nil
+}
+
+func showTx(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("expected a single HASH")
+	}
+
+	hash := common.HexToHash(c.Args().First())
+	if len(c.Args().First()) != 66 || hash == (common.Hash{}) {
+		return fmt.Errorf("invalid transaction hash %q", c.Args().First())
+	}
+
+	backend, err := dial(c)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	tx, pending, err := backend.TransactionByHash(c.Context, hash)
+	if err != nil {
+		if errors.Is(err, geth.NotFound) {
+			return fmt.Errorf("transaction %s not found", hash.Hex())
+		}
+		return fmt.Errorf("transaction by hash: %w", err)
+	}
+
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+
+	converter := converter(c)
+
+	t := transaction{
+		Status:      "pending",
+		From:        from,
+		To:          tx.To(),
+		Transaction: converter.CalculateTransactionDetails(tx),
+	}
+
+	var receipt *types.Receipt
+	if !pending {
+		receipt, err = backend.TransactionReceipt(c.Context, hash)
+		if err != nil {
+			return fmt.Errorf("transaction receipt: %w", err)
+		}
+
+		gasPrice := receipt.EffectiveGasPrice
+		if gasPrice == nil {
+			gasPrice = tx.GasPrice()
+		}
+
+		details := converter.CalculateReceiptDetails(receipt, gasPrice)
+		t.Receipt = &details
+		t.BlockNumber = receipt.BlockNumber.Uint64()
+
+		t.Status = "success"
+		if receipt.Status == types.ReceiptStatusFailed {
+			t.Status = "failed"
+		}
+
+		// Logs from other contracts can't be decoded with the basic ABI.
+		if logs, err := currency.ExtractLogData(basic.BasicMetaData.ABI, receipt); err == nil {
+			t.Logs = logs
+		}
+	}
+
+	if jsonOutput(c) {
+		return printJSON(t)
+	}
+
+	fmt.Println("\nTransaction")
+	fmt.Println("----------------------------------------------------")
+	fmt.Println("status     :", t.Status)
+	fmt.Println("from       :", t.From)
+	if t.To != nil {
+		fmt.Println("to         :", t.To.Hex())
+	}
+	fmt.Print(converter.FmtTransaction(tx))
+
+	if receipt != nil {
+		fmt.Println("block      :", t.BlockNumber)
+		fmt.Print(converter.FmtTransactionReceipt(receipt, tx.GasPrice()))
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ardanlabs/smartcontract/business/basic/historical"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/urfave/cli/v2"
+)
+
+// blockFlags are the flags that select the block a read is made at. Only one
+// of them may be used at a time.
+func blockFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.Uint64Flag{
+			Name:  "block",
+			Usage: "block number to read at, the latest block when not set",
+		},
+		&cli.StringFlag{
+			Name:  "block-hash",
+			Usage: "hash of the block to read at",
+		},
+		&cli.StringFlag{
+			Name:  "at",
+			Usage: "read at the last block mined at or before a time, RFC3339 or unix seconds",
+		},
+	}
+}
+
+// resolveBlock returns the header of the block selected by the block flags.
+func resolveBlock(c *cli.Context, r *historical.Reader) (*types.Header, error) {
+	var set int
+	for _, name := range []string{"block", "block-hash", "at"} {
+		if c.IsSet(name) {
+			set++
+		}
+	}
+
+	if set > 1 {
+		return nil, errors.New("use only one of block, block-hash and at")
+	}
+
+	switch {
+	case c.IsSet("block"):
+		return r.BlockByNumber(c.Context, c.Uint64("block"))
+
+	case c.IsSet("block-hash"):
+		hash, err := hexutil.Decode(c.String("block-hash"))
+		if err != nil || len(hash) != common.HashLength {
+			return nil, fmt.Errorf("invalid block hash %q", c.String("block-hash"))
+		}
+		return r.BlockByHash(c.Context, common.BytesToHash(hash))
+
+	case c.IsSet("at"):
+		t, err := parseTime(c.String("at"))
+		if err != nil {
+			return nil, err
+		}
+		return r.BlockByTime(c.Context, t)
+	}
+
+	return r.Head(c.Context)
+}
+
+// parseTime accepts a time as RFC3339 or as unix seconds.
+func parseTime(s string) (time.Time, error) {
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected RFC3339 or unix seconds", s)
+	}
+
+	return t, nil
+}
+
+// =============================================================================
+
+func versionCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "version",
+		Usage:  "read the version of the contract",
+		Flags:  blockFlags(),
+		Action: version,
+	}
+}
+
+// contractVersion represents the version read at a block for JSON output.
+type contractVersion struct {
+	Version string      `json:"version"`
+	Block   uint64      `json:"block"`
+	Hash    common.Hash `json:"hash"`
+	Time    uint64      `json:"time"`
+}
+
+func version(c *cli.Context) error {
+	backend, err := dial(c)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	id, err := contractID(c, backend)
+	if err != nil {
+		return err
+	}
+
+	r, err := historical.New(backend, id)
+	if err != nil {
+		return err
+	}
+
+	header, err := resolveBlock(c, r)
+	if err != nil {
+		return err
+	}
+
+	v, err := r.Version(c.Context, header)
+	if err != nil {
+		return err
+	}
+
+	cv := contractVersion{
+		Version: v,
+		Block:   header.Number.Uint64(),
+		Hash:    header.Hash(),
+		Time:    header.Time,
+	}
+
+	if jsonOutput(c) {
+		return printJSON(cv)
+	}
+
+	fmt.Println("\nContract Version")
+	fmt.Println("----------------------------------------------------")
+	fmt.Println("version:", cv.Version)
+	printBlock(header)
+
+	return nil
+}
+
+// printBlock prints the block a read was made at.
+func printBlock(header *types.Header) {
+	fmt.Printf("block  : %d %s (%s)\n", header.Number, header.Hash().Hex(), time.Unix(int64(header.Time), 0).UTC().Format(time.RFC3339))
+}
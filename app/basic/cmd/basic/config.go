@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ardanlabs/smartcontract/business/basic/config"
+	"github.com/urfave/cli/v2"
+)
+
+func configCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "config",
+		Usage:  "show the effective settings with secrets redacted",
+		Action: showConfig,
+	}
+}
+
+func showConfig(c *cli.Context) error {
+	cfg := settings(c)
+	file, _ := c.App.Metadata["configFile"].(string)
+
+	if jsonOutput(c) {
+		doc := struct {
+			Profile  string           `json:"profile"`
+			File     string           `json:"file"`
+			Settings []config.Setting `json:"settings"`
+		}{
+			Profile:  cfg.Profile,
+			File:     file,
+			Settings: cfg.Redacted(),
+		}
+		return printJSON(doc)
+	}
+
+	if file == "" {
+		file = "none"
+	}
+
+	fmt.Println("\nEffective Config")
+	fmt.Println("----------------------------------------------------")
+	fmt.Println("profile:", cfg.Profile)
+	fmt.Println("file   :", file)
+	fmt.Println()
+	for _, s := range cfg.Redacted() {
+		fmt.Printf("%-22s: %-45s (%s, %s)\n", s.Name, s.Value, s.Source, s.Env)
+	}
+
+	return nil
+}
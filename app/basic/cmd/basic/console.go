@@ -0,0 +1,76 @@
+package main
+
+import (
+	"math/big"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/business/basic/console"
+	"github.com/urfave/cli/v2"
+)
+
+func consoleCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "console",
+		Usage: "open an interactive console for the contract",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "history",
+				Value: defaultHistoryFile(),
+				Usage: "file the command history is kept in, empty to not keep one",
+			},
+		},
+		Action: openConsole,
+	}
+}
+
+func openConsole(c *cli.Context) error {
+	backend, err := dial(c)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	clt, err := client(c, backend)
+	if err != nil {
+		return err
+	}
+
+	id, err := contractID(c, backend)
+	if err != nil {
+		return err
+	}
+
+	cfg := settings(c)
+
+	con, err := console.New(console.Config{
+		Client:     clt,
+		ContractID: id,
+		Converter:  converter(c),
+		GasLimit:   cfg.GasLimit,
+		GasPrice:   currency.GWei2Wei(big.NewFloat(cfg.GasPriceGWei)),
+		Out:        os.Stdout,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Ctrl-C stops the running console command instead of the program, the
+	// console handles the interrupt itself and exits on Ctrl-D or exit.
+	signal.Reset(os.Interrupt)
+
+	return con.Run(c.Context, c.String("history"))
+}
+
+// defaultHistoryFile returns the history file in the user's home directory,
+// or no file when there is no home directory.
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".basic_history")
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/multicall"
+	"github.com/ardanlabs/smartcontract/business/basic/preflight"
+	"github.com/ardanlabs/smartcontract/business/basic/registry"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/urfave/cli/v2"
+)
+
+func deployCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "deploy",
+		Usage: "deploy the basic contract along with the multicall contract",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "simulate the deployments without broadcasting them",
+			},
+		},
+		Action: deploy,
+	}
+}
+
+func deploy(c *cli.Context) error {
+	ctx := c.Context
+
+	backend, err := dial(c)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	clt, err := client(c, backend)
+	if err != nil {
+		return err
+	}
+
+	converter := converter(c)
+
+	if !jsonOutput(c) {
+		oneETHToUSD, oneUSDToETH := converter.Values()
+
+		fmt.Println("\nInput Values")
+		fmt.Println("----------------------------------------------------")
+		fmt.Println("fromAddress:", clt.Address())
+		fmt.Println("oneETHToUSD:", oneETHToUSD)
+		fmt.Println("oneUSDToETH:", oneUSDToETH)
+	}
+
+	tranOpts, err := transactOpts(c, clt)
+	if err != nil {
+		return err
+	}
+
+	// =========================================================================
+
+	if c.Bool("dry-run") {
+		tranOpts.NoSend = true
+
+		_, tx, _, err := basic.DeployBasic(tranOpts, clt.Backend)
+		if err != nil {
+			return err
+		}
+
+		// The multicall deployment would follow the basic deployment.
+		tranOpts.Nonce = new(big.Int).Add(tranOpts.Nonce, big.NewInt(1))
+
+		_, mtx, _, err := multicall.DeployMulticall(tranOpts, clt.Backend)
+		if err != nil {
+			return err
+		}
+
+		return simulate(c, clt.Backend, converter, tx, mtx)
+	}
+
+	// =========================================================================
+
+	startingBalance, err := clt.Balance(ctx)
+	if err != nil {
+		return err
+	}
+
+	reg, err := registry.Open(settings(c).Registry)
+	if err != nil {
+		return err
+	}
+
+	var deployments []registry.Deployment
+
+	address, tx, contract, err := basic.DeployBasic(tranOpts, clt.Backend)
+	if err != nil {
+		return err
+	}
+
+	receipt, err := mined(c, clt, converter, "Contract Details", address, tx)
+	if err != nil {
+		return err
+	}
+
+	d, err := registry.Describe(ctx, clt.Backend, registry.ContractBasic, settings(c).Network, tx, receipt)
+	if err != nil {
+		return err
+	}
+
+	if d.Version, err = contract.Version(&bind.CallOpts{Context: ctx}); err != nil {
+		return fmt.Errorf("version: %w", err)
+	}
+
+	if err := reg.Record(d); err != nil {
+		return err
+	}
+	deployments = append(deployments, d)
+
+	// =========================================================================
+
+	tranOpts, err = transactOpts(c, clt)
+	if err != nil {
+		return err
+	}
+
+	address, tx, _, err = multicall.DeployMulticall(tranOpts, clt.Backend)
+	if err != nil {
+		return err
+	}
+
+	receipt, err = mined(c, clt, converter, "Multicall Contract Details", address, tx)
+	if err != nil {
+		return err
+	}
+
+	d, err = registry.Describe(ctx, clt.Backend, registry.ContractMulticall, settings(c).Network, tx, receipt)
+	if err != nil {
+		return err
+	}
+
+	if err := reg.Record(d); err != nil {
+		return err
+	}
+	deployments = append(deployments, d)
+
+	// =========================================================================
+
+	if jsonOutput(c) {
+		return printJSON(deployments)
+	}
+
+	endingBalance, err := clt.Balance(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Print(converter.FmtBalanceSheet(startingBalance, endingBalance))
+
+	return nil
+}
+
+// mined waits for the deployment to be mined, printing its details along
+// the way when the output is text.
+func mined(c *cli.Context, clt *ethereum.Client, converter *currency.Converter, title string, address common.Address, tx *types.Transaction) (*types.Receipt, error) {
+	if !jsonOutput(c) {
+		fmt.Print(converter.FmtTransaction(tx))
+
+		fmt.Println("\n" + title)
+		fmt.Println("----------------------------------------------------")
+		fmt.Println("contract id     :", address.Hex())
+	}
+
+	receipt, err := clt.WaitMined(c.Context, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !jsonOutput(c) {
+		fmt.Print(converter.FmtTransactionReceipt(receipt, tx.GasPrice()))
+	}
+
+	return receipt, nil
+}
+
+// simulation describes the predicted outcome of a transaction for JSON output.
+type simulation struct {
+	TxHash          common.Hash      `json:"tx_hash"`
+	GasEstimate     uint64           `json:"gas_estimate"`
+	FeeWei          *big.Int         `json:"fee_wei"`
+	PredictedEvents []simulatedEvent `json:"predicted_events,omitempty"`
+	Revert          string           `json:"revert,omitempty"`
+	Error           string           `json:"error,omitempty"`
+}
+
+// simulatedEvent describes an event predicted from the call arguments.
+type simulatedEvent struct {
+	Event string         `json:"event"`
+	Args  map[string]any `json:"args"`
+}
+
+// simulate runs the signed transactions through preflight without
+// broadcasting them and reports the predicted outcome of each.
+func simulate(c *cli.Context, backend ethereum.Backend, converter *currency.Converter, txs ...*types.Transaction) error {
+	var sims []simulation
+
+	for _, tx := range txs {
+		from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if err != nil {
+			return fmt.Errorf("recovering sender: %w", err)
+		}
+
+		report, err := preflight.Simulate(c.Context, backend, from, tx)
+		if err != nil {
+			return err
+		}
+
+		if !jsonOutput(c) {
+			fmt.Print(report.Format(converter))
+		}
+
+		sim := simulation{
+			TxHash:      tx.Hash(),
+			GasEstimate: report.GasEstimate,
+			FeeWei:      report.FeeWei,
+			Revert:      report.Revert,
+		}
+		for _, ev := range report.PredictedEvents {
+			sim.PredictedEvents = append(sim.PredictedEvents, simulatedEvent{Event: ev.EventName, Args: ev.Data})
+		}
+		if report.Err != nil {
+			sim.Error = report.Err.Error()
+		}
+		sims = append(sims, sim)
+	}
+
+	if jsonOutput(c) {
+		return printJSON(sims)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// transactOpts constructs the options for a transaction using the configured
+// gas settings.
+func transactOpts(c *cli.Context, clt *ethereum.Client) (*bind.TransactOpts, error) {
+	const valueGwei = 0.0
+
+	cfg := settings(c)
+	gasPrice := currency.GWei2Wei(big.NewFloat(cfg.GasPriceGWei))
+
+	return clt.NewTransactOpts(c.Context, cfg.GasLimit, gasPrice, big.NewFloat(valueGwei))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ardanlabs/smartcontract/business/basic/registry"
+	"github.com/urfave/cli/v2"
+)
+
+func deploymentsCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "deployments",
+		Usage:     "list the deployments recorded for the connected network",
+		ArgsUsage: "[CONTRACT...]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "all",
+				Usage: "list every network in the registry without connecting to a node",
+			},
+		},
+		Action: deployments,
+	}
+}
+
+func deployments(c *cli.Context) error {
+	reg, err := registry.Open(settings(c).Registry)
+	if err != nil {
+		return err
+	}
+
+	var chains []*big.Int
+	switch {
+	case c.Bool("all"):
+		chains = reg.Chains()
+
+	default:
+		backend, err := dial(c)
+		if err != nil {
+			return err
+		}
+		defer backend.Close()
+
+		chains = []*big.Int{backend.ChainID()}
+	}
+
+	var list []registry.Deployment
+	for _, chainID := range chains {
+		contracts := c.Args().Slice()
+		if len(contracts) == 0 {
+			contracts = reg.Contracts(chainID)
+		}
+
+		for _, contract := range contracts {
+			list = append(list, reg.History(chainID, contract)...)
+		}
+	}
+
+	if jsonOutput(c) {
+		return printJSON(list)
+	}
+
+	fmt.Println("\nDeployments")
+	fmt.Println("----------------------------------------------------")
+	for i, d := range list {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%-13s: %s\n", "contract", d.Contract)
+		fmt.Printf("%-13s: %d (%s)\n", "chain", d.ChainID, d.Network)
+		fmt.Printf("%-13s: %s\n", "address", d.Address.Hex())
+		fmt.Printf("%-13s: %s\n", "tx hash", d.TxHash.Hex())
+		fmt.Printf("%-13s: %d\n", "block", d.Block)
+		fmt.Printf("%-13s: %s\n", "deployer", d.Deployer.Hex())
+		fmt.Printf("%-13s: %s\n", "bytecode hash", d.BytecodeHash.Hex())
+		if d.Version != "" {
+			fmt.Printf("%-13s: %s\n", "version", d.Version)
+		}
+		fmt.Printf("%-13s: %s\n", "timestamp", d.Timestamp.Format(time.RFC3339))
+	}
+
+	return nil
+}
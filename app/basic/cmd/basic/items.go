@@ -0,0 +1,575 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/config"
+	"github.com/ardanlabs/smartcontract/business/basic/historical"
+	"github.com/ardanlabs/smartcontract/business/basic/watch"
+	"github.com/ardanlabs/smartcontract/foundation/sender"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/urfave/cli/v2"
+)
+
+// item represents a key and its value for JSON output.
+type item struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Block uint64 `json:"block,omitempty"`
+}
+
+// write represents the outcome of a SetItem transaction for JSON output.
+type write struct {
+	Key     string         `json:"key"`
+	Value   string         `json:"value"`
+	From    common.Address `json:"from"`
+	TxHash  common.Hash    `json:"tx_hash"`
+	Block   uint64         `json:"block"`
+	Status  uint64         `json:"status"`
+	GasUsed uint64         `json:"gas_used"`
+}
+
+// change represents an ItemSet event for JSON output.
+type change struct {
+	Block  uint64      `json:"block"`
+	TxHash common.Hash `json:"tx_hash"`
+	Value  string      `json:"value"`
+}
+
+// =============================================================================
+
+func getCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "get",
+		Usage:     "read the value of one or more keys, now or at a past block",
+		ArgsUsage: "KEY [KEY...]",
+		Flags: append(blockFlags(),
+			&cli.Uint64Flag{
+				Name:  "from",
+				Usage: "sample the keys across blocks starting at this block",
+			},
+			&cli.Uint64Flag{
+				Name:  "to",
+				Usage: "with --from, the last block to sample, the latest block when not set",
+			},
+			&cli.Uint64Flag{
+				Name:  "step",
+				Value: 1,
+				Usage: "with --from, the number of blocks between samples",
+			},
+		),
+		Action: get,
+	}
+}
+
+func setCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "set",
+		Usage:     "set the value of one or more keys",
+		ArgsUsage: "KEY VALUE [KEY VALUE...]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "simulate the transactions without broadcasting them",
+			},
+			&cli.StringFlag{
+				Name:  "senders",
+				Usage: "spread writes across every keystore account: round-robin or least-pending",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() == 0 || c.NArg()%2 != 0 {
+				return fmt.Errorf("expected KEY VALUE pairs, got %d arguments", c.NArg())
+			}
+
+			var items []item
+			for i := 0; i < c.NArg(); i += 2 {
+				items = append(items, item{Key: c.Args().Get(i), Value: c.Args().Get(i + 1)})
+			}
+
+			return set(c, items)
+		},
+	}
+}
+
+func deleteCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "delete",
+		Usage:     "clear one or more keys by setting their value to zero",
+		ArgsUsage: "KEY [KEY...]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "simulate the transactions without broadcasting them",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() == 0 {
+				return errors.New("expected at least one KEY")
+			}
+
+			var items []item
+			for _, key := range c.Args().Slice() {
+				items = append(items, item{Key: key, Value: "0"})
+			}
+
+			return set(c, items)
+		},
+	}
+}
+
+func historyCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "history",
+		Usage:     "list every value a key has been set to",
+		ArgsUsage: "KEY",
+		Flags: []cli.Flag{
+			&cli.Uint64Flag{
+				Name:  "from",
+				Usage: "block to start searching from",
+			},
+		},
+		Action: history,
+	}
+}
+
+// =============================================================================
+
+func get(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return errors.New("expected at least one KEY")
+	}
+
+	backend, err := dial(c)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	id, err := contractID(c, backend)
+	if err != nil {
+		return err
+	}
+
+	r, err := historical.New(backend, id)
+	if err != nil {
+		return err
+	}
+
+	if c.IsSet("from") {
+		return sample(c, r)
+	}
+
+	header, err := resolveBlock(c, r)
+	if err != nil {
+		return err
+	}
+
+	var items []item
+	for _, key := range c.Args().Slice() {
+		value, err := r.Item(c.Context, header, key)
+		if err != nil {
+			return err
+		}
+		items = append(items, item{Key: key, Value: value.String(), Block: header.Number.Uint64()})
+	}
+
+	if jsonOutput(c) {
+		return printJSON(items)
+	}
+
+	fmt.Println("\nRead Value")
+	fmt.Println("----------------------------------------------------")
+	printBlock(header)
+	for _, item := range items {
+		fmt.Printf("%s: %s\n", item.Key, item.Value)
+	}
+
+	return nil
+}
+
+// sample reads each key across the range of blocks given by the from, to
+// and step flags.
+func sample(c *cli.Context, r *historical.Reader) error {
+	to := c.Uint64("to")
+	if !c.IsSet("to") {
+		head, err := r.Head(c.Context)
+		if err != nil {
+			return err
+		}
+		to = head.Number.Uint64()
+	}
+
+	series := make(map[string][]historical.Sample)
+	for _, key := range c.Args().Slice() {
+		samples, err := r.Sample(c.Context, key, c.Uint64("from"), to, c.Uint64("step"))
+		if err != nil {
+			return err
+		}
+		series[key] = samples
+	}
+
+	if jsonOutput(c) {
+		return printJSON(series)
+	}
+
+	for _, key := range c.Args().Slice() {
+		fmt.Println("\nSamples for", key)
+		fmt.Println("----------------------------------------------------")
+		for _, s := range series[key] {
+			var mark string
+			if s.Changed {
+				mark = " *"
+			}
+			fmt.Printf("block[%d] %s %s%s\n", s.Block, time.Unix(int64(s.Time), 0).UTC().Format(time.RFC3339), s.Value, mark)
+		}
+	}
+
+	return nil
+}
+
+func set(c *cli.Context, items []item) error {
+	values := make([]*big.Int, len(items))
+	for i, item := range items {
+		value, err := parseValue(item.Value)
+		if err != nil {
+			return err
+		}
+		values[i] = value
+	}
+
+	if c.String("senders") != "" {
+		return setPool(c, items, values)
+	}
+
+	backend, err := dial(c)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	clt, err := client(c, backend)
+	if err != nil {
+		return err
+	}
+
+	id, err := contractID(c, backend)
+	if err != nil {
+		return err
+	}
+
+	contract, err := basic.NewBasic(id, clt.Backend)
+	if err != nil {
+		return fmt.Errorf("new contract: %w", err)
+	}
+
+	converter := converter(c)
+
+	if !jsonOutput(c) {
+		fmt.Println("\nInput Values")
+		fmt.Println("----------------------------------------------------")
+		fmt.Println("fromAddress:", clt.Address())
+		fmt.Println("contractID:", id.Hex())
+	}
+
+	// =========================================================================
+
+	if c.Bool("dry-run") {
+		var txs []*types.Transaction
+
+		tranOpts, err := transactOpts(c, clt)
+		if err != nil {
+			return err
+		}
+		tranOpts.NoSend = true
+
+		for i, item := range items {
+			tx, err := contract.SetItem(tranOpts, item.Key, values[i])
+			if err != nil {
+				return fmt.Errorf("SetItem %s: %w", item.Key, err)
+			}
+			txs = append(txs, tx)
+
+			// Each write would follow the one before it.
+			tranOpts.Nonce = new(big.Int).Add(tranOpts.Nonce, big.NewInt(1))
+		}
+
+		return simulate(c, clt.Backend, converter, txs...)
+	}
+
+	// =========================================================================
+
+	startingBalance, err := clt.Balance(c.Context)
+	if err != nil {
+		return err
+	}
+
+	var writes []write
+	for i, item := range items {
+		tranOpts, err := transactOpts(c, clt)
+		if err != nil {
+			return err
+		}
+
+		tx, err := contract.SetItem(tranOpts, item.Key, values[i])
+		if err != nil {
+			return fmt.Errorf("SetItem %s: %w", item.Key, err)
+		}
+
+		if !jsonOutput(c) {
+			fmt.Print(converter.FmtTransaction(tx))
+		}
+
+		receipt, err := clt.WaitMined(c.Context, tx)
+		if err != nil {
+			return err
+		}
+
+		if !jsonOutput(c) {
+			fmt.Print(converter.FmtTransactionReceipt(receipt, tx.GasPrice()))
+		}
+
+		writes = append(writes, newWrite(item, clt.Address(), receipt))
+	}
+
+	if jsonOutput(c) {
+		return printJSON(writes)
+	}
+
+	endingBalance, err := clt.Balance(c.Context)
+	if err != nil {
+		return err
+	}
+	fmt.Print(converter.FmtBalanceSheet(startingBalance, endingBalance))
+
+	return nil
+}
+
+// setPool writes the items concurrently using every account in the keystore,
+// so the writes are not serialized behind a single nonce.
+func setPool(c *cli.Context, items []item, values []*big.Int) error {
+	ctx := c.Context
+
+	strategy, err := sender.ParseStrategy(c.String("senders"))
+	if err != nil {
+		return err
+	}
+
+	backend, err := dial(c)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	cfg := settings(c)
+
+	passphrase, err := cfg.Secrets().Secret(config.SecretPassphrase)
+	if err != nil {
+		return err
+	}
+
+	privateKeys, err := sender.LoadKeyStore(cfg.KeyStoreDir, passphrase)
+	if err != nil {
+		return err
+	}
+
+	pool, err := sender.New(backend, strategy, privateKeys...)
+	if err != nil {
+		return err
+	}
+
+	id, err := contractID(c, backend)
+	if err != nil {
+		return err
+	}
+
+	contract, err := basic.NewBasic(id, backend)
+	if err != nil {
+		return fmt.Errorf("new contract: %w", err)
+	}
+
+	converter := converter(c)
+
+	if !jsonOutput(c) {
+		fmt.Println("\nInput Values")
+		fmt.Println("----------------------------------------------------")
+		fmt.Println("strategy:", strategy)
+		for _, address := range pool.Addresses() {
+			fmt.Println("fromAddress:", address)
+		}
+		fmt.Println("contractID:", id.Hex())
+	}
+
+	// =========================================================================
+
+	gasPrice := currency.GWei2Wei(big.NewFloat(cfg.GasPriceGWei))
+	const valueGwei = 0.0
+
+	// A dry run builds the transactions the pool would send, with the
+	// nonces it would use, without using any of them up.
+	if c.Bool("dry-run") {
+		dry := pool.DryRun()
+
+		txs := make([]*types.Transaction, len(items))
+		for i, it := range items {
+			tx, err := dry.Build(ctx, cfg.GasLimit, gasPrice, big.NewFloat(valueGwei), func(tranOpts *bind.TransactOpts) (*types.Transaction, error) {
+				return contract.SetItem(tranOpts, it.Key, values[i])
+			})
+			if err != nil {
+				return fmt.Errorf("SetItem %s: %w", it.Key, err)
+			}
+			txs[i] = tx
+		}
+
+		return simulate(c, backend, converter, txs...)
+	}
+
+	var mu sync.Mutex
+	var errs []error
+	writes := make([]write, len(items))
+
+	send := func(i int) error {
+		tx, err := pool.Send(ctx, cfg.GasLimit, gasPrice, big.NewFloat(valueGwei), func(tranOpts *bind.TransactOpts) (*types.Transaction, error) {
+			return contract.SetItem(tranOpts, items[i].Key, values[i])
+		})
+		if err != nil {
+			return err
+		}
+
+		receipt, err := pool.WaitMined(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		writes[i] = newWrite(items[i], from, receipt)
+		if !jsonOutput(c) {
+			fmt.Print(converter.FmtTransactionReceipt(receipt, tx.GasPrice()))
+		}
+
+		return nil
+	}
+
+	// No more writes run at once than there are accounts in the pool.
+	sem := make(chan struct{}, len(pool.Addresses()))
+
+	var wg sync.WaitGroup
+	for i := range items {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := send(i); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("SetItem %s: %w", items[i].Key, err))
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if jsonOutput(c) {
+		if err := printJSON(writes); err != nil {
+			return err
+		}
+		return errors.Join(errs...)
+	}
+
+	fmt.Println("\nSender Pool")
+	fmt.Println("----------------------------------------------------")
+	for _, status := range pool.Status() {
+		fmt.Printf("%s : sent %d, next nonce %d\n", status.Address, status.Sent, status.Nonce)
+	}
+
+	return errors.Join(errs...)
+}
+
+// newWrite describes the mined write of the item.
+func newWrite(it item, from common.Address, receipt *types.Receipt) write {
+	return write{
+		Key:     it.Key,
+		Value:   it.Value,
+		From:    from,
+		TxHash:  receipt.TxHash,
+		Block:   receipt.BlockNumber.Uint64(),
+		Status:  receipt.Status,
+		GasUsed: receipt.GasUsed,
+	}
+}
+
+// =============================================================================
+
+func history(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("expected a single KEY")
+	}
+	key := c.Args().First()
+
+	backend, err := dial(c)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	id, err := contractID(c, backend)
+	if err != nil {
+		return err
+	}
+
+	w, err := watch.New(backend, id, watch.Config{})
+	if err != nil {
+		return err
+	}
+
+	// The events are read in block ranges the node accepts. The key isn't
+	// indexed by the event, so the matching is done here.
+	var changes []change
+	err = w.Range(c.Context, c.Uint64("from"), nil, func(ev *basic.BasicItemSet) error {
+		if ev.Key != key {
+			return nil
+		}
+
+		changes = append(changes, change{
+			Block:  ev.Raw.BlockNumber,
+			TxHash: ev.Raw.TxHash,
+			Value:  ev.Value.String(),
+		})
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("item set history: %w", err)
+	}
+
+	if jsonOutput(c) {
+		return printJSON(changes)
+	}
+
+	fmt.Println("\nHistory for", key)
+	fmt.Println("----------------------------------------------------")
+	for _, ch := range changes {
+		fmt.Printf("block[%d] tx[%s] %s\n", ch.Block, ch.TxHash.Hex(), ch.Value)
+	}
+
+	return nil
+}
@@ -0,0 +1,337 @@
+// This program provides a single command line tool for deploying and working
+// with the basic contract.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/config"
+	"github.com/ardanlabs/smartcontract/business/basic/registry"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/urfave/cli/v2"
+)
+
+// flagSettings maps the global flags to the config settings they override.
+var flagSettings = map[string]string{
+	"network":                "network",
+	"networks":               "networks",
+	"keystore":               "keystore",
+	"keystore-dir":           "keystore_dir",
+	"passphrase-file":        "passphrase_file",
+	"coinmarketcap-key-file": "coinmarketcap_key_file",
+	"vault":                  "vault",
+	"contract":               "contract",
+	"registry":               "registry",
+	"gas-limit":              "gas_limit",
+	"gas-price":              "gas_price_gwei",
+	"rpc-attempts":           "rpc_attempts",
+	"rpc-rate":               "rpc_rate",
+	"output":                 "output",
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if err := newApp().RunContext(ctx, os.Args); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// newApp constructs the command line app with its global flags and
+// commands.
+func newApp() *cli.App {
+	app := cli.App{
+		Name:  "basic",
+		Usage: "deploy and work with the basic contract",
+		Description: "Settings come from the selected profile, then the config file, then\n" +
+			"BASIC_* environment variables, then these flags. Run the config command\n" +
+			"to see the effective settings and where each one came from.\n\n" +
+			"The remote profile has no network or keystore of its own, set them\n" +
+			"in the config file, BASIC_NETWORK and BASIC_KEYSTORE or the flags.\n\n" +
+			"The keystore passphrase is read from BASIC_PASSPHRASE, the passphrase\n" +
+			"file, the vault, or prompted for on a terminal, in that order.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "config",
+				Usage:   "JSON config file, " + config.DefaultFile + " is used when it exists",
+				EnvVars: []string{"BASIC_CONFIG"},
+			},
+			&cli.StringFlag{
+				Name:    "profile",
+				Aliases: []string{"p"},
+				Usage:   "settings profile: " + strings.Join(config.Profiles(), ", ") + " or one defined in the config file",
+			},
+			&cli.StringFlag{
+				Name:    "network",
+				Aliases: []string{"n"},
+				Usage:   "node to connect to, an IPC path or an HTTP/WS url",
+			},
+			&cli.StringFlag{
+				Name:  "networks",
+				Usage: "comma separated nodes on the same chain to fail over to when the network stops responding",
+			},
+			&cli.StringFlag{
+				Name:    "keystore",
+				Aliases: []string{"k"},
+				Usage:   "keystore file for the account sending transactions",
+			},
+			&cli.StringFlag{
+				Name:  "keystore-dir",
+				Usage: "keystore directory used for the sender pool",
+			},
+			&cli.StringFlag{
+				Name:  "passphrase-file",
+				Usage: "file holding the keystore passphrase, readable by the owner only",
+			},
+			&cli.StringFlag{
+				Name:  "coinmarketcap-key-file",
+				Usage: "file holding the API key used to price transactions in USD",
+			},
+			&cli.StringFlag{
+				Name:  "vault",
+				Usage: "encrypted vault holding secrets, see the vault command",
+			},
+			&cli.StringFlag{
+				Name:  "contract",
+				Usage: "address of the basic contract, the latest deployment in the registry when empty",
+			},
+			&cli.StringFlag{
+				Name:  "registry",
+				Usage: "deployments registry file",
+			},
+			&cli.StringFlag{
+				Name:  "gas-limit",
+				Usage: "gas limit for each transaction",
+			},
+			&cli.StringFlag{
+				Name:  "gas-price",
+				Usage: "gas price in GWei for each transaction",
+			},
+			&cli.StringFlag{
+				Name:  "rpc-attempts",
+				Usage: "times a call to the node is attempted when it fails with a transient error",
+			},
+			&cli.StringFlag{
+				Name:  "rpc-rate",
+				Usage: "calls per second allowed to the node, 0 for no limit",
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "output format, text or json",
+			},
+		},
+		Before: loadConfig,
+		After:  reportRPC,
+		Commands: []*cli.Command{
+			configCommand(),
+			vaultCommand(),
+			deployCommand(),
+			deploymentsCommand(),
+			verifyCommand(),
+			getCommand(),
+			versionCommand(),
+			setCommand(),
+			deleteCommand(),
+			watchCommand(),
+			historyCommand(),
+			balanceCommand(),
+			txCommand(),
+			consoleCommand(),
+			importCommand(),
+			exportCommand(),
+		},
+	}
+
+	return &app
+}
+
+// loadConfig builds the effective config from the profile, file, environment
+// and flags, and keeps it for the commands.
+func loadConfig(c *cli.Context) error {
+	opts := config.Options{
+		File:    c.String("config"),
+		Profile: c.String("profile"),
+		Flags:   make(map[string]string),
+	}
+
+	if opts.File == "" {
+		opts.File = config.DefaultFile
+		opts.FileOptional = true
+	}
+
+	for flag, name := range flagSettings {
+		if c.IsSet(flag) {
+			opts.Flags[name] = c.String(flag)
+		}
+	}
+
+	cfg, err := config.Load(opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.FileOptional {
+		if _, err := os.Stat(opts.File); err != nil {
+			opts.File = ""
+		}
+	}
+
+	c.App.Metadata = map[string]any{
+		"config":     cfg,
+		"configFile": opts.File,
+	}
+
+	return nil
+}
+
+// settings returns the effective config loaded before the command ran.
+func settings(c *cli.Context) config.Config {
+	return c.App.Metadata["config"].(config.Config)
+}
+
+// =============================================================================
+
+// dial connects to the configured network, failing over to the configured
+// failover networks when there are any. The backend is kept so its retry
+// counters can be reported once the command is done.
+func dial(c *cli.Context) (*config.Backend, error) {
+	backend, err := settings(c).Dial(c.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	c.App.Metadata["backend"] = backend
+
+	return backend, nil
+}
+
+// reportRPC prints the retry and rate limit counters to stderr when calls to
+// the node had to be retried or throttled, so a struggling node is noticed.
+func reportRPC(c *cli.Context) error {
+	backend, ok := c.App.Metadata["backend"].(*config.Backend)
+	if !ok {
+		return nil
+	}
+
+	stats := backend.Stats()
+	if stats.Retries == 0 && stats.Throttled == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "rpc: %d calls, %d attempts, %d retries, %d throttled, %d failed\n", stats.Calls, stats.Attempts, stats.Retries, stats.Throttled, stats.Failures)
+
+	return nil
+}
+
+// client constructs a client for the account in the configured keystore.
+func client(c *cli.Context, backend ethereum.Backend) (*ethereum.Client, error) {
+	cfg := settings(c)
+
+	passphrase, err := cfg.Secrets().Secret(config.SecretPassphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := ethereum.PrivateKeyByKeyFile(cfg.KeyStore, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("load keystore: %w", err)
+	}
+
+	return ethereum.NewClient(backend, privateKey)
+}
+
+// contractID returns the configured contract address, falling back to the
+// latest basic deployment in the registry for the connected chain.
+func contractID(c *cli.Context, backend ethereum.Backend) (common.Address, error) {
+	cfg := settings(c)
+
+	if cfg.Contract != "" {
+		return common.HexToAddress(cfg.Contract), nil
+	}
+
+	reg, err := registry.Open(cfg.Registry)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	d, err := reg.Latest(backend.ChainID(), registry.ContractBasic)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	return d.Address, nil
+}
+
+// multicallID returns the latest multicall deployment in the registry for
+// the connected chain.
+func multicallID(c *cli.Context, backend ethereum.Backend) (common.Address, error) {
+	reg, err := registry.Open(settings(c).Registry)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	d, err := reg.Latest(backend.ChainID(), registry.ContractMulticall)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	return d.Address, nil
+}
+
+// converter returns a converter with live prices, or the default prices
+// when there is no API key or the prices can't be retrieved.
+func converter(c *cli.Context) *currency.Converter {
+	key, err := settings(c).Secrets().Secret(config.SecretCoinMarketCapKey)
+	if err != nil {
+		return currency.NewDefaultConverter(basic.BasicMetaData.ABI)
+	}
+
+	converter, err := currency.NewConverter(basic.BasicMetaData.ABI, key)
+	if err != nil {
+		converter = currency.NewDefaultConverter(basic.BasicMetaData.ABI)
+	}
+
+	return converter
+}
+
+// parseValue converts a decimal string into a value that fits a uint256.
+func parseValue(s string) (*big.Int, error) {
+	value, ok := new(big.Int).SetString(s, 10)
+	if !ok || value.Sign() < 0 || value.BitLen() > 256 {
+		return nil, fmt.Errorf("value %q must be an unsigned 256 bit decimal integer", s)
+	}
+
+	return value, nil
+}
+
+// jsonOutput reports whether results should be printed as JSON.
+func jsonOutput(c *cli.Context) bool {
+	return settings(c).Output == config.OutputJSON
+}
+
+// printJSON writes the value to stdout as indented JSON.
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
+
+// printJSONLine writes the value to stdout as a single line of JSON, for
+// commands that stream results.
+func printJSONLine(v any) error {
+	return json.NewEncoder(os.Stdout).Encode(v)
+}
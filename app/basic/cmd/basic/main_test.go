@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ardanlabs/smartcontract/business/basic/config"
+	"github.com/urfave/cli/v2"
+)
+
+func TestFlagSettings(t *testing.T) {
+	app := newApp()
+
+	// Every global flag other than the ones choosing the layers must
+	// override a setting, or loadConfig never reads it.
+	for _, flag := range app.Flags {
+		name := flag.Names()[0]
+		if name == "config" || name == "profile" {
+			continue
+		}
+
+		if _, exists := flagSettings[name]; !exists {
+			t.Fatalf("flag --%s should map to a setting", name)
+		}
+	}
+
+	// =========================================================================
+
+	file := filepath.Join(t.TempDir(), "basic.json")
+	data := `{"profiles": {"dev-ipc": {"registry": "file.json"}}}`
+
+	if err := os.WriteFile(file, []byte(data), 0600); err != nil {
+		t.Fatalf("unable to write config file: %s", err)
+	}
+
+	var cfg config.Config
+	app.After = nil
+	app.Commands = []*cli.Command{
+		{
+			Name: "probe",
+			Action: func(c *cli.Context) error {
+				cfg = settings(c)
+				return nil
+			},
+		},
+	}
+
+	args := []string{"basic", "--config", file, "--profile", config.ProfileDevIPC, "--registry", "other.json", "probe"}
+	if err := app.Run(args); err != nil {
+		t.Fatalf("should be able to run: %s", err)
+	}
+
+	if cfg.Registry != "other.json" || cfg.Sources["registry"] != config.SourceFlag {
+		t.Fatalf("registry flag should override the config file, got %q from %s", cfg.Registry, cfg.Sources["registry"])
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/business/basic/config"
+	"github.com/ardanlabs/smartcontract/business/basic/registry"
+	"github.com/ardanlabs/smartcontract/business/basic/snapshot"
+	"github.com/ardanlabs/smartcontract/foundation/sender"
+	"github.com/urfave/cli/v2"
+)
+
+// export represents the result of an export for JSON output.
+type export struct {
+	File  string `json:"file"`
+	Block uint64 `json:"block"`
+	Items int    `json:"items"`
+}
+
+func importCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "import",
+		Usage:     "write the items in a CSV or JSON file, skipping keys that already hold the value",
+		ArgsUsage: "FILE",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "checkpoint",
+				Usage: "file to save progress to so an interrupted import resumes, FILE.checkpoint by default",
+			},
+			&cli.IntFlag{
+				Name:  "batch",
+				Value: 100,
+				Usage: "number of items read and written at a time",
+			},
+			&cli.StringFlag{
+				Name:  "senders",
+				Usage: "spread writes across every keystore account: round-robin or least-pending",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "report what would be written without sending any transactions",
+			},
+		},
+		Action: importItems,
+	}
+}
+
+func exportCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "export",
+		Usage:     "write the items held at a block to a CSV or JSON file that import accepts",
+		ArgsUsage: "FILE",
+		Flags: []cli.Flag{
+			&cli.Uint64Flag{
+				Name:  "block",
+				Usage: "block to export the items at, the latest block when not set",
+			},
+			&cli.Uint64Flag{
+				Name:  "from",
+				Usage: "block to search for keys from, the registry's deploy block when not set",
+			},
+		},
+		Action: exportItems,
+	}
+}
+
+// =============================================================================
+
+func importItems(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("expected a single FILE")
+	}
+	file := c.Args().First()
+
+	items, err := snapshot.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	backend, err := dial(c)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	pool, err := importPool(c, backend)
+	if err != nil {
+		return err
+	}
+
+	basicID, err := contractID(c, backend)
+	if err != nil {
+		return err
+	}
+
+	mcID, err := multicallID(c, backend)
+	if err != nil {
+		return err
+	}
+
+	// Progress goes to stderr in JSON mode so the summary on stdout stays
+	// parseable.
+	progressOut := os.Stdout
+	if jsonOutput(c) {
+		progressOut = os.Stderr
+	}
+
+	checkpoint := c.String("checkpoint")
+	if checkpoint == "" {
+		checkpoint = file + ".checkpoint"
+	}
+
+	cfg := snapshot.ImportConfig{
+		Checkpoint: checkpoint,
+		BatchSize:  c.Int("batch"),
+		GasLimit:   settings(c).GasLimit,
+		GasPrice:   currency.GWei2Wei(big.NewFloat(settings(c).GasPriceGWei)),
+		DryRun:     c.Bool("dry-run"),
+		Progress: func(p snapshot.Progress) {
+			fmt.Fprintf(progressOut, "progress: %d/%d written[%d] skipped[%d]\n", p.Done, p.Total, p.Written, p.Skipped)
+		},
+	}
+
+	im, err := snapshot.NewImporter(backend, pool, mcID, basicID, cfg)
+	if err != nil {
+		return err
+	}
+
+	if !jsonOutput(c) {
+		fmt.Println("\nImport")
+		fmt.Println("----------------------------------------------------")
+		fmt.Println("file      :", file)
+		fmt.Println("items     :", len(items))
+		fmt.Println("contractID:", basicID.Hex())
+		fmt.Println("checkpoint:", checkpoint)
+		if cfg.DryRun {
+			fmt.Println("dry run   : nothing will be written")
+		}
+	}
+
+	progress, err := im.Import(c.Context, items)
+	if err != nil {
+		return fmt.Errorf("import stopped at %d/%d, run again to resume: %w", progress.Done, progress.Total, err)
+	}
+
+	if jsonOutput(c) {
+		return printJSON(progress)
+	}
+
+	fmt.Println("\nImported")
+	fmt.Println("----------------------------------------------------")
+	fmt.Println("written:", progress.Written)
+	fmt.Println("skipped:", progress.Skipped)
+
+	return nil
+}
+
+func exportItems(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("expected a single FILE")
+	}
+	file := c.Args().First()
+
+	if _, err := snapshot.FormatOf(file); err != nil {
+		return err
+	}
+
+	backend, err := dial(c)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	basicID, err := contractID(c, backend)
+	if err != nil {
+		return err
+	}
+
+	mcID, err := multicallID(c, backend)
+	if err != nil {
+		return err
+	}
+
+	block := c.Uint64("block")
+	if !c.IsSet("block") {
+		header, err := backend.HeaderByNumber(c.Context, nil)
+		if err != nil {
+			return fmt.Errorf("head block: %w", err)
+		}
+		block = header.Number.Uint64()
+	}
+
+	from := c.Uint64("from")
+	if !c.IsSet("from") && settings(c).Contract == "" {
+		reg, err := registry.Open(settings(c).Registry)
+		if err != nil {
+			return err
+		}
+
+		d, err := reg.Latest(backend.ChainID(), registry.ContractBasic)
+		if err != nil {
+			return err
+		}
+		from = d.Block
+	}
+
+	items, err := snapshot.Export(c.Context, backend, mcID, basicID, block, snapshot.ExportConfig{DeployBlock: from})
+	if err != nil {
+		return err
+	}
+
+	if err := snapshot.WriteFile(file, items); err != nil {
+		return err
+	}
+
+	if jsonOutput(c) {
+		e := export{
+			File:  file,
+			Block: block,
+			Items: len(items),
+		}
+		return printJSON(e)
+	}
+
+	fmt.Println("\nExport")
+	fmt.Println("----------------------------------------------------")
+	fmt.Println("file      :", file)
+	fmt.Println("contractID:", basicID.Hex())
+	fmt.Println("block     :", block)
+	fmt.Println("items     :", len(items))
+
+	return nil
+}
+
+// importPool returns the pool the import sends from, the keystore account or
+// every account in the keystore directory when a senders strategy is given.
+func importPool(c *cli.Context, backend ethereum.Backend) (*sender.Pool, error) {
+	if c.String("senders") == "" {
+		clt, err := client(c, backend)
+		if err != nil {
+			return nil, err
+		}
+		return sender.New(backend, sender.RoundRobin, clt.PrivateKey())
+	}
+
+	strategy, err := sender.ParseStrategy(c.String("senders"))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := settings(c)
+
+	passphrase, err := cfg.Secrets().Secret(config.SecretPassphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKeys, err := sender.LoadKeyStore(cfg.KeyStoreDir, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return sender.New(backend, strategy, privateKeys...)
+}
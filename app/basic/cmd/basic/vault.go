@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ardanlabs/smartcontract/business/basic/config"
+	"github.com/ardanlabs/smartcontract/foundation/secret"
+	"github.com/ardanlabs/smartcontract/foundation/terminal"
+	"github.com/urfave/cli/v2"
+)
+
+func vaultCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "vault",
+		Usage: "manage the encrypted vault of secrets",
+		Description: "The vault password is read from BASIC_VAULT_PASSWORD or prompted for.\n" +
+			"Store the keystore passphrase as \"" + config.SecretPassphrase + "\" and the CoinMarketCap\n" +
+			"key as \"" + config.SecretCoinMarketCapKey + "\".",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "set",
+				Usage:     "store a secret, read from the terminal or stdin",
+				ArgsUsage: "NAME",
+				Action:    vaultSet,
+			},
+			{
+				Name:   "list",
+				Usage:  "list the names of the stored secrets",
+				Action: vaultList,
+			},
+			{
+				Name:      "delete",
+				Usage:     "remove a secret",
+				ArgsUsage: "NAME",
+				Action:    vaultDelete,
+			},
+		},
+	}
+}
+
+// openVault opens the configured vault using the vault password.
+func openVault(c *cli.Context) (*secret.Vault, error) {
+	path := settings(c).Vault
+	if path == "" {
+		return nil, errors.New("no vault configured")
+	}
+
+	provider := secret.Chain{
+		secret.Env{Prefix: config.EnvPrefix},
+		secret.Prompt{},
+	}
+
+	password, err := provider.Secret(secret.VaultPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	return secret.OpenVault(path, password)
+}
+
+func vaultSet(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("expected a single NAME")
+	}
+	name := c.Args().First()
+
+	v, err := openVault(c)
+	if err != nil {
+		return err
+	}
+
+	// Values are never taken from the command line, where they would end up
+	// in the shell history.
+	var value string
+	switch {
+	case terminal.IsTerminal(os.Stdin):
+		if value, err = (secret.Prompt{}).Secret(name); err != nil {
+			return err
+		}
+
+	default:
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("read %s: %w", name, err)
+		}
+		value = strings.TrimRight(line, "\r\n")
+	}
+
+	if value == "" {
+		return fmt.Errorf("no value given for %s", name)
+	}
+
+	v.Set(name, value)
+
+	if err := v.Save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "stored %s in %s\n", name, settings(c).Vault)
+
+	return nil
+}
+
+func vaultList(c *cli.Context) error {
+	v, err := openVault(c)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput(c) {
+		return printJSON(v.Names())
+	}
+
+	for _, name := range v.Names() {
+		fmt.Println(name)
+	}
+
+	return nil
+}
+
+func vaultDelete(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("expected a single NAME")
+	}
+
+	v, err := openVault(c)
+	if err != nil {
+		return err
+	}
+
+	v.Delete(c.Args().First())
+
+	return v.Save()
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/registry"
+	"github.com/ardanlabs/smartcontract/business/basic/verify"
+	"github.com/urfave/cli/v2"
+)
+
+func verifyCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "verify",
+		Usage: "verify the deployed contract runs the code in Basic.bin",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "all",
+				Usage: "verify the latest deployment on every network in the registry",
+			},
+		},
+		Action: verifyDeployments,
+	}
+}
+
+// verification represents the outcome of verifying the contract on a network.
+type verification struct {
+	Network string `json:"network"`
+	ChainID uint64 `json:"chain_id"`
+	verify.Result
+}
+
+func verifyDeployments(c *cli.Context) error {
+	var list []verification
+
+	switch {
+	case c.Bool("all"):
+		reg, err := registry.Open(settings(c).Registry)
+		if err != nil {
+			return err
+		}
+
+		for _, chainID := range reg.Chains() {
+			d, err := reg.Latest(chainID, registry.ContractBasic)
+			if errors.Is(err, registry.ErrNotFound) {
+				continue
+			}
+
+			v, err := verifyNetwork(c, d)
+			if err != nil {
+				return err
+			}
+			list = append(list, v)
+		}
+
+	default:
+		backend, err := dial(c)
+		if err != nil {
+			return err
+		}
+		defer backend.Close()
+
+		address, err := contractID(c, backend)
+		if err != nil {
+			return err
+		}
+
+		r, err := verify.Contract(c.Context, backend, address, basic.BasicMetaData.Bin)
+		if err != nil {
+			return err
+		}
+
+		list = append(list, verification{
+			Network: settings(c).Network,
+			ChainID: backend.ChainID().Uint64(),
+			Result:  r,
+		})
+	}
+
+	// =========================================================================
+
+	var mismatches int
+	for _, v := range list {
+		if v.Status == verify.StatusMismatch {
+			mismatches++
+		}
+	}
+
+	if jsonOutput(c) {
+		if err := printJSON(list); err != nil {
+			return err
+		}
+	} else {
+		printVerifications(list)
+	}
+
+	if mismatches > 0 {
+		return fmt.Errorf("%d deployment(s) don't run the compiled code", mismatches)
+	}
+
+	return nil
+}
+
+// verifyNetwork connects to the network a deployment was recorded on and
+// verifies the code at its address.
+func verifyNetwork(c *cli.Context, d registry.Deployment) (verification, error) {
+	network := d.Network
+
+	backend, err := ethereum.CreateDialedBackend(c.Context, network)
+	if err != nil {
+		return verification{}, fmt.Errorf("dial %s: %w", network, err)
+	}
+	defer backend.Close()
+
+	if backend.ChainID().Uint64() != d.ChainID {
+		return verification{}, fmt.Errorf("network %s is chain %d, the registry expects %d", network, backend.ChainID(), d.ChainID)
+	}
+
+	r, err := verify.Contract(c.Context, backend, d.Address, basic.BasicMetaData.Bin)
+	if err != nil {
+		return verification{}, err
+	}
+
+	v := verification{
+		Network: network,
+		ChainID: d.ChainID,
+		Result:  r,
+	}
+
+	return v, nil
+}
+
+func printVerifications(list []verification) {
+	fmt.Println("\nBytecode Verification")
+	fmt.Println("----------------------------------------------------")
+	for i, v := range list {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%-14s: %d (%s)\n", "chain", v.ChainID, v.Network)
+		fmt.Printf("%-14s: %s\n", "address", v.Address.Hex())
+		fmt.Printf("%-14s: %s\n", "status", v.Status)
+		fmt.Printf("%-14s: %s (%d bytes)\n", "on chain", v.OnChainHash.Hex(), v.OnChainSize)
+		fmt.Printf("%-14s: %s (%d bytes)\n", "compiled", v.CompiledHash.Hex(), v.CompiledSize)
+
+		if v.Status == verify.StatusMetadata {
+			fmt.Printf("%-14s: %s\n", "on chain meta", v.OnChainMetadata)
+			fmt.Printf("%-14s: %s\n", "compiled meta", v.CompiledMetadata)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/watch"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/urfave/cli/v2"
+)
+
+// event represents an ItemSet event for JSON output.
+type event struct {
+	Block  uint64      `json:"block"`
+	TxHash common.Hash `json:"tx_hash"`
+	Key    string      `json:"key"`
+	Value  string      `json:"value"`
+}
+
+func watchCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "watch",
+		Usage:     "stream ItemSet events as they are mined",
+		ArgsUsage: "[KEY...]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "poll",
+				Usage: "always poll, even when the endpoint supports subscriptions",
+			},
+		},
+		Action: watchItems,
+	}
+}
+
+func watchItems(c *cli.Context) error {
+	ctx := c.Context
+
+	// Logs go to stderr so JSON output on stdout stays parseable.
+	logOut := os.Stdout
+	if jsonOutput(c) {
+		logOut = os.Stderr
+	}
+	stdOut := log.NewLogger(log.NewTerminalHandlerWithLevel(logOut, log.LevelInfo, true))
+
+	backend, err := dial(c)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	id, err := contractID(c, backend)
+	if err != nil {
+		return err
+	}
+
+	cfg := watch.Config{
+		ForcePolling: c.Bool("poll"),
+		Log:          stdOut,
+	}
+
+	w, err := watch.New(backend, id, cfg)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]bool)
+	for _, key := range c.Args().Slice() {
+		keys[key] = true
+	}
+
+	// =========================================================================
+
+	sink := make(chan *basic.BasicItemSet, 100)
+
+	sub, err := w.WatchItemSet(&bind.WatchOpts{Context: ctx}, sink)
+	if err != nil {
+		return fmt.Errorf("watch item set: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	if !jsonOutput(c) {
+		fmt.Println("\nEvents")
+		fmt.Println("----------------------------------------------------")
+	}
+
+	for {
+		select {
+		case ev := <-sink:
+			if len(keys) > 0 && !keys[ev.Key] {
+				continue
+			}
+
+			if jsonOutput(c) {
+				e := event{
+					Block:  ev.Raw.BlockNumber,
+					TxHash: ev.Raw.TxHash,
+					Key:    ev.Key,
+					Value:  ev.Value.String(),
+				}
+				if err := printJSONLine(e); err != nil {
+					return err
+				}
+				continue
+			}
+
+			fmt.Printf("block[%d] tx[%s] %s = %v\n", ev.Raw.BlockNumber, ev.Raw.TxHash.Hex(), ev.Key, ev.Value)
+
+		case err := <-sub.Err():
+			return err
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/config"
+	"github.com/ardanlabs/smartcontract/business/basic/offline"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func main() {
+	signed := flag.String("tx", "zarf/ethereum/offline/signed.json", "signed transaction to broadcast")
+	flag.Parse()
+
+	if err := run(*signed); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// run only needs the signed transaction. No private key is required to
+// broadcast it.
+func run(signed string) error {
+	ctx := context.Background()
+
+	stx, err := offline.ReadSignedTx(signed)
+	if err != nil {
+		return err
+	}
+
+	tx, err := stx.Transaction()
+	if err != nil {
+		return err
+	}
+
+	summary, err := offline.Summarize(tx)
+	if err != nil {
+		return err
+	}
+	fmt.Print(summary)
+
+	// =========================================================================
+
+	settings, err := config.Load(config.Options{File: config.DefaultFile, FileOptional: true})
+	if err != nil {
+		return err
+	}
+
+	backend, err := settings.Dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	if tx.ChainId().Cmp(backend.ChainID()) != 0 {
+		return fmt.Errorf("transaction signed for chain %d, network is chain %d", tx.ChainId(), backend.ChainID())
+	}
+
+	converter := currency.NewDefaultConverter(basic.BasicMetaData.ABI)
+	if key, err := settings.Secrets().Secret(config.SecretCoinMarketCapKey); err == nil {
+		if c, err := currency.NewConverter(basic.BasicMetaData.ABI, key); err == nil {
+			converter = c
+		}
+	}
+
+	// =========================================================================
+
+	if err := backend.SendTransaction(ctx, tx); err != nil {
+		return fmt.Errorf("sending transaction: %w", err)
+	}
+	fmt.Print(converter.FmtTransaction(tx))
+
+	receipt, err := bind.WaitMined(ctx, backend, tx)
+	if err != nil {
+		return err
+	}
+	fmt.Print(converter.FmtTransactionReceipt(receipt, tx.GasPrice()))
+
+	if receipt.ContractAddress != (common.Address{}) {
+		fmt.Println("\nContract Details")
+		fmt.Println("----------------------------------------------------")
+		fmt.Println("contract id     :", receipt.ContractAddress.Hex())
+	}
+
+	return nil
+}
@@ -1,119 +0,0 @@
-package main
-
-import (
-	"context"
-	"fmt"
-	"math/big"
-	"os"
-
-	"github.com/ardanlabs/ethereum"
-	"github.com/ardanlabs/ethereum/currency"
-	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
-	"github.com/ethereum/go-ethereum/log"
-)
-
-const (
-	keyStoreFile     = "zarf/ethereum/keystore/UTC--2022-05-12T14-47-50.112225000Z--6327a38415c53ffb36c11db55ea74cc9cb4976fd"
-	passPhrase       = "123"
-	coinMarketCapKey = "a8cd12fb-d056-423f-877b-659046af0aa5"
-)
-
-func main() {
-	if err := run(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
-}
-
-func run() (err error) {
-
-	ctx := context.Background()
-
-	stdOut := log.NewLogger(log.NewTerminalHandlerWithLevel(os.Stdout, log.LevelInfo, true))
-
-	backend, err := ethereum.CreateDialedBackend(ctx, ethereum.NetworkLocalhost)
-	if err != nil {
-		return err
-	}
-	defer backend.Close()
-
-	privateKey, err := ethereum.PrivateKeyByKeyFile(keyStoreFile, passPhrase)
-	if err != nil {
-		return err
-	}
-
-	clt, err := ethereum.NewClient(backend, privateKey)
-	if err != nil {
-		return err
-	}
-
-	fmt.Println("\nInput Values")
-	fmt.Println("--------------------------------")
-	fmt.Println("fromAddress:", clt.Address())
-
-	// ==============================================================================
-
-	converter, err := currency.NewConverter(basic.BasicMetaData.ABI, coinMarketCapKey)
-	if err != nil {
-		converter = currency.NewDefaultConverter(basic.BasicMetaData.ABI)
-	}
-	oneETHToUSD, oneUSDToETH := converter.Values()
-
-	fmt.Println("oneETHToUSD:", oneETHToUSD)
-	fmt.Println("oneUSDToETH:", oneUSDToETH)
-
-	// =========================================================================
-
-	startingBalance, err := clt.Balance(ctx)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		endingBalance, dErr := clt.Balance(ctx)
-		if dErr != nil {
-			err = dErr
-			return
-		}
-		fmt.Print(converter.FmtBalanceSheet(startingBalance, endingBalance))
-	}()
-
-	// =========================================================================
-
-	const gasLimit = 1600000
-	const gasPriceGwei = 39.576
-	const valueGwei = 0.0
-	tranOpts, err := clt.NewTransactOpts(ctx, gasLimit, currency.GWei2Wei(big.NewFloat(gasPriceGwei)), big.NewFloat(valueGwei))
-	if err != nil {
-		return err
-	}
-
-	// =========================================================================
-
-	address, tx, _, err := basic.DeployBasic(tranOpts, clt.Backend)
-	if err != nil {
-		return err
-	}
-	fmt.Print(converter.FmtTransaction(tx))
-
-	fmt.Println("\nContract Details")
-	fmt.Println("----------------------------------------------------")
-	fmt.Println("contract id     :", address.Hex())
-
-	if err := os.WriteFile("zarf/ethereum/basic.cid", []byte(address.Hex()), 0644); err != nil {
-		return fmt.Errorf("exporting basic.cid file: %w", err)
-	}
-
-	// =========================================================================
-
-	fmt.Println("\nWaiting Logs")
-	fmt.Println("----------------------------------------------------")
-	log.SetDefault(stdOut)
-
-	receipt, err := clt.WaitMined(ctx, tx)
-	if err != nil {
-		return err
-	}
-	fmt.Print(converter.FmtTransactionReceipt(receipt, tx.GasPrice()))
-
-	return nil
-}
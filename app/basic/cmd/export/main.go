@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ardanlabs/smartcontract/business/basic/config"
+	"github.com/ardanlabs/smartcontract/business/basic/export"
+	"github.com/ardanlabs/smartcontract/business/basic/registry"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func main() {
+	format := flag.String("format", export.FormatCSV, "output format, csv or jsonl")
+	dir := flag.String("dir", "zarf/ethereum/export", "directory for the exported files and cursor")
+	from := flag.Uint64("from", 0, "first block to export, ignored when resuming past it")
+	to := flag.Uint64("to", 0, "last block to export, zero for the current head")
+	maxRows := flag.Int("max-rows", 100_000, "number of rows written to a file before starting the next")
+	flag.Parse()
+
+	cfg := export.Config{
+		Format:  *format,
+		Dir:     *dir,
+		MaxRows: *maxRows,
+	}
+
+	if err := run(cfg, *from, *to); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func run(cfg export.Config, from uint64, to uint64) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cfg.Log = log.NewLogger(log.NewTerminalHandlerWithLevel(os.Stdout, log.LevelInfo, true))
+
+	settings, err := config.Load(config.Options{File: config.DefaultFile, FileOptional: true})
+	if err != nil {
+		return err
+	}
+
+	backend, err := settings.Dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	// =========================================================================
+
+	reg, err := registry.Open(settings.Registry)
+	if err != nil {
+		return err
+	}
+
+	deployment, err := reg.Latest(backend.ChainID(), registry.ContractBasic)
+	if err != nil {
+		return err
+	}
+	contractID := deployment.Address.Hex()
+
+	e, err := export.New(backend, common.HexToAddress(contractID), cfg)
+	if err != nil {
+		return err
+	}
+
+	cur, found, err := e.Cursor()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\nInput Values")
+	fmt.Println("----------------------------------------------------")
+	fmt.Println("contractID:", contractID)
+	fmt.Println("format:", cfg.Format)
+	fmt.Println("directory:", cfg.Dir)
+	if found {
+		fmt.Println("resuming at block:", cur.NextBlock)
+	}
+
+	// =========================================================================
+
+	fmt.Println("\nExport Logs")
+	fmt.Println("----------------------------------------------------")
+
+	sum, err := e.Export(ctx, from, to)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\nExport Summary")
+	fmt.Println("----------------------------------------------------")
+	fmt.Println("blocks:", sum.From, "-", sum.To)
+	fmt.Println("rows  :", sum.Rows)
+	for _, name := range sum.Files {
+		fmt.Println("file  :", name)
+	}
+
+	return nil
+}
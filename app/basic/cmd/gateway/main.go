@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/config"
+	"github.com/ardanlabs/smartcontract/business/basic/gateway"
+	"github.com/ardanlabs/smartcontract/business/basic/metrics"
+	"github.com/ardanlabs/smartcontract/business/basic/registry"
+	"github.com/ardanlabs/smartcontract/foundation/sigauth"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8092", "address to serve the gateway on")
+	auth := flag.String("auth", "", "permissions file, requests must be signed when set")
+	flag.Parse()
+
+	if err := run(*addr, *auth); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func run(addr string, auth string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	stdOut := log.NewLogger(log.NewTerminalHandlerWithLevel(os.Stdout, log.LevelInfo, true))
+
+	settings, err := config.Load(config.Options{File: config.DefaultFile, FileOptional: true})
+	if err != nil {
+		return err
+	}
+
+	backend, err := settings.Dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	passPhrase, err := settings.Secrets().Secret(config.SecretPassphrase)
+	if err != nil {
+		return err
+	}
+
+	privateKey, err := ethereum.PrivateKeyByKeyFile(settings.KeyStore, passPhrase)
+	if err != nil {
+		return err
+	}
+
+	converter := currency.NewDefaultConverter(basic.BasicMetaData.ABI)
+	if key, err := settings.Secrets().Secret(config.SecretCoinMarketCapKey); err == nil {
+		if c, err := currency.NewConverter(basic.BasicMetaData.ABI, key); err == nil {
+			converter = c
+		}
+	}
+
+	m := metrics.New(converter)
+	m.RPCStats(backend.Stats)
+
+	clt, err := ethereum.NewClient(m.Backend(backend), privateKey)
+	if err != nil {
+		return err
+	}
+
+	// =========================================================================
+
+	reg, err := registry.Open(settings.Registry)
+	if err != nil {
+		return err
+	}
+
+	deployment, err := reg.Latest(backend.ChainID(), registry.ContractBasic)
+	if err != nil {
+		return err
+	}
+	contractID := deployment.Address.Hex()
+
+	g, err := gateway.New(clt, common.HexToAddress(contractID), converter, gateway.Config{Metrics: m, Log: stdOut})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\nInput Values")
+	fmt.Println("----------------------------------------------------")
+	fmt.Println("fromAddress:", clt.Address())
+	fmt.Println("contractID:", contractID)
+	fmt.Println("gateway:", "http://"+addr)
+	fmt.Println("metrics:", "http://"+addr+"/metrics")
+
+	// =========================================================================
+
+	handler := g.Handler()
+
+	if auth != "" {
+		perms, err := sigauth.LoadPermissions(auth)
+		if err != nil {
+			return err
+		}
+
+		handler = sigauth.New(sigauth.Config{Permissions: perms}).Middleware(sigauth.ReadWrite)(handler)
+		fmt.Println("permissions:", auth)
+	}
+
+	// Metrics are served outside the authentication so scrapers don't need
+	// to sign requests.
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m.Handler())
+	mux.Handle("/", handler)
+
+	srv := http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		serverErrors <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErrors:
+		return fmt.Errorf("gateway server: %w", err)
+
+	case <-ctx.Done():
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer shutdownCancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/config"
+	"github.com/ardanlabs/smartcontract/business/basic/indexer"
+	"github.com/ardanlabs/smartcontract/business/basic/metrics"
+	"github.com/ardanlabs/smartcontract/business/basic/registry"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func main() {
+	dbPath := flag.String("db", "zarf/ethereum/indexer", "directory for the index database")
+	deployBlock := flag.Uint64("deploy-block", 0, "block the contract was deployed in, zero for the registry's block")
+	batchSize := flag.Uint64("batch", 1000, "number of blocks to request at a time while backfilling")
+	metricsAddr := flag.String("metrics", "localhost:9091", "address to serve /metrics on, empty to disable")
+	flag.Parse()
+
+	if err := run(*dbPath, *deployBlock, *batchSize, *metricsAddr); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func run(dbPath string, deployBlock uint64, batchSize uint64, metricsAddr string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	stdOut := log.NewLogger(log.NewTerminalHandlerWithLevel(os.Stdout, log.LevelInfo, true))
+
+	settings, err := config.Load(config.Options{File: config.DefaultFile, FileOptional: true})
+	if err != nil {
+		return err
+	}
+
+	backend, err := settings.Dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	// =========================================================================
+
+	reg, err := registry.Open(settings.Registry)
+	if err != nil {
+		return err
+	}
+
+	deployment, err := reg.Latest(backend.ChainID(), registry.ContractBasic)
+	if err != nil {
+		return err
+	}
+	contractID := deployment.Address.Hex()
+
+	if deployBlock == 0 {
+		deployBlock = deployment.Block
+	}
+
+	fmt.Println("\nInput Values")
+	fmt.Println("----------------------------------------------------")
+	fmt.Println("contractID:", contractID)
+	fmt.Println("database:", dbPath)
+
+	// =========================================================================
+
+	cfg := indexer.Config{
+		ContractID:  common.HexToAddress(contractID),
+		DeployBlock: deployBlock,
+		BatchSize:   batchSize,
+		Log:         stdOut,
+	}
+
+	m := metrics.New(currency.NewDefaultConverter(basic.BasicMetaData.ABI))
+	m.RPCStats(backend.Stats)
+
+	idx, err := indexer.Open(dbPath, m.Backend(backend), cfg)
+	if err != nil {
+		return err
+	}
+	defer idx.Close()
+
+	m.IndexedItems(idx.Count)
+
+	if metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", m.Handler())
+
+		srv := http.Server{
+			Addr:              metricsAddr,
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				stdOut.Error("metrics", "ERROR", err)
+			}
+		}()
+		defer srv.Close()
+
+		fmt.Println("metrics:", "http://"+metricsAddr+"/metrics")
+	}
+
+	checkpoint, err := idx.Checkpoint()
+	if err != nil {
+		return err
+	}
+	fmt.Println("checkpoint:", checkpoint)
+
+	fmt.Println("\nIndexer Logs")
+	fmt.Println("----------------------------------------------------")
+
+	if err := idx.Run(ctx); err != nil {
+		return err
+	}
+
+	// =========================================================================
+
+	items, err := idx.Items()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\nIndexed Items")
+	fmt.Println("----------------------------------------------------")
+	for _, item := range items {
+		fmt.Printf("%-15s : %v (block %d)\n", item.Key, item.Value, item.Block)
+	}
+
+	return nil
+}
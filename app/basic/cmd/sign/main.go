@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/smartcontract/business/basic/config"
+	"github.com/ardanlabs/smartcontract/business/basic/offline"
+	"github.com/ardanlabs/smartcontract/business/basic/registry"
+)
+
+func main() {
+	request := flag.String("request", "zarf/ethereum/offline/request.json", "unsigned transaction request to sign")
+	out := flag.String("out", "zarf/ethereum/offline/signed.json", "file to write the signed transaction to")
+	flag.Parse()
+
+	if err := run(*request, *out); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// run never talks to a node. Everything needed to build the transaction,
+// including the chain id and nonce, comes from the request file.
+func run(request string, out string) error {
+	req, err := offline.ReadRequest(request)
+	if err != nil {
+		return err
+	}
+
+	settings, err := config.Load(config.Options{File: config.DefaultFile, FileOptional: true})
+	if err != nil {
+		return err
+	}
+
+	if req.To == "" && req.Method != offline.MethodDeploy {
+		reg, err := registry.Open(settings.Registry)
+		if err != nil {
+			return err
+		}
+
+		deployment, err := reg.Latest(big.NewInt(req.ChainID), registry.ContractBasic)
+		if err != nil {
+			return fmt.Errorf("request has no to address: %w", err)
+		}
+		req.To = deployment.Address.Hex()
+	}
+
+	passPhrase, err := settings.Secrets().Secret(config.SecretPassphrase)
+	if err != nil {
+		return err
+	}
+
+	privateKey, err := ethereum.PrivateKeyByKeyFile(settings.KeyStore, passPhrase)
+	if err != nil {
+		return err
+	}
+
+	stx, err := offline.Sign(req, privateKey)
+	if err != nil {
+		return err
+	}
+
+	if err := offline.WriteSignedTx(out, stx); err != nil {
+		return err
+	}
+
+	fmt.Print(stx.Summary)
+
+	fmt.Println("\nOutput")
+	fmt.Println("----------------------------------------------------")
+	fmt.Println("tx hash         :", stx.Hash)
+	fmt.Println("signed file     :", out)
+
+	return nil
+}
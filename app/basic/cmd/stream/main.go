@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ardanlabs/smartcontract/business/basic/config"
+	"github.com/ardanlabs/smartcontract/business/basic/registry"
+	"github.com/ardanlabs/smartcontract/business/basic/stream"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8091", "address to serve the event streams on")
+	anyOrigin := flag.Bool("any-origin", false, "allow websocket connections from pages on any origin")
+	flag.Parse()
+
+	if err := run(*addr, *anyOrigin); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func run(addr string, anyOrigin bool) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	stdOut := log.NewLogger(log.NewTerminalHandlerWithLevel(os.Stdout, log.LevelInfo, true))
+
+	settings, err := config.Load(config.Options{File: config.DefaultFile, FileOptional: true})
+	if err != nil {
+		return err
+	}
+
+	backend, err := settings.Dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	// =========================================================================
+
+	reg, err := registry.Open(settings.Registry)
+	if err != nil {
+		return err
+	}
+
+	deployment, err := reg.Latest(backend.ChainID(), registry.ContractBasic)
+	if err != nil {
+		return err
+	}
+	contractID := deployment.Address.Hex()
+
+	cfg := stream.Config{
+		Log: stdOut,
+	}
+
+	if anyOrigin {
+		cfg.CheckOrigin = func(r *http.Request) bool { return true }
+	}
+
+	s, err := stream.New(backend, common.HexToAddress(contractID), cfg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\nInput Values")
+	fmt.Println("----------------------------------------------------")
+	fmt.Println("contractID:", contractID)
+	fmt.Println("websocket:", "ws://"+addr+"/events/ws")
+	fmt.Println("sse:", "http://"+addr+"/events/sse")
+
+	// =========================================================================
+
+	srv := http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		serverErrors <- srv.ListenAndServe()
+	}()
+
+	streamErrors := make(chan error, 1)
+	go func() {
+		streamErrors <- s.Run(ctx)
+	}()
+
+	select {
+	case err := <-serverErrors:
+		cancel()
+		<-streamErrors
+		return fmt.Errorf("stream server: %w", err)
+
+	case err := <-streamErrors:
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer shutdownCancel()
+		srv.Shutdown(shutdownCtx)
+		return err
+	}
+}
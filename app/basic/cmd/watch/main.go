@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/config"
+	"github.com/ardanlabs/smartcontract/business/basic/registry"
+	"github.com/ardanlabs/smartcontract/business/basic/watch"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func main() {
+	network := flag.String("network", "", "node endpoint overriding the configured network, polling is used when it can't push notifications")
+	poll := flag.Bool("poll", false, "always poll, even when the endpoint supports subscriptions")
+	flag.Parse()
+
+	if err := run(*network, *poll); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func run(network string, poll bool) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	stdOut := log.NewLogger(log.NewTerminalHandlerWithLevel(os.Stdout, log.LevelInfo, true))
+
+	opts := config.Options{
+		File:         config.DefaultFile,
+		FileOptional: true,
+		Flags:        make(map[string]string),
+	}
+
+	if network != "" {
+		opts.Flags["network"] = network
+	}
+
+	settings, err := config.Load(opts)
+	if err != nil {
+		return err
+	}
+
+	backend, err := settings.Dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	// =========================================================================
+
+	reg, err := registry.Open(settings.Registry)
+	if err != nil {
+		return err
+	}
+
+	deployment, err := reg.Latest(backend.ChainID(), registry.ContractBasic)
+	if err != nil {
+		return err
+	}
+	contractID := deployment.Address.Hex()
+
+	cfg := watch.Config{
+		ForcePolling: poll,
+		Log:          stdOut,
+	}
+
+	w, err := watch.New(backend, common.HexToAddress(contractID), cfg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\nInput Values")
+	fmt.Println("----------------------------------------------------")
+	fmt.Println("network:", settings.Network)
+	fmt.Println("contractID:", contractID)
+
+	// =========================================================================
+
+	sink := make(chan *basic.BasicItemSet, 100)
+
+	sub, err := w.WatchItemSet(&bind.WatchOpts{Context: ctx}, sink)
+	if err != nil {
+		return fmt.Errorf("watch item set: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	fmt.Println("\nEvents")
+	fmt.Println("----------------------------------------------------")
+
+	for {
+		select {
+		case ev := <-sink:
+			fmt.Printf("block[%d] tx[%s] %s = %v\n", ev.Raw.BlockNumber, ev.Raw.TxHash.Hex(), ev.Key, ev.Value)
+
+		case err := <-sub.Err():
+			return err
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
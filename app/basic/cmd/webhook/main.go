@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/smartcontract/business/basic/config"
+	"github.com/ardanlabs/smartcontract/business/basic/registry"
+	"github.com/ardanlabs/smartcontract/business/basic/webhook"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func main() {
+	var hooks []string
+	flag.Func("hook", "url to deliver events to, can be repeated", func(s string) error {
+		hooks = append(hooks, s)
+		return nil
+	})
+	addr := flag.String("addr", "localhost:8090", "address for the status and registration api")
+	deadLetter := flag.String("dead-letter", "zarf/ethereum/webhook-dead-letters.jsonl", "file that receives failed deliveries")
+	flag.Parse()
+
+	if err := run(*addr, *deadLetter, hooks); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func run(addr string, deadLetter string, hooks []string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	stdOut := log.NewLogger(log.NewTerminalHandlerWithLevel(os.Stdout, log.LevelInfo, true))
+
+	settings, err := config.Load(config.Options{File: config.DefaultFile, FileOptional: true})
+	if err != nil {
+		return err
+	}
+
+	backend, err := settings.Dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	passPhrase, err := settings.Secrets().Secret(config.SecretPassphrase)
+	if err != nil {
+		return err
+	}
+
+	privateKey, err := ethereum.PrivateKeyByKeyFile(settings.KeyStore, passPhrase)
+	if err != nil {
+		return err
+	}
+
+	// =========================================================================
+
+	reg, err := registry.Open(settings.Registry)
+	if err != nil {
+		return err
+	}
+
+	deployment, err := reg.Latest(backend.ChainID(), registry.ContractBasic)
+	if err != nil {
+		return err
+	}
+	contractID := deployment.Address.Hex()
+
+	cfg := webhook.Config{
+		DeadLetterFile: deadLetter,
+		Log:            stdOut,
+	}
+
+	d, err := webhook.New(backend, common.HexToAddress(contractID), privateKey, cfg)
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range hooks {
+		if err := d.Register(hook); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("\nInput Values")
+	fmt.Println("----------------------------------------------------")
+	fmt.Println("contractID:", contractID)
+	fmt.Println("api:", addr)
+	for _, hook := range d.Hooks() {
+		fmt.Println("hook:", hook)
+	}
+
+	// =========================================================================
+
+	srv := http.Server{
+		Addr:              addr,
+		Handler:           d.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		serverErrors <- srv.ListenAndServe()
+	}()
+
+	dispatchErrors := make(chan error, 1)
+	go func() {
+		dispatchErrors <- d.Run(ctx)
+	}()
+
+	fmt.Println("\nWebhook Logs")
+	fmt.Println("----------------------------------------------------")
+
+	select {
+	case err := <-serverErrors:
+		cancel()
+		<-dispatchErrors
+		return fmt.Errorf("status api: %w", err)
+
+	case err := <-dispatchErrors:
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer shutdownCancel()
+		srv.Shutdown(shutdownCtx)
+		return err
+	}
+}
@@ -0,0 +1,3 @@
+// Package multicall is generated code for accessing the multicall smart contract
+
+package multicall
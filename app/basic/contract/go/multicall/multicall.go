@@ -0,0 +1,260 @@
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package multicall
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = errors.New
+	_ = big.NewInt
+	_ = strings.NewReader
+	_ = ethereum.NotFound
+	_ = bind.Bind
+	_ = common.Big1
+	_ = types.BloomLookup
+	_ = event.NewSubscription
+	_ = abi.ConvertType
+)
+
+// MulticallCall is an auto generated low-level Go binding around an user-defined struct.
+type MulticallCall struct {
+	Target   common.Address
+	CallData []byte
+}
+
+// MulticallResult is an auto generated low-level Go binding around an user-defined struct.
+type MulticallResult struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// MulticallMetaData contains all meta data concerning the Multicall contract.
+var MulticallMetaData = &bind.MetaData{
+	ABI: "[{\"inputs\":[{\"components\":[{\"internalType\":\"address\",\"name\":\"target\",\"type\":\"address\"},{\"internalType\":\"bytes\",\"name\":\"callData\",\"type\":\"bytes\"}],\"internalType\":\"structMulticall.Call[]\",\"name\":\"calls\",\"type\":\"tuple[]\"}],\"name\":\"TryAggregate\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"components\":[{\"internalType\":\"bool\",\"name\":\"success\",\"type\":\"bool\"},{\"internalType\":\"bytes\",\"name\":\"returnData\",\"type\":\"bytes\"}],\"internalType\":\"structMulticall.Result[]\",\"name\":\"results\",\"type\":\"tuple[]\"}],\"stateMutability\":\"view\",\"type\":\"function\"}]",
+	Bin: "0x3415600957600080fd5b6100c3806100176000396000f33461001d576004361061001d5760003560e01c6392af33a114610022575b600080fd5b6004356004018035436000526040602052806040528060051b60600160005b828110156100be57606082038160051b6060015283602001808260051b0135018060200135810180358082602001866060013760006000828760600186355afa925050508252604082602001523d82604001523d6000836060013e60003d830160600152601f3d0160051c60051b60600182019150600101610041565b816000f3",
+}
+
+// MulticallABI is the input ABI used to generate the binding from.
+// Deprecated: Use MulticallMetaData.ABI instead.
+var MulticallABI = MulticallMetaData.ABI
+
+// MulticallBin is the compiled bytecode used for deploying new contracts.
+// Deprecated: Use MulticallMetaData.Bin instead.
+var MulticallBin = MulticallMetaData.Bin
+
+// DeployMulticall deploys a new Ethereum contract, binding an instance of Multicall to it.
+func DeployMulticall(auth *bind.TransactOpts, backend bind.ContractBackend) (common.Address, *types.Transaction, *Multicall, error) {
+	parsed, err := MulticallMetaData.GetAbi()
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	if parsed == nil {
+		return common.Address{}, nil, nil, errors.New("GetABI returned nil")
+	}
+
+	address, tx, contract, err := bind.DeployContract(auth, *parsed, common.FromHex(MulticallBin), backend)
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	return address, tx, &Multicall{MulticallCaller: MulticallCaller{contract: contract}, MulticallTransactor: MulticallTransactor{contract: contract}, MulticallFilterer: MulticallFilterer{contract: contract}}, nil
+}
+
+// Multicall is an auto generated Go binding around an Ethereum contract.
+type Multicall struct {
+	MulticallCaller     // Read-only binding to the contract
+	MulticallTransactor // Write-only binding to the contract
+	MulticallFilterer   // Log filterer for contract events
+}
+
+// MulticallCaller is an auto generated read-only Go binding around an Ethereum contract.
+type MulticallCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// MulticallTransactor is an auto generated write-only Go binding around an Ethereum contract.
+type MulticallTransactor struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// MulticallFilterer is an auto generated log filtering Go binding around an Ethereum contract events.
+type MulticallFilterer struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// MulticallSession is an auto generated Go binding around an Ethereum contract,
+// with pre-set call and transact options.
+type MulticallSession struct {
+	Contract     *Multicall        // Generic contract binding to set the session for
+	CallOpts     bind.CallOpts     // Call options to use throughout this session
+	TransactOpts bind.TransactOpts // Transaction auth options to use throughout this session
+}
+
+// MulticallCallerSession is an auto generated read-only Go binding around an Ethereum contract,
+// with pre-set call options.
+type MulticallCallerSession struct {
+	Contract *MulticallCaller // Generic contract caller binding to set the session for
+	CallOpts bind.CallOpts    // Call options to use throughout this session
+}
+
+// MulticallTransactorSession is an auto generated write-only Go binding around an Ethereum contract,
+// with pre-set transact options.
+type MulticallTransactorSession struct {
+	Contract     *MulticallTransactor // Generic contract transactor binding to set the session for
+	TransactOpts bind.TransactOpts    // Transaction auth options to use throughout this session
+}
+
+// MulticallRaw is an auto generated low-level Go binding around an Ethereum contract.
+type MulticallRaw struct {
+	Contract *Multicall // Generic contract binding to access the raw methods on
+}
+
+// MulticallCallerRaw is an auto generated low-level read-only Go binding around an Ethereum contract.
+type MulticallCallerRaw struct {
+	Contract *MulticallCaller // Generic read-only contract binding to access the raw methods on
+}
+
+// MulticallTransactorRaw is an auto generated low-level write-only Go binding around an Ethereum contract.
+type MulticallTransactorRaw struct {
+	Contract *MulticallTransactor // Generic write-only contract binding to access the raw methods on
+}
+
+// NewMulticall creates a new instance of Multicall, bound to a specific deployed contract.
+func NewMulticall(address common.Address, backend bind.ContractBackend) (*Multicall, error) {
+	contract, err := bindMulticall(address, backend, backend, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &Multicall{MulticallCaller: MulticallCaller{contract: contract}, MulticallTransactor: MulticallTransactor{contract: contract}, MulticallFilterer: MulticallFilterer{contract: contract}}, nil
+}
+
+// NewMulticallCaller creates a new read-only instance of Multicall, bound to a specific deployed contract.
+func NewMulticallCaller(address common.Address, caller bind.ContractCaller) (*MulticallCaller, error) {
+	contract, err := bindMulticall(address, caller, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &MulticallCaller{contract: contract}, nil
+}
+
+// NewMulticallTransactor creates a new write-only instance of Multicall, bound to a specific deployed contract.
+func NewMulticallTransactor(address common.Address, transactor bind.ContractTransactor) (*MulticallTransactor, error) {
+	contract, err := bindMulticall(address, nil, transactor, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &MulticallTransactor{contract: contract}, nil
+}
+
+// NewMulticallFilterer creates a new log filterer instance of Multicall, bound to a specific deployed contract.
+func NewMulticallFilterer(address common.Address, filterer bind.ContractFilterer) (*MulticallFilterer, error) {
+	contract, err := bindMulticall(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
+	return &MulticallFilterer{contract: contract}, nil
+}
+
+// bindMulticall binds a generic wrapper to an already deployed contract.
+func bindMulticall(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := MulticallMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, *parsed, caller, transactor, filterer), nil
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_Multicall *MulticallRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _Multicall.Contract.MulticallCaller.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_Multicall *MulticallRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _Multicall.Contract.MulticallTransactor.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_Multicall *MulticallRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _Multicall.Contract.MulticallTransactor.contract.Transact(opts, method, params...)
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_Multicall *MulticallCallerRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _Multicall.Contract.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_Multicall *MulticallTransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _Multicall.Contract.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_Multicall *MulticallTransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _Multicall.Contract.contract.Transact(opts, method, params...)
+}
+
+// TryAggregate is a free data retrieval call binding the contract method 0x92af33a1.
+//
+// Solidity: function TryAggregate((address,bytes)[] calls) view returns(uint256 blockNumber, (bool,bytes)[] results)
+func (_Multicall *MulticallCaller) TryAggregate(opts *bind.CallOpts, calls []MulticallCall) (struct {
+	BlockNumber *big.Int
+	Results     []MulticallResult
+}, error) {
+	var out []interface{}
+	err := _Multicall.contract.Call(opts, &out, "TryAggregate", calls)
+
+	outstruct := new(struct {
+		BlockNumber *big.Int
+		Results     []MulticallResult
+	})
+	if err != nil {
+		return *outstruct, err
+	}
+
+	outstruct.BlockNumber = *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+	outstruct.Results = *abi.ConvertType(out[1], new([]MulticallResult)).(*[]MulticallResult)
+
+	return *outstruct, err
+
+}
+
+// TryAggregate is a free data retrieval call binding the contract method 0x92af33a1.
+//
+// Solidity: function TryAggregate((address,bytes)[] calls) view returns(uint256 blockNumber, (bool,bytes)[] results)
+func (_Multicall *MulticallSession) TryAggregate(calls []MulticallCall) (struct {
+	BlockNumber *big.Int
+	Results     []MulticallResult
+}, error) {
+	return _Multicall.Contract.TryAggregate(&_Multicall.CallOpts, calls)
+}
+
+// TryAggregate is a free data retrieval call binding the contract method 0x92af33a1.
+//
+// Solidity: function TryAggregate((address,bytes)[] calls) view returns(uint256 blockNumber, (bool,bytes)[] results)
+func (_Multicall *MulticallCallerSession) TryAggregate(calls []MulticallCall) (struct {
+	BlockNumber *big.Int
+	Results     []MulticallResult
+}, error) {
+	return _Multicall.Contract.TryAggregate(&_Multicall.CallOpts, calls)
+}
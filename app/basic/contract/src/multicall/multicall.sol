@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: UNLICENSED
+pragma solidity ^0.8.0;
+
+// Multicall is a contract which aggregates many read-only calls into a
+// single call that executes against one block.
+contract Multicall {
+
+    // Call represents a single call to execute.
+    struct Call {
+        address target;
+        bytes callData;
+    }
+
+    // Result represents the outcome of a single call. When the call fails,
+    // returnData holds the revert data.
+    struct Result {
+        bool success;
+        bytes returnData;
+    }
+
+    // TryAggregate is an external-only function which executes every call
+    // and returns the block number the calls executed against along with
+    // the result of each call. A failing call does not fail the aggregate.
+    function TryAggregate(Call[] calldata calls) external view returns (uint256 blockNumber, Result[] memory results) {
+        blockNumber = block.number;
+        results = new Result[](calls.length);
+
+        for (uint256 i = 0; i < calls.length; i++) {
+            (bool success, bytes memory ret) = calls[i].target.staticcall(calls[i].callData);
+            results[i] = Result(success, ret);
+        }
+    }
+}
@@ -0,0 +1,335 @@
+// Package audit provides support for checking that the key/value map rebuilt
+// from the ItemSet events matches what the basic contract holds on chain.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Backend represents the node behavior the auditor needs. Blocks and
+// receipts are used to find the transactions behind a divergence.
+type Backend interface {
+	bind.ContractBackend
+	bind.DeployBackend
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+}
+
+// Config represents the settings for the auditor.
+type Config struct {
+
+	// DeployBlock is the block the contract was deployed in. Replaying
+	// starts here.
+	DeployBlock uint64
+
+	// BatchSize is the number of blocks requested with each FilterItemSet
+	// call. A value of zero defaults to 1000.
+	BatchSize uint64
+
+	// Keys are checked on chain even if no event was found for them, which
+	// catches values written without an event.
+	Keys []string
+
+	// ScanLimit caps the number of blocks searched for the transactions
+	// behind a divergence. A value of zero defaults to 10,000.
+	ScanLimit uint64
+
+	// Log receives progress. When nil, the root logger is used.
+	Log log.Logger
+}
+
+// Change represents a single ItemSet event for a key.
+type Change struct {
+	Value    *big.Int    `json:"value"`
+	Block    uint64      `json:"block"`
+	TxHash   common.Hash `json:"tx_hash"`
+	LogIndex uint        `json:"log_index"`
+}
+
+// Divergence represents a key whose replayed value doesn't match the value
+// returned by Items at the audited block.
+type Divergence struct {
+	Key      string   `json:"key"`
+	Replayed *big.Int `json:"replayed"`
+	OnChain  *big.Int `json:"on_chain"`
+
+	// History holds every event for the key in order. The last entry is the
+	// transaction the replayed value came from.
+	History []Change `json:"history"`
+
+	// Transactions holds the successful SetItem calls for the key after the
+	// last replayed change whose events were not replayed. These explain
+	// the on-chain value.
+	Transactions []common.Hash `json:"transactions"`
+}
+
+// Report represents the result of an audit.
+type Report struct {
+	Block       uint64       `json:"block"`
+	Events      int          `json:"events"`
+	Keys        int          `json:"keys"`
+	Divergences []Divergence `json:"divergences"`
+}
+
+// Consistent reports whether every key matched.
+func (r Report) Consistent() bool {
+	return len(r.Divergences) == 0
+}
+
+// =============================================================================
+
+// Auditor compares the replayed ItemSet events with the contract's state.
+type Auditor struct {
+	backend    Backend
+	basic      *basic.Basic
+	contractID common.Address
+	abi        *abi.ABI
+	cfg        Config
+}
+
+// New constructs an auditor for the basic contract at the specified address.
+func New(backend Backend, contractID common.Address, cfg Config) (*Auditor, error) {
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 1000
+	}
+
+	if cfg.ScanLimit == 0 {
+		cfg.ScanLimit = 10_000
+	}
+
+	if cfg.Log == nil {
+		cfg.Log = log.Root()
+	}
+
+	b, err := basic.NewBasic(contractID, backend)
+	if err != nil {
+		return nil, fmt.Errorf("new basic: %w", err)
+	}
+
+	contractABI, err := basic.BasicMetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("parse abi: %w", err)
+	}
+
+	a := Auditor{
+		backend:    backend,
+		basic:      b,
+		contractID: contractID,
+		abi:        contractABI,
+		cfg:        cfg,
+	}
+
+	return &a, nil
+}
+
+// Audit replays the events up to and including the specified block and
+// compares each key with the value Items returns at that same block, so
+// writes landing while the audit runs don't show up as divergences.
+func (a *Auditor) Audit(ctx context.Context, block uint64) (Report, error) {
+	history, events, err := a.replay(ctx, block)
+	if err != nil {
+		return Report{}, err
+	}
+
+	for _, key := range a.cfg.Keys {
+		if _, exists := history[key]; !exists {
+			history[key] = nil
+		}
+	}
+
+	keys := make([]string, 0, len(history))
+	for key := range history {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	report := Report{
+		Block:  block,
+		Events: events,
+		Keys:   len(keys),
+	}
+
+	// =========================================================================
+
+	opts := bind.CallOpts{
+		Context:     ctx,
+		BlockNumber: new(big.Int).SetUint64(block),
+	}
+
+	for _, key := range keys {
+		onChain, err := a.basic.Items(&opts, key)
+		if err != nil {
+			return Report{}, fmt.Errorf("items %q at %d: %w", key, block, err)
+		}
+
+		changes := history[key]
+
+		replayed := new(big.Int)
+		if len(changes) > 0 {
+			replayed = changes[len(changes)-1].Value
+		}
+
+		if replayed.Cmp(onChain) == 0 {
+			continue
+		}
+
+		div := Divergence{
+			Key:      key,
+			Replayed: replayed,
+			OnChain:  onChain,
+			History:  changes,
+		}
+		report.Divergences = append(report.Divergences, div)
+
+		a.cfg.Log.Warn("audit", "status", "divergence", "key", key, "replayed", replayed, "onchain", onChain)
+	}
+
+	if err := a.explain(ctx, block, history, report.Divergences); err != nil {
+		return Report{}, err
+	}
+
+	return report, nil
+}
+
+// replay returns the changes for every key found in the events up to the
+// block, along with the number of events.
+func (a *Auditor) replay(ctx context.Context, block uint64) (map[string][]Change, int, error) {
+	history := make(map[string][]Change)
+	var events int
+
+	for start := a.cfg.DeployBlock; start <= block; start += a.cfg.BatchSize {
+		end := min(start+a.cfg.BatchSize-1, block)
+
+		iter, err := a.basic.FilterItemSet(&bind.FilterOpts{Start: start, End: &end, Context: ctx})
+		if err != nil {
+			return nil, 0, fmt.Errorf("filter %d-%d: %w", start, end, err)
+		}
+
+		for iter.Next() {
+			ev := iter.Event
+
+			change := Change{
+				Value:    ev.Value,
+				Block:    ev.Raw.BlockNumber,
+				TxHash:   ev.Raw.TxHash,
+				LogIndex: ev.Raw.Index,
+			}
+			history[ev.Key] = append(history[ev.Key], change)
+			events++
+		}
+
+		err = iter.Error()
+		iter.Close()
+		if err != nil {
+			return nil, 0, fmt.Errorf("filter %d-%d: %w", start, end, err)
+		}
+
+		a.cfg.Log.Info("audit", "status", "replayed", "from", start, "to", end, "events", events)
+	}
+
+	return history, events, nil
+}
+
+// explain searches the blocks after the oldest last replayed change for
+// SetItem calls that account for each divergence.
+func (a *Auditor) explain(ctx context.Context, block uint64, history map[string][]Change, divs []Divergence) error {
+	if len(divs) == 0 {
+		return nil
+	}
+
+	// Only changes that were replayed can be ruled out.
+	replayed := make(map[common.Hash]bool)
+	for _, changes := range history {
+		for _, change := range changes {
+			replayed[change.TxHash] = true
+		}
+	}
+
+	// Each key only needs the blocks after its own last replayed change.
+	after := make(map[string]uint64)
+	start := block
+	for _, div := range divs {
+		from := a.cfg.DeployBlock
+		if len(div.History) > 0 {
+			from = div.History[len(div.History)-1].Block
+		}
+		after[div.Key] = from
+		start = min(start, from)
+	}
+
+	if block-start >= a.cfg.ScanLimit {
+		start = block - a.cfg.ScanLimit + 1
+	}
+
+	// =========================================================================
+
+	index := make(map[string]int)
+	for i, div := range divs {
+		index[div.Key] = i
+	}
+
+	for number := start; number <= block; number++ {
+		blk, err := a.backend.BlockByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return fmt.Errorf("block %d: %w", number, err)
+		}
+
+		for _, tx := range blk.Transactions() {
+			if tx.To() == nil || *tx.To() != a.contractID || replayed[tx.Hash()] {
+				continue
+			}
+
+			key, ok := a.setItemKey(tx.Data())
+			if !ok {
+				continue
+			}
+
+			i, exists := index[key]
+			if !exists || number < after[key] {
+				continue
+			}
+
+			receipt, err := a.backend.TransactionReceipt(ctx, tx.Hash())
+			if err != nil {
+				return fmt.Errorf("receipt %s: %w", tx.Hash().Hex(), err)
+			}
+
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				continue
+			}
+
+			divs[i].Transactions = append(divs[i].Transactions, tx.Hash())
+		}
+	}
+
+	return nil
+}
+
+// setItemKey returns the key from SetItem call data.
+func (a *Auditor) setItemKey(data []byte) (string, bool) {
+	if len(data) < 4 {
+		return "", false
+	}
+
+	method, err := a.abi.MethodById(data[:4])
+	if err != nil || method.Name != "SetItem" {
+		return "", false
+	}
+
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil || len(args) == 0 {
+		return "", false
+	}
+
+	key, ok := args[0].(string)
+	return key, ok
+}
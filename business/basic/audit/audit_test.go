@@ -0,0 +1,158 @@
+package audit_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/audit"
+	geth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// lossyBackend drops the logs for one transaction, like an off-chain system
+// that missed an event.
+type lossyBackend struct {
+	*ethereum.SimulatedBackend
+	drop common.Hash
+}
+
+func (b *lossyBackend) FilterLogs(ctx context.Context, query geth.FilterQuery) ([]types.Log, error) {
+	logs, err := b.SimulatedBackend.FilterLogs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var kept []types.Log
+	for _, l := range logs {
+		if l.TxHash != b.drop {
+			kept = append(kept, l)
+		}
+	}
+
+	return kept, nil
+}
+
+// =============================================================================
+
+func TestAudit(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	clt, err := ethereum.NewClient(backend, backend.PrivateKeys[0])
+	if err != nil {
+		t.Fatalf("unable to create ethereum api: %s", err)
+	}
+
+	// =========================================================================
+
+	const gasLimit = 1600000
+	valueGwei := big.NewFloat(0.0)
+	gasPrice := currency.GWei2Wei(big.NewFloat(39.576))
+
+	transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+	if err != nil {
+		t.Fatalf("unable to create opts for deploy: %s", err)
+	}
+
+	contractID, tx, testBasic, err := basic.DeployBasic(transOpts, clt.Backend)
+	if err != nil {
+		t.Fatalf("unable to deploy basic: %s", err)
+	}
+
+	if _, err := clt.WaitMined(ctx, tx); err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+
+	setItem := func(key string, value int64) *types.Receipt {
+		t.Helper()
+
+		transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+		if err != nil {
+			t.Fatalf("unable to create transaction opts for setItems: %s", err)
+		}
+
+		tx, err := testBasic.SetItem(transOpts, key, big.NewInt(value))
+		if err != nil {
+			t.Fatalf("should be able to set item: %s", err)
+		}
+
+		receipt, err := clt.WaitMined(ctx, tx)
+		if err != nil {
+			t.Fatalf("waiting for set item: %s", err)
+		}
+
+		return receipt
+	}
+
+	// =========================================================================
+
+	setItem("bill", 1)
+	setItem("jill", 2)
+	first := setItem("bill", 3)
+	missed := setItem("bill", 4)
+	audited := missed.BlockNumber.Uint64()
+
+	// Writes after the audited block must not be reported.
+	setItem("jill", 5)
+
+	cfg := audit.Config{
+		Keys: []string{"nobody"},
+		Log:  log.NewLogger(log.DiscardHandler()),
+	}
+
+	a, err := audit.New(backend, contractID, cfg)
+	if err != nil {
+		t.Fatalf("unable to create auditor: %s", err)
+	}
+
+	report, err := a.Audit(ctx, audited)
+	if err != nil {
+		t.Fatalf("should be able to audit: %s", err)
+	}
+
+	if !report.Consistent() || report.Events != 4 || report.Keys != 3 {
+		t.Fatalf("should be consistent, got %+v", report)
+	}
+
+	// =========================================================================
+
+	lossy := lossyBackend{SimulatedBackend: backend, drop: missed.TxHash}
+
+	a, err = audit.New(&lossy, contractID, cfg)
+	if err != nil {
+		t.Fatalf("unable to create auditor: %s", err)
+	}
+
+	report, err = a.Audit(ctx, audited)
+	if err != nil {
+		t.Fatalf("should be able to audit: %s", err)
+	}
+
+	if len(report.Divergences) != 1 {
+		t.Fatalf("should find one divergence, got %+v", report.Divergences)
+	}
+
+	div := report.Divergences[0]
+	if div.Key != "bill" || div.Replayed.Int64() != 3 || div.OnChain.Int64() != 4 {
+		t.Fatalf("wrong divergence, got %+v", div)
+	}
+
+	if len(div.History) != 2 || div.History[1].TxHash != first.TxHash {
+		t.Fatalf("history should end at the last replayed transaction, got %+v", div.History)
+	}
+
+	if len(div.Transactions) != 1 || div.Transactions[0] != missed.TxHash {
+		t.Fatalf("should explain the divergence with the missed transaction, got %v", div.Transactions)
+	}
+}
@@ -0,0 +1,169 @@
+// Package bulk provides support for reading many values from the basic
+// contract in a single call by way of the multicall contract.
+package bulk
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/multicall"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrCallFailed is returned for a call that failed without a revert reason.
+var ErrCallFailed = errors.New("call failed")
+
+// Call represents a single read-only call to the basic contract.
+type Call struct {
+	Method string
+	Args   []any
+}
+
+// Result represents the outcome of a single call. Values holds the decoded
+// outputs of the method when the call succeeded.
+type Result struct {
+	Call   Call
+	Values []any
+	Err    error
+}
+
+// Item represents the value read for a single key.
+type Item struct {
+	Key   string
+	Value *big.Int
+	Err   error
+}
+
+// =============================================================================
+
+// Reader batches reads of the basic contract into a single call to the
+// multicall contract, so every read executes against the same block.
+type Reader struct {
+	multicall *multicall.MulticallCaller
+	basicID   common.Address
+	basicABI  *abi.ABI
+}
+
+// NewReader constructs a reader for the basic contract at basicID using the
+// multicall contract at multicallID.
+func NewReader(caller bind.ContractCaller, multicallID common.Address, basicID common.Address) (*Reader, error) {
+	mc, err := multicall.NewMulticallCaller(multicallID, caller)
+	if err != nil {
+		return nil, fmt.Errorf("new multicall: %w", err)
+	}
+
+	basicABI, err := basic.BasicMetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("basic abi: %w", err)
+	}
+
+	r := Reader{
+		multicall: mc,
+		basicID:   basicID,
+		basicABI:  basicABI,
+	}
+
+	return &r, nil
+}
+
+// Aggregate executes the calls in a single call to the multicall contract and
+// returns the block number they executed against. A failing call is reported
+// in its result and does not fail the other calls.
+func (r *Reader) Aggregate(opts *bind.CallOpts, calls []Call) (uint64, []Result, error) {
+	mcCalls := make([]multicall.MulticallCall, len(calls))
+	for i, call := range calls {
+		data, err := r.basicABI.Pack(call.Method, call.Args...)
+		if err != nil {
+			return 0, nil, fmt.Errorf("pack call[%d] %s: %w", i, call.Method, err)
+		}
+
+		mcCalls[i] = multicall.MulticallCall{
+			Target:   r.basicID,
+			CallData: data,
+		}
+	}
+
+	out, err := r.multicall.TryAggregate(opts, mcCalls)
+	if err != nil {
+		return 0, nil, fmt.Errorf("aggregate: %w", err)
+	}
+
+	if len(out.Results) != len(calls) {
+		return 0, nil, fmt.Errorf("aggregate: got %d results for %d calls", len(out.Results), len(calls))
+	}
+
+	results := make([]Result, len(calls))
+	for i, call := range calls {
+		results[i] = r.decode(call, out.Results[i])
+	}
+
+	return out.BlockNumber.Uint64(), results, nil
+}
+
+// Items reads the value for every key in a single call and returns the block
+// number the values were read at.
+func (r *Reader) Items(opts *bind.CallOpts, keys []string) (uint64, []Item, error) {
+	calls := make([]Call, len(keys))
+	for i, key := range keys {
+		calls[i] = Call{
+			Method: "Items",
+			Args:   []any{key},
+		}
+	}
+
+	block, results, err := r.Aggregate(opts, calls)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	items := make([]Item, len(keys))
+	for i, key := range keys {
+		items[i] = Item{
+			Key: key,
+			Err: results[i].Err,
+		}
+
+		if results[i].Err != nil {
+			continue
+		}
+
+		items[i].Value = *abi.ConvertType(results[i].Values[0], new(*big.Int)).(**big.Int)
+	}
+
+	return block, items, nil
+}
+
+// =============================================================================
+
+// decode converts the raw result of a call into its decoded values or the
+// reason the call failed.
+func (r *Reader) decode(call Call, res multicall.MulticallResult) Result {
+	result := Result{
+		Call: call,
+	}
+
+	if !res.Success {
+		reason, err := abi.UnpackRevert(res.ReturnData)
+		switch {
+		case err != nil:
+			result.Err = ErrCallFailed
+		default:
+			result.Err = fmt.Errorf("%w: %s", ErrCallFailed, reason)
+		}
+		return result
+	}
+
+	values, err := r.basicABI.Unpack(call.Method, res.ReturnData)
+	if err != nil {
+		result.Err = fmt.Errorf("unpack %s: %w", call.Method, err)
+		return result
+	}
+
+	result.Values = values
+
+	return result
+}
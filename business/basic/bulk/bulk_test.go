@@ -0,0 +1,152 @@
+package bulk_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/multicall"
+	"github.com/ardanlabs/smartcontract/business/basic/bulk"
+)
+
+func TestBulk(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	clt, err := ethereum.NewClient(backend, backend.PrivateKeys[0])
+	if err != nil {
+		t.Fatalf("unable to create ethereum api: %s", err)
+	}
+
+	// =========================================================================
+
+	const gasLimit = 1600000
+	valueGwei := big.NewFloat(0.0)
+	gasPrice := currency.GWei2Wei(big.NewFloat(39.576))
+	transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+	if err != nil {
+		t.Fatalf("unable to create opts for deploy: %s", err)
+	}
+
+	basicID, tx, testBasic, err := basic.DeployBasic(transOpts, clt.Backend)
+	if err != nil {
+		t.Fatalf("unable to deploy basic: %s", err)
+	}
+
+	if _, err := clt.WaitMined(ctx, tx); err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+
+	transOpts, err = clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+	if err != nil {
+		t.Fatalf("unable to create opts for deploy: %s", err)
+	}
+
+	multicallID, tx, _, err := multicall.DeployMulticall(transOpts, clt.Backend)
+	if err != nil {
+		t.Fatalf("unable to deploy multicall: %s", err)
+	}
+
+	if _, err := clt.WaitMined(ctx, tx); err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+
+	// =========================================================================
+
+	const items = 10
+	keys := make([]string, items+1)
+
+	for i := range items {
+		transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+		if err != nil {
+			t.Fatalf("unable to create transaction opts for setItems: %s", err)
+		}
+
+		keys[i] = fmt.Sprintf("key-%d", i)
+
+		tx, err := testBasic.SetItem(transOpts, keys[i], big.NewInt(int64(i*100)))
+		if err != nil {
+			t.Fatalf("should be able to set item: %s", err)
+		}
+
+		if _, err := clt.WaitMined(ctx, tx); err != nil {
+			t.Fatalf("waiting for setitem: %s", err)
+		}
+	}
+	keys[items] = "unknown"
+
+	// =========================================================================
+
+	reader, err := bulk.NewReader(clt.Backend, multicallID, basicID)
+	if err != nil {
+		t.Fatalf("unable to create bulk reader: %s", err)
+	}
+
+	header, err := clt.HeaderByNumber(ctx, nil)
+	if err != nil {
+		t.Fatalf("unable to read head block: %s", err)
+	}
+
+	block, got, err := reader.Items(nil, keys)
+	if err != nil {
+		t.Fatalf("should be able to read items: %s", err)
+	}
+
+	if block != header.Number.Uint64() {
+		t.Fatalf("wrong block, got %d exp %d", block, header.Number.Uint64())
+	}
+
+	for i, item := range got {
+		if item.Err != nil {
+			t.Fatalf("should be able to read item %s: %s", item.Key, item.Err)
+		}
+
+		exp := big.NewInt(int64(i * 100))
+		if i == items {
+			exp = big.NewInt(0)
+		}
+
+		if item.Key != keys[i] || item.Value.Cmp(exp) != 0 {
+			t.Fatalf("wrong item, got %s=%s exp %s=%s", item.Key, item.Value, keys[i], exp)
+		}
+	}
+
+	// =========================================================================
+
+	_, results, err := reader.Aggregate(nil, []bulk.Call{{Method: "Version"}})
+	if err != nil {
+		t.Fatalf("should be able to aggregate: %s", err)
+	}
+
+	if ver := results[0].Values[0].(string); ver != "1.1" {
+		t.Fatalf("should be able to get the correct version, got %s exp %s", ver, "1.1")
+	}
+
+	// =========================================================================
+
+	badReader, err := bulk.NewReader(clt.Backend, multicallID, multicallID)
+	if err != nil {
+		t.Fatalf("unable to create bulk reader: %s", err)
+	}
+
+	_, got, err = badReader.Items(nil, keys[:2])
+	if err != nil {
+		t.Fatalf("a failing call should not fail the aggregate: %s", err)
+	}
+
+	for _, item := range got {
+		if !errors.Is(item.Err, bulk.ErrCallFailed) {
+			t.Fatalf("should report the failed call for %s, got %v", item.Key, item.Err)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Package cache provides a read cache for the basic contract's Items that is
+// kept consistent by watching the ItemSet events emitted by the contract.
+package cache
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/watch"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Stats represents the counters maintained by the cache.
+type Stats struct {
+	Hits          uint64 // Reads served from memory.
+	Misses        uint64 // Reads that had to call the contract and filled the cache.
+	Direct        uint64 // Reads that bypassed the cache completely.
+	Invalidations uint64 // Entries removed because of an ItemSet event.
+	Subscriptions uint64 // Subscriptions established, including the first.
+}
+
+// =============================================================================
+
+// Cache serves reads for the Items mapping from memory. Entries are removed
+// as ItemSet events arrive, so the next read fetches the new value. When the
+// event subscription drops, the cache is emptied and every read is sent to
+// the contract until a new subscription is established.
+type Cache struct {
+	backend bind.ContractBackend
+	caller  *basic.BasicCaller
+	watcher *watch.Watcher
+
+	mu    sync.RWMutex
+	items map[string]*big.Int
+	block uint64
+	gen   uint64
+	live  bool
+
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+	direct        atomic.Uint64
+	invalidations atomic.Uint64
+	subscriptions atomic.Uint64
+
+	shutdown chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New constructs a cache for the basic contract at the specified address and
+// starts watching for ItemSet events.
+func New(backend bind.ContractBackend, contractID common.Address) (*Cache, error) {
+	caller, err := basic.NewBasicCaller(contractID, backend)
+	if err != nil {
+		return nil, fmt.Errorf("new caller: %w", err)
+	}
+
+	watcher, err := watch.New(backend, contractID, watch.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("new watcher: %w", err)
+	}
+
+	c := Cache{
+		backend:  backend,
+		caller:   caller,
+		watcher:  watcher,
+		items:    make(map[string]*big.Int),
+		shutdown: make(chan struct{}),
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.watch()
+	}()
+
+	return &c, nil
+}
+
+// Close stops watching for events.
+func (c *Cache) Close() {
+	close(c.shutdown)
+	c.wg.Wait()
+}
+
+// Items returns the value for the specified key. Reads pinned to a block
+// number bypass the cache, as do all reads while no subscription is live.
+// Reads for the pending state are served from the mined state the cache
+// tracks.
+func (c *Cache) Items(opts *bind.CallOpts, key string) (*big.Int, error) {
+	if opts != nil && opts.BlockNumber != nil {
+		c.direct.Add(1)
+		return c.caller.Items(opts, key)
+	}
+
+	c.mu.RLock()
+	live := c.live
+	value, found := c.items[key]
+	gen := c.gen
+	c.mu.RUnlock()
+
+	if !live {
+		c.direct.Add(1)
+		return c.caller.Items(opts, key)
+	}
+
+	if found {
+		c.hits.Add(1)
+		return new(big.Int).Set(value), nil
+	}
+
+	c.misses.Add(1)
+
+	return c.fill(opts, key, gen)
+}
+
+// Block returns the highest block the cache is known to be consistent with.
+// Zero is returned while no subscription is live.
+func (c *Cache) Block() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.block
+}
+
+// Live reports whether the event subscription is established and reads are
+// being served from memory.
+func (c *Cache) Live() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.live
+}
+
+// Stats returns a snapshot of the counters.
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		Direct:        c.direct.Load(),
+		Invalidations: c.invalidations.Load(),
+		Subscriptions: c.subscriptions.Load(),
+	}
+}
+
+// =============================================================================
+
+// fill reads the value for the key at the current head block and stores it,
+// unless an event or a dropped subscription changed the cache in the meantime.
+func (c *Cache) fill(opts *bind.CallOpts, key string, gen uint64) (*big.Int, error) {
+	ctx := context.Background()
+	if opts != nil && opts.Context != nil {
+		ctx = opts.Context
+	}
+
+	header, err := c.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("head block: %w", err)
+	}
+
+	pinned := bind.CallOpts{
+		Context:     ctx,
+		BlockNumber: header.Number,
+	}
+	if opts != nil {
+		pinned.From = opts.From
+	}
+
+	value, err := c.caller.Items(&pinned, key)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.live && c.gen == gen {
+		c.items[key] = new(big.Int).Set(value)
+		c.block = max(c.block, header.Number.Uint64())
+	}
+
+	return value, nil
+}
+
+// watch maintains the ItemSet subscription until the cache is closed.
+func (c *Cache) watch() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		<-c.shutdown
+		cancel()
+	}()
+
+	h := watch.Handler{
+		Start: func(ctx context.Context) error {
+			c.subscriptions.Add(1)
+			c.setLive(true)
+			return nil
+		},
+		Event: func(ctx context.Context, ev *basic.BasicItemSet) error {
+			c.invalidate(ev)
+			return nil
+		},
+		Stop: func() {
+			c.setLive(false)
+		},
+	}
+
+	c.watcher.Run(ctx, h)
+}
+
+// invalidate removes the entry for the key carried by the event. Removed
+// logs from a reorg invalidate the same way so the canonical value is read.
+func (c *Cache) invalidate(ev *basic.BasicItemSet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, found := c.items[ev.Key]; found {
+		delete(c.items, ev.Key)
+		c.invalidations.Add(1)
+	}
+
+	c.gen++
+	if !ev.Raw.Removed {
+		c.block = max(c.block, ev.Raw.BlockNumber)
+	}
+}
+
+// setLive switches between serving from memory and calling the contract.
+// Either way the cache starts empty since events may have been missed.
+func (c *Cache) setLive(live bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.live = live
+	c.items = make(map[string]*big.Int)
+	c.block = 0
+	c.gen++
+}
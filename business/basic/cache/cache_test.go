@@ -0,0 +1,131 @@
+package cache_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/cache"
+)
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	clt, err := ethereum.NewClient(backend, backend.PrivateKeys[0])
+	if err != nil {
+		t.Fatalf("unable to create ethereum api: %s", err)
+	}
+
+	// =========================================================================
+
+	const gasLimit = 1600000
+	valueGwei := big.NewFloat(0.0)
+	gasPrice := currency.GWei2Wei(big.NewFloat(39.576))
+	transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+	if err != nil {
+		t.Fatalf("unable to create opts for deploy: %s", err)
+	}
+
+	contractID, tx, testBasic, err := basic.DeployBasic(transOpts, clt.Backend)
+	if err != nil {
+		t.Fatalf("unable to deploy basic: %s", err)
+	}
+
+	if _, err := clt.WaitMined(ctx, tx); err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+
+	setItem := func(key string, value *big.Int) {
+		transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+		if err != nil {
+			t.Fatalf("unable to create transaction opts for setItems: %s", err)
+		}
+
+		tx, err := testBasic.SetItem(transOpts, key, value)
+		if err != nil {
+			t.Fatalf("should be able to set item: %s", err)
+		}
+
+		if _, err := clt.WaitMined(ctx, tx); err != nil {
+			t.Fatalf("waiting for setitem: %s", err)
+		}
+	}
+
+	const key = "bill"
+	setItem(key, big.NewInt(100))
+
+	// =========================================================================
+
+	c, err := cache.New(clt.Backend, contractID)
+	if err != nil {
+		t.Fatalf("unable to create cache: %s", err)
+	}
+	defer c.Close()
+
+	waitFor(t, "subscription to be live", c.Live)
+
+	for range 3 {
+		item, err := c.Items(nil, key)
+		if err != nil {
+			t.Fatalf("should be able to retrieve item: %s", err)
+		}
+
+		if item.Cmp(big.NewInt(100)) != 0 {
+			t.Fatalf("wrong value, got %s exp %d", item, 100)
+		}
+	}
+
+	stats := c.Stats()
+	if stats.Misses != 1 || stats.Hits != 2 {
+		t.Fatalf("should have one miss and two hits, got %+v", stats)
+	}
+
+	// =========================================================================
+
+	setItem(key, big.NewInt(200))
+
+	waitFor(t, "entry to be invalidated", func() bool {
+		return c.Stats().Invalidations == 1
+	})
+
+	item, err := c.Items(nil, key)
+	if err != nil {
+		t.Fatalf("should be able to retrieve item: %s", err)
+	}
+
+	if item.Cmp(big.NewInt(200)) != 0 {
+		t.Fatalf("wrong value after invalidation, got %s exp %d", item, 200)
+	}
+
+	header, err := clt.HeaderByNumber(ctx, nil)
+	if err != nil {
+		t.Fatalf("unable to read head block: %s", err)
+	}
+
+	if c.Block() != header.Number.Uint64() {
+		t.Fatalf("wrong consistent block, got %d exp %d", c.Block(), header.Number.Uint64())
+	}
+}
+
+// waitFor polls the condition until it is true or the test times out.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
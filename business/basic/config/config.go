@@ -0,0 +1,566 @@
+// Package config provides the layered settings used by the basic programs.
+// Values start from a named profile and are overridden, in order, by a JSON
+// file, environment variables and command line flags.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/smartcontract/business/basic/registry"
+	"github.com/ardanlabs/smartcontract/foundation/secret"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// EnvPrefix prefixes the environment variable for every setting, so the
+// network is read from BASIC_NETWORK.
+const EnvPrefix = "BASIC_"
+
+// Set of built in profile names. The remote profile only carries the shared
+// defaults, so the network and keystore must come from the config file, the
+// BASIC_NETWORK and BASIC_KEYSTORE environment variables or flags.
+const (
+	ProfileDevIPC  = "dev-ipc"
+	ProfileDevHTTP = "dev-http"
+	ProfileRemote  = "remote"
+)
+
+// DefaultProfile is used when no profile is selected.
+const DefaultProfile = ProfileDevIPC
+
+// DefaultFile is the config file the programs read when it exists.
+const DefaultFile = "zarf/ethereum/basic.json"
+
+// Set of secret names the programs ask the secret provider for.
+const (
+	SecretPassphrase       = "passphrase"
+	SecretCoinMarketCapKey = "coinmarketcap_key"
+)
+
+// Set of output formats.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// Set of sources a setting can come from, from lowest to highest precedence.
+const (
+	SourceProfile = "profile"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// redacted replaces the value of secrets when the config is displayed.
+const redacted = "********"
+
+// Config represents the effective settings.
+type Config struct {
+	Profile           string
+	Network           string
+	Networks          []string
+	KeyStore          string
+	KeyStoreDir       string
+	Passphrase        string
+	PassphraseFile    string
+	CoinMarketCapKey  string
+	CoinMarketCapFile string
+	Vault             string
+	Registry          string
+	Contract          string
+	GasLimit          uint64
+	GasPriceGWei      float64
+	RPCAttempts       int
+	RPCRate           float64
+	Output            string
+
+	// Sources records where each setting came from, keyed by setting name.
+	Sources map[string]string
+}
+
+// =============================================================================
+
+// setting describes a single configurable value.
+type setting struct {
+	name   string
+	secret bool
+	get    func(cfg *Config) string
+	set    func(cfg *Config, value string) error
+}
+
+// settings lists every value that can be configured. The name is used as the
+// key in the file and, upper cased with EnvPrefix, as the environment
+// variable.
+var settings = []setting{
+	{
+		name: "network",
+		get:  func(cfg *Config) string { return cfg.Network },
+		set:  func(cfg *Config, v string) error { cfg.Network = v; return nil },
+	},
+	{
+		name: "networks",
+		get:  func(cfg *Config) string { return strings.Join(cfg.Networks, ",") },
+		set: func(cfg *Config, v string) error {
+			cfg.Networks = nil
+			for _, network := range strings.Split(v, ",") {
+				if network = strings.TrimSpace(network); network != "" {
+					cfg.Networks = append(cfg.Networks, network)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		name: "keystore",
+		get:  func(cfg *Config) string { return cfg.KeyStore },
+		set:  func(cfg *Config, v string) error { cfg.KeyStore = v; return nil },
+	},
+	{
+		name: "keystore_dir",
+		get:  func(cfg *Config) string { return cfg.KeyStoreDir },
+		set:  func(cfg *Config, v string) error { cfg.KeyStoreDir = v; return nil },
+	},
+	{
+		name:   "passphrase",
+		secret: true,
+		get:    func(cfg *Config) string { return cfg.Passphrase },
+		set:    func(cfg *Config, v string) error { cfg.Passphrase = v; return nil },
+	},
+	{
+		name: "passphrase_file",
+		get:  func(cfg *Config) string { return cfg.PassphraseFile },
+		set:  func(cfg *Config, v string) error { cfg.PassphraseFile = v; return nil },
+	},
+	{
+		name:   "coinmarketcap_key",
+		secret: true,
+		get:    func(cfg *Config) string { return cfg.CoinMarketCapKey },
+		set:    func(cfg *Config, v string) error { cfg.CoinMarketCapKey = v; return nil },
+	},
+	{
+		name: "coinmarketcap_key_file",
+		get:  func(cfg *Config) string { return cfg.CoinMarketCapFile },
+		set:  func(cfg *Config, v string) error { cfg.CoinMarketCapFile = v; return nil },
+	},
+	{
+		name: "vault",
+		get:  func(cfg *Config) string { return cfg.Vault },
+		set:  func(cfg *Config, v string) error { cfg.Vault = v; return nil },
+	},
+	{
+		name: "registry",
+		get:  func(cfg *Config) string { return cfg.Registry },
+		set:  func(cfg *Config, v string) error { cfg.Registry = v; return nil },
+	},
+	{
+		name: "contract",
+		get:  func(cfg *Config) string { return cfg.Contract },
+		set:  func(cfg *Config, v string) error { cfg.Contract = v; return nil },
+	},
+	{
+		name: "gas_limit",
+		get:  func(cfg *Config) string { return strconv.FormatUint(cfg.GasLimit, 10) },
+		set: func(cfg *Config, v string) error {
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return err
+			}
+			cfg.GasLimit = n
+			return nil
+		},
+	},
+	{
+		name: "gas_price_gwei",
+		get:  func(cfg *Config) string { return strconv.FormatFloat(cfg.GasPriceGWei, 'f', -1, 64) },
+		set: func(cfg *Config, v string) error {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return err
+			}
+			cfg.GasPriceGWei = n
+			return nil
+		},
+	},
+	{
+		name: "rpc_attempts",
+		get:  func(cfg *Config) string { return strconv.Itoa(cfg.RPCAttempts) },
+		set: func(cfg *Config, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return err
+			}
+			cfg.RPCAttempts = n
+			return nil
+		},
+	},
+	{
+		name: "rpc_rate",
+		get:  func(cfg *Config) string { return strconv.FormatFloat(cfg.RPCRate, 'f', -1, 64) },
+		set: func(cfg *Config, v string) error {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return err
+			}
+			cfg.RPCRate = n
+			return nil
+		},
+	},
+	{
+		name: "output",
+		get:  func(cfg *Config) string { return cfg.Output },
+		set:  func(cfg *Config, v string) error { cfg.Output = v; return nil },
+	},
+}
+
+// lookup returns the setting with the specified name.
+func lookup(name string) (setting, bool) {
+	for _, s := range settings {
+		if s.name == name {
+			return s, true
+		}
+	}
+
+	return setting{}, false
+}
+
+// =============================================================================
+
+// profiles holds the built in profiles. The dev profiles point at the local
+// geth started by this repo and read the passphrase from the same file geth
+// unlocks the account with. The remote profile leaves the network and
+// account to be configured.
+var profiles = map[string]map[string]string{
+	ProfileDevIPC: {
+		"network":         ethereum.NetworkLocalhost,
+		"keystore":        "zarf/ethereum/keystore/UTC--2022-05-12T14-47-50.112225000Z--6327a38415c53ffb36c11db55ea74cc9cb4976fd",
+		"passphrase_file": "zarf/ethereum/password",
+	},
+	ProfileDevHTTP: {
+		"network":         ethereum.NetworkHTTPLocalhost,
+		"keystore":        "zarf/ethereum/keystore/UTC--2022-05-12T14-47-50.112225000Z--6327a38415c53ffb36c11db55ea74cc9cb4976fd",
+		"passphrase_file": "zarf/ethereum/password",
+	},
+	ProfileRemote: {},
+}
+
+// base holds the values shared by every profile.
+var base = map[string]string{
+	"vault":          "zarf/ethereum/vault.json",
+	"registry":       registry.DefaultFile,
+	"keystore_dir":   "zarf/ethereum/keystore",
+	"gas_limit":      "1600000",
+	"gas_price_gwei": "39.576",
+	"rpc_attempts":   "5",
+	"rpc_rate":       "0",
+	"output":         OutputText,
+}
+
+// Profiles returns the names of the built in profiles.
+func Profiles() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// =============================================================================
+
+// File represents the contents of a config file.
+//
+//	{
+//	  "profile": "dev-http",
+//	  "profiles": {
+//	    "remote": {"network": "https://rpc.sepolia.org", "gas_price_gwei": 20}
+//	  }
+//	}
+//
+// Profiles named after a built in profile override its values, any other
+// name defines a new profile starting from the shared defaults.
+type File struct {
+	Profile  string                       `json:"profile"`
+	Profiles map[string]map[string]string `json:"profiles"`
+}
+
+// ReadFile reads and decodes the config file. Values can be JSON strings,
+// numbers or booleans.
+func ReadFile(path string) (File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return File{}, fmt.Errorf("read config: %w", err)
+	}
+
+	var raw struct {
+		Profile  string                    `json:"profile"`
+		Profiles map[string]map[string]any `json:"profiles"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&raw); err != nil {
+		return File{}, fmt.Errorf("decode config %s: %w", path, err)
+	}
+
+	file := File{
+		Profile:  raw.Profile,
+		Profiles: make(map[string]map[string]string, len(raw.Profiles)),
+	}
+
+	for profile, values := range raw.Profiles {
+		file.Profiles[profile] = make(map[string]string, len(values))
+
+		for name, value := range values {
+			if _, exists := lookup(name); !exists {
+				return File{}, fmt.Errorf("config %s: profile %s: unknown setting %q", path, profile, name)
+			}
+
+			switch v := value.(type) {
+			case string:
+				file.Profiles[profile][name] = v
+			case json.Number:
+				file.Profiles[profile][name] = v.String()
+			case bool:
+				file.Profiles[profile][name] = strconv.FormatBool(v)
+			default:
+				return File{}, fmt.Errorf("config %s: profile %s: setting %q must be a string, number or boolean", path, profile, name)
+			}
+		}
+	}
+
+	return file, nil
+}
+
+// =============================================================================
+
+// Options selects the layers used to build the config.
+type Options struct {
+
+	// File is the path to the config file. When empty no file is read.
+	File string
+
+	// FileOptional ignores the file when it doesn't exist, so programs can
+	// point at DefaultFile without requiring it.
+	FileOptional bool
+
+	// Profile selects the profile. When empty the BASIC_PROFILE environment
+	// variable is used, then the file's profile, then DefaultProfile.
+	Profile string
+
+	// LookupEnv reads environment variables. When nil, os.LookupEnv is used.
+	LookupEnv func(key string) (string, bool)
+
+	// Flags holds the values given on the command line, keyed by setting
+	// name. Only flags the user actually set should be included.
+	Flags map[string]string
+}
+
+// Load builds the config from the layers and validates it.
+func Load(opts Options) (Config, error) {
+	if opts.LookupEnv == nil {
+		opts.LookupEnv = os.LookupEnv
+	}
+
+	if opts.File != "" && opts.FileOptional {
+		if _, err := os.Stat(opts.File); errors.Is(err, os.ErrNotExist) {
+			opts.File = ""
+		}
+	}
+
+	var file File
+	if opts.File != "" {
+		var err error
+		if file, err = ReadFile(opts.File); err != nil {
+			return Config{}, err
+		}
+	}
+
+	profile := opts.Profile
+	if profile == "" {
+		profile, _ = opts.LookupEnv(EnvPrefix + "PROFILE")
+	}
+	if profile == "" {
+		profile = file.Profile
+	}
+	if profile == "" {
+		profile = DefaultProfile
+	}
+
+	builtin, isBuiltin := profiles[profile]
+	fromFile, inFile := file.Profiles[profile]
+	if !isBuiltin && !inFile {
+		return Config{}, fmt.Errorf("unknown profile %q", profile)
+	}
+
+	cfg := Config{
+		Profile: profile,
+		Sources: make(map[string]string),
+	}
+
+	// Apply each layer from the lowest precedence to the highest.
+	layers := []struct {
+		source string
+		values func(name string) (string, bool)
+	}{
+		{SourceProfile, func(name string) (string, bool) { v, ok := base[name]; return v, ok }},
+		{SourceProfile, func(name string) (string, bool) { v, ok := builtin[name]; return v, ok }},
+		{SourceFile, func(name string) (string, bool) { v, ok := fromFile[name]; return v, ok }},
+		{SourceEnv, func(name string) (string, bool) { return opts.LookupEnv(EnvPrefix + strings.ToUpper(name)) }},
+		{SourceFlag, func(name string) (string, bool) { v, ok := opts.Flags[name]; return v, ok }},
+	}
+
+	for name := range opts.Flags {
+		if _, exists := lookup(name); !exists {
+			return Config{}, fmt.Errorf("unknown setting %q", name)
+		}
+	}
+
+	for _, layer := range layers {
+		for _, s := range settings {
+			value, ok := layer.values(s.name)
+			if !ok {
+				continue
+			}
+
+			if err := s.set(&cfg, value); err != nil {
+				return Config{}, fmt.Errorf("%s %s: %w", layer.source, s.name, err)
+			}
+			cfg.Sources[s.name] = layer.source
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the settings make sense together.
+func (cfg Config) Validate() error {
+	var errs []error
+
+	if cfg.Network == "" {
+		errs = append(errs, required("network"))
+	}
+
+	if cfg.KeyStore == "" {
+		errs = append(errs, required("keystore"))
+	}
+
+	if cfg.Registry == "" {
+		errs = append(errs, required("registry"))
+	}
+
+	if cfg.Contract != "" && !common.IsHexAddress(cfg.Contract) {
+		errs = append(errs, fmt.Errorf("contract %q is not an address", cfg.Contract))
+	}
+
+	if cfg.GasLimit < 21000 {
+		errs = append(errs, fmt.Errorf("gas_limit %d is below the 21000 needed by any transaction", cfg.GasLimit))
+	}
+
+	if cfg.GasPriceGWei <= 0 {
+		errs = append(errs, fmt.Errorf("gas_price_gwei %v must be positive", cfg.GasPriceGWei))
+	}
+
+	if cfg.RPCAttempts < 1 {
+		errs = append(errs, fmt.Errorf("rpc_attempts %d must be at least 1", cfg.RPCAttempts))
+	}
+
+	if cfg.RPCRate < 0 {
+		errs = append(errs, fmt.Errorf("rpc_rate %v can't be negative, use 0 for no limit", cfg.RPCRate))
+	}
+
+	switch cfg.Output {
+	case OutputText, OutputJSON:
+	default:
+		errs = append(errs, fmt.Errorf("output %q must be %s or %s", cfg.Output, OutputText, OutputJSON))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config for profile %s: %w", cfg.Profile, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// required returns the error for a setting with no value, saying where it
+// can be set.
+func required(name string) error {
+	return fmt.Errorf("%s is required, set it in the config file, with %s%s or on the command line", name, EnvPrefix, strings.ToUpper(name))
+}
+
+// =============================================================================
+
+// Setting represents a single effective value for display.
+type Setting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Env    string `json:"env"`
+}
+
+// Redacted returns every setting with its source, hiding the value of
+// secrets that have been set.
+func (cfg Config) Redacted() []Setting {
+	list := make([]Setting, len(settings))
+
+	for i, s := range settings {
+		value := s.get(&cfg)
+		if s.secret && value != "" {
+			value = redacted
+		}
+
+		source := cfg.Sources[s.name]
+		if source == "" {
+			source = "unset"
+		}
+
+		list[i] = Setting{
+			Name:   s.name,
+			Value:  value,
+			Source: source,
+			Env:    EnvPrefix + strings.ToUpper(s.name),
+		}
+	}
+
+	return list
+}
+
+// =============================================================================
+
+// Secrets returns the provider for the passphrase and CoinMarketCap key. A
+// value set directly in the config wins, then the secret's file, then the
+// vault, and finally the passphrase is prompted for on a terminal. The vault
+// password is read from BASIC_VAULT_PASSWORD or prompted for.
+func (cfg Config) Secrets() secret.Provider {
+	return secret.Chain{
+		secret.Static{
+			SecretPassphrase:       cfg.Passphrase,
+			SecretCoinMarketCapKey: cfg.CoinMarketCapKey,
+		},
+		secret.Files{
+			SecretPassphrase:       cfg.PassphraseFile,
+			SecretCoinMarketCapKey: cfg.CoinMarketCapFile,
+		},
+		&secret.VaultFile{
+			Path: cfg.Vault,
+			Password: secret.Chain{
+				secret.Env{Prefix: EnvPrefix},
+				secret.Prompt{},
+			},
+		},
+		secret.Prompt{Names: []string{SecretPassphrase}},
+	}
+}
@@ -0,0 +1,133 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/smartcontract/business/basic/config"
+)
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "basic.json")
+
+	data := `{
+		"profile": "dev-http",
+		"profiles": {
+			"dev-http": {"gas_limit": 90000, "gas_price_gwei": 10},
+			"staging": {"network": "https://staging.example.com", "keystore": "staging.json"}
+		}
+	}`
+
+	if err := os.WriteFile(file, []byte(data), 0600); err != nil {
+		t.Fatalf("unable to write config: %s", err)
+	}
+
+	env := map[string]string{
+		"BASIC_GAS_PRICE_GWEI": "20",
+		"BASIC_OUTPUT":         "json",
+		"BASIC_PASSPHRASE":     "from-env",
+		"BASIC_NETWORKS":       " http://a:8545, ,http://b:8545",
+	}
+	lookupEnv := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+
+	// =========================================================================
+
+	opts := config.Options{
+		File:      file,
+		LookupEnv: lookupEnv,
+		Flags:     map[string]string{"output": "text"},
+	}
+
+	cfg, err := config.Load(opts)
+	if err != nil {
+		t.Fatalf("should be able to load config: %s", err)
+	}
+
+	if cfg.Profile != config.ProfileDevHTTP {
+		t.Fatalf("should use the file's profile, got %s", cfg.Profile)
+	}
+
+	if cfg.Network != ethereum.NetworkHTTPLocalhost {
+		t.Fatalf("should use the profile's network, got %s", cfg.Network)
+	}
+
+	if cfg.GasLimit != 90000 || cfg.Sources["gas_limit"] != config.SourceFile {
+		t.Fatalf("should take the gas limit from the file, got %d from %s", cfg.GasLimit, cfg.Sources["gas_limit"])
+	}
+
+	if cfg.GasPriceGWei != 20 || cfg.Sources["gas_price_gwei"] != config.SourceEnv {
+		t.Fatalf("should take the gas price from the env, got %v from %s", cfg.GasPriceGWei, cfg.Sources["gas_price_gwei"])
+	}
+
+	if cfg.Output != config.OutputText || cfg.Sources["output"] != config.SourceFlag {
+		t.Fatalf("should take the output from the flag, got %s from %s", cfg.Output, cfg.Sources["output"])
+	}
+
+	if strings.Join(cfg.Networks, " ") != "http://a:8545 http://b:8545" {
+		t.Fatalf("should split the failover networks, got %q", cfg.Networks)
+	}
+
+	// =========================================================================
+
+	passphrase, err := cfg.Secrets().Secret(config.SecretPassphrase)
+	if err != nil {
+		t.Fatalf("should find the passphrase: %s", err)
+	}
+
+	if passphrase != "from-env" {
+		t.Fatalf("should take the passphrase from the env, got %q", passphrase)
+	}
+
+	for _, s := range cfg.Redacted() {
+		if strings.Contains(s.Value, "from-env") {
+			t.Fatalf("should redact %s, got %s", s.Name, s.Value)
+		}
+	}
+
+	// =========================================================================
+
+	// Profiles defined only in the file start from the shared defaults.
+	opts.Profile = "staging"
+
+	cfg, err = config.Load(opts)
+	if err != nil {
+		t.Fatalf("should be able to load the staging profile: %s", err)
+	}
+
+	if cfg.Network != "https://staging.example.com" || cfg.PassphraseFile != "" || cfg.GasLimit != 1600000 {
+		t.Fatalf("wrong staging config: %+v", cfg)
+	}
+
+	// The remote profile has no network or account until one is configured.
+	opts.Profile = config.ProfileRemote
+
+	if _, err := config.Load(opts); err == nil || !strings.Contains(err.Error(), "network is required") {
+		t.Fatalf("should fail validation without a network, got %v", err)
+	}
+
+	if _, err := config.Load(opts); err == nil || !strings.Contains(err.Error(), "BASIC_KEYSTORE") {
+		t.Fatalf("should say how to set the keystore, got %v", err)
+	}
+
+	opts.Profile = "missing"
+
+	if _, err := config.Load(opts); err == nil {
+		t.Fatal("should fail on an unknown profile")
+	}
+
+	// =========================================================================
+
+	if err := os.WriteFile(file, []byte(`{"profiles": {"dev-ipc": {"gas_limt": 1}}}`), 0600); err != nil {
+		t.Fatalf("unable to write config: %s", err)
+	}
+
+	if _, err := config.Load(config.Options{File: file, LookupEnv: lookupEnv}); err == nil {
+		t.Fatal("should reject unknown settings in the file")
+	}
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/smartcontract/foundation/backend"
+	"github.com/ardanlabs/smartcontract/foundation/backend/failover"
+	"github.com/ardanlabs/smartcontract/foundation/backend/retry"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Backend represents the connection to the configured nodes.
+type Backend struct {
+	ethereum.Backend
+	retry *retry.Backend
+	close func()
+}
+
+// Dial connects to the configured network. When failover networks are
+// configured, the network and every failover network are dialed as one
+// backend that health checks the nodes, refuses nodes on another chain and
+// moves calls to a healthy node when the one in use stops responding. Every
+// call is retried on transient errors up to rpc_attempts times and held to
+// rpc_rate requests per second.
+func (cfg Config) Dial(ctx context.Context) (*Backend, error) {
+	node, closer, err := cfg.dialNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rcfg := retry.Config{
+		MaxAttempts:       cfg.RPCAttempts,
+		RequestsPerSecond: cfg.RPCRate,
+	}
+
+	rb := retry.NewBackend(node, rcfg)
+
+	b := Backend{
+		Backend: rb,
+		retry:   rb,
+		close:   closer,
+	}
+
+	return &b, nil
+}
+
+// dialNodes connects to the network, or to the network and the failover
+// networks as a single backend.
+func (cfg Config) dialNodes(ctx context.Context) (ethereum.Backend, func(), error) {
+	if len(cfg.Networks) == 0 {
+		dialed, err := ethereum.CreateDialedBackend(ctx, cfg.Network)
+		if err != nil {
+			return nil, nil, fmt.Errorf("dial %s: %w", cfg.Network, err)
+		}
+
+		return dialed, dialed.Close, nil
+	}
+
+	networks := append([]string{cfg.Network}, cfg.Networks...)
+
+	fcfg := failover.Config{
+		MaxBlockLag:   5,
+		CheckInterval: 15 * time.Second,
+	}
+
+	fb, err := failover.CreateBackend(ctx, fcfg, networks...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dial %s: %w", strings.Join(networks, ", "), err)
+	}
+
+	return fb, fb.Close, nil
+}
+
+// Close releases the connections to the nodes.
+func (b *Backend) Close() {
+	b.close()
+}
+
+// Stats returns the retry and rate limit counters for the calls made so far.
+func (b *Backend) Stats() retry.Stats {
+	return b.retry.Stats()
+}
+
+// HeaderByHash returns the block header with the given hash.
+func (b *Backend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	cr, ok := b.Backend.(backend.ChainReader)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+
+	return cr.HeaderByHash(ctx, hash)
+}
+
+// BlockByNumber returns a block from the current canonical chain.
+func (b *Backend) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	cr, ok := b.Backend.(backend.ChainReader)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+
+	return cr.BlockByNumber(ctx, number)
+}
@@ -0,0 +1,533 @@
+// Package console provides an interactive shell for a deployed basic
+// contract. Besides the get, set, watch, balance and tx commands, every
+// method in the basic ABI can be called by name, with tab completion of the
+// commands and methods and a history that survives between sessions.
+package console
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/watch"
+	"github.com/ardanlabs/smartcontract/foundation/abiarg"
+	geth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/peterh/liner"
+)
+
+// Prompt is shown when the console is waiting for a command.
+const Prompt = "basic> "
+
+// ErrExit is returned by Execute when the exit command is entered.
+var ErrExit = errors.New("exit")
+
+// Config represents the settings for the console.
+type Config struct {
+	Client     *ethereum.Client
+	ContractID common.Address
+	Converter  *currency.Converter
+	GasLimit   uint64
+	GasPrice   *big.Int
+	Out        io.Writer
+
+	// Log receives notice of the polling fallback while watching. When nil,
+	// the root logger is used.
+	Log log.Logger
+}
+
+// command represents a built in console command.
+type command struct {
+	usage string
+	help  string
+	run   func(ctx context.Context, args []string) error
+}
+
+// Console executes commands against the basic contract.
+type Console struct {
+	cfg      Config
+	abi      *abi.ABI
+	contract *bind.BoundContract
+	basic    *basic.Basic
+	commands map[string]command
+}
+
+// New constructs a console for the contract at the configured address.
+func New(cfg Config) (*Console, error) {
+	if cfg.Out == nil {
+		cfg.Out = os.Stdout
+	}
+
+	if cfg.Log == nil {
+		cfg.Log = log.Root()
+	}
+
+	contractABI, err := basic.BasicMetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("parse abi: %w", err)
+	}
+
+	backend := cfg.Client.Backend
+
+	contract, err := basic.NewBasic(cfg.ContractID, backend)
+	if err != nil {
+		return nil, fmt.Errorf("new contract: %w", err)
+	}
+
+	c := Console{
+		cfg:      cfg,
+		abi:      contractABI,
+		contract: bind.NewBoundContract(cfg.ContractID, *contractABI, backend, backend, backend),
+		basic:    contract,
+	}
+
+	c.commands = map[string]command{
+		"get":     {"get KEY [KEY...]", "read the value of one or more keys", c.get},
+		"set":     {"set KEY VALUE", "set the value of a key and show the receipt", c.set},
+		"watch":   {"watch [KEY...]", "stream ItemSet events until Ctrl-C", c.watch},
+		"balance": {"balance [ADDRESS]", "show the balance of an account, this account by default", c.balance},
+		"tx":      {"tx HASH", "show a transaction and its receipt", c.tx},
+		"help":    {"help", "list the commands and contract methods", c.help},
+		"exit":    {"exit", "leave the console, Ctrl-D works too", func(context.Context, []string) error { return ErrExit }},
+	}
+
+	return &c, nil
+}
+
+// Run reads commands from the terminal until exit, Ctrl-D or the context is
+// cancelled. Ctrl-C at the prompt clears the line and Ctrl-C while a command
+// runs stops that command. The history is loaded from and saved to the
+// history file when one is given. A read from the terminal can't be
+// interrupted, so when the context is cancelled at the prompt the terminal
+// is restored and Run returns once the pending line is entered.
+func (c *Console) Run(ctx context.Context, historyFile string) error {
+	ln := liner.NewLiner()
+	defer ln.Close()
+
+	ln.SetCtrlCAborts(true)
+	ln.SetTabCompletionStyle(liner.TabPrints)
+	ln.SetCompleter(c.Complete)
+
+	if historyFile != "" {
+		if f, err := os.Open(historyFile); err == nil {
+			ln.ReadHistory(f)
+			f.Close()
+		}
+		defer c.saveHistory(ln, historyFile)
+	}
+
+	fmt.Fprintf(c.cfg.Out, "connected to %s as %s\n", c.cfg.ContractID.Hex(), c.cfg.Client.Address().Hex())
+	fmt.Fprintln(c.cfg.Out, "type help for the commands, tab completes")
+
+	type result struct {
+		line string
+		err  error
+	}
+
+	for {
+		// The prompt blocks on the terminal, so it is read on the side to
+		// stay responsive to the context.
+		ch := make(chan result, 1)
+		go func() {
+			line, err := ln.Prompt(Prompt)
+			ch <- result{line, err}
+		}()
+
+		var r result
+		select {
+		case r = <-ch:
+		case <-ctx.Done():
+			ln.Close()
+			fmt.Fprintln(c.cfg.Out, "\nshutting down, press enter to exit")
+			<-ch
+			return nil
+		}
+
+		switch {
+		case errors.Is(r.err, liner.ErrPromptAborted):
+			continue
+		case errors.Is(r.err, io.EOF):
+			fmt.Fprintln(c.cfg.Out)
+			return nil
+		case r.err != nil:
+			return fmt.Errorf("prompt: %w", r.err)
+		}
+
+		line := strings.TrimSpace(r.line)
+		if line == "" {
+			continue
+		}
+		ln.AppendHistory(line)
+
+		cmdCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		err := c.Execute(cmdCtx, line)
+		stop()
+
+		switch {
+		case errors.Is(err, ErrExit):
+			return nil
+		case err != nil:
+			fmt.Fprintln(c.cfg.Out, "error:", err)
+		}
+	}
+}
+
+// Execute runs a single command line. Lines that start with a method of the
+// basic ABI call that method, reads are returned directly and writes are
+// sent as transactions.
+func (c *Console) Execute(ctx context.Context, line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	name, args := fields[0], fields[1:]
+
+	if cmd, exists := c.commands[name]; exists {
+		return cmd.run(ctx, args)
+	}
+
+	if method, exists := c.abi.Methods[name]; exists {
+		return c.method(ctx, method, args)
+	}
+
+	return fmt.Errorf("unknown command %q, type help for the list", name)
+}
+
+// Complete returns the completions for the line. The first word completes
+// to a command or a method of the basic ABI.
+func (c *Console) Complete(line string) []string {
+	if strings.ContainsAny(line, " \t") {
+		return nil
+	}
+
+	var matches []string
+	for _, name := range c.names() {
+		if strings.HasPrefix(name, line) {
+			matches = append(matches, name)
+		}
+	}
+
+	return matches
+}
+
+// =============================================================================
+
+func (c *Console) get(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected at least one KEY")
+	}
+
+	for _, key := range args {
+		value, err := c.basic.Items(&bind.CallOpts{Context: ctx}, key)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", key, err)
+		}
+		fmt.Fprintf(c.cfg.Out, "%s: %v\n", key, value)
+	}
+
+	return nil
+}
+
+func (c *Console) set(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return errors.New("expected KEY VALUE")
+	}
+
+	value, ok := new(big.Int).SetString(args[1], 10)
+	if !ok || value.Sign() < 0 || value.BitLen() > 256 {
+		return fmt.Errorf("value %q must be an unsigned 256 bit decimal integer", args[1])
+	}
+
+	opts, err := c.transactOpts(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.basic.SetItem(opts, args[0], value)
+	if err != nil {
+		return fmt.Errorf("set item: %w", err)
+	}
+
+	return c.printMined(ctx, tx)
+}
+
+func (c *Console) watch(ctx context.Context, args []string) error {
+	w, err := watch.New(c.cfg.Client.Backend, c.cfg.ContractID, watch.Config{Log: c.cfg.Log})
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]bool)
+	for _, key := range args {
+		keys[key] = true
+	}
+
+	sink := make(chan *basic.BasicItemSet, 100)
+
+	sub, err := w.WatchItemSet(&bind.WatchOpts{Context: ctx}, sink)
+	if err != nil {
+		return fmt.Errorf("watch item set: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	fmt.Fprintln(c.cfg.Out, "watching, Ctrl-C to stop")
+
+	for {
+		select {
+		case ev := <-sink:
+			if len(keys) > 0 && !keys[ev.Key] {
+				continue
+			}
+			fmt.Fprintf(c.cfg.Out, "block[%d] tx[%s] %s = %v\n", ev.Raw.BlockNumber, ev.Raw.TxHash.Hex(), ev.Key, ev.Value)
+
+		case err := <-sub.Err():
+			return err
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (c *Console) balance(ctx context.Context, args []string) error {
+	address := c.cfg.Client.Address()
+
+	switch len(args) {
+	case 0:
+	case 1:
+		if !common.IsHexAddress(args[0]) {
+			return fmt.Errorf("invalid address %q", args[0])
+		}
+		address = common.HexToAddress(args[0])
+	default:
+		return errors.New("expected at most one ADDRESS")
+	}
+
+	wei, err := c.cfg.Client.Backend.BalanceAt(ctx, address, nil)
+	if err != nil {
+		return fmt.Errorf("balance: %w", err)
+	}
+
+	fmt.Fprintln(c.cfg.Out, "address:", address.Hex())
+	fmt.Fprintln(c.cfg.Out, "wei    :", wei)
+	fmt.Fprintln(c.cfg.Out, "gwei   :", currency.Wei2GWei(wei).Text('f', 9))
+	fmt.Fprintln(c.cfg.Out, "usd    :", c.cfg.Converter.Wei2USD(wei))
+
+	return nil
+}
+
+func (c *Console) tx(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("expected a single HASH")
+	}
+
+	hash := common.HexToHash(args[0])
+	if len(args[0]) != 66 || hash == (common.Hash{}) {
+		return fmt.Errorf("invalid transaction hash %q", args[0])
+	}
+
+	backend := c.cfg.Client.Backend
+
+	tx, pending, err := backend.TransactionByHash(ctx, hash)
+	if err != nil {
+		if errors.Is(err, geth.NotFound) {
+			return fmt.Errorf("transaction %s not found", hash.Hex())
+		}
+		return fmt.Errorf("transaction by hash: %w", err)
+	}
+
+	if pending {
+		fmt.Fprintln(c.cfg.Out, "status: pending")
+		fmt.Fprint(c.cfg.Out, c.cfg.Converter.FmtTransaction(tx))
+		return nil
+	}
+
+	receipt, err := backend.TransactionReceipt(ctx, hash)
+	if err != nil {
+		return fmt.Errorf("transaction receipt: %w", err)
+	}
+
+	c.printReceipt(tx, receipt)
+
+	return nil
+}
+
+func (c *Console) help(ctx context.Context, args []string) error {
+	names := make([]string, 0, len(c.commands))
+	for name := range c.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(c.cfg.Out, "\nCommands")
+	fmt.Fprintln(c.cfg.Out, "----------------------------------------------------")
+	for _, name := range names {
+		cmd := c.commands[name]
+		fmt.Fprintf(c.cfg.Out, "%-20s %s\n", cmd.usage, cmd.help)
+	}
+
+	fmt.Fprintln(c.cfg.Out, "\nContract Methods")
+	fmt.Fprintln(c.cfg.Out, "----------------------------------------------------")
+	for _, name := range c.methodNames() {
+		method := c.abi.Methods[name]
+
+		kind := "write"
+		if method.IsConstant() {
+			kind = "read"
+		}
+		fmt.Fprintf(c.cfg.Out, "%-20s %s\n", methodUsage(method), kind)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// method calls a method of the basic ABI with the arguments converted to
+// the method's input types.
+func (c *Console) method(ctx context.Context, method abi.Method, args []string) error {
+	if len(args) != len(method.Inputs) {
+		return fmt.Errorf("usage: %s", methodUsage(method))
+	}
+
+	params := make([]any, len(args))
+	for i, input := range method.Inputs {
+		v, err := abiarg.Parse(input.Type, args[i])
+		if err != nil {
+			return fmt.Errorf("%s: %w", input.Name, err)
+		}
+		params[i] = v
+	}
+
+	if !method.IsConstant() {
+		opts, err := c.transactOpts(ctx)
+		if err != nil {
+			return err
+		}
+
+		tx, err := c.contract.Transact(opts, method.Name, params...)
+		if err != nil {
+			return fmt.Errorf("%s: %w", method.Name, err)
+		}
+
+		return c.printMined(ctx, tx)
+	}
+
+	var out []any
+	if err := c.contract.Call(&bind.CallOpts{Context: ctx}, &out, method.Name, params...); err != nil {
+		return fmt.Errorf("%s: %w", method.Name, err)
+	}
+
+	for i, v := range out {
+		name := method.Outputs[i].Name
+		if name == "" {
+			name = method.Outputs[i].Type.String()
+		}
+		fmt.Fprintf(c.cfg.Out, "%s: %v\n", name, v)
+	}
+
+	return nil
+}
+
+// transactOpts returns the options for a transaction from the account.
+func (c *Console) transactOpts(ctx context.Context) (*bind.TransactOpts, error) {
+	opts, err := c.cfg.Client.NewTransactOpts(ctx, c.cfg.GasLimit, c.cfg.GasPrice, big.NewFloat(0))
+	if err != nil {
+		return nil, fmt.Errorf("new transact opts: %w", err)
+	}
+
+	return opts, nil
+}
+
+// printMined waits for the transaction to be mined and prints its receipt.
+func (c *Console) printMined(ctx context.Context, tx *types.Transaction) error {
+	fmt.Fprintln(c.cfg.Out, "sent", tx.Hash().Hex(), "waiting to be mined")
+
+	receipt, err := c.cfg.Client.WaitMined(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("wait mined: %w", err)
+	}
+
+	c.printReceipt(tx, receipt)
+
+	return nil
+}
+
+// printReceipt prints the transaction and its receipt with the costs in
+// GWei and USD and the decoded logs.
+func (c *Console) printReceipt(tx *types.Transaction, receipt *types.Receipt) {
+	status := "success"
+	if receipt.Status == types.ReceiptStatusFailed {
+		status = "failed"
+	}
+
+	gasPrice := receipt.EffectiveGasPrice
+	if gasPrice == nil {
+		gasPrice = tx.GasPrice()
+	}
+
+	fmt.Fprint(c.cfg.Out, c.cfg.Converter.FmtTransaction(tx))
+	fmt.Fprint(c.cfg.Out, c.cfg.Converter.FmtTransactionReceipt(receipt, gasPrice))
+	fmt.Fprintf(c.cfg.Out, "\n%s in block %d\n", status, receipt.BlockNumber)
+}
+
+// saveHistory writes the history so the next session can recall it.
+func (c *Console) saveHistory(ln *liner.State, historyFile string) {
+	f, err := os.OpenFile(historyFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		fmt.Fprintln(c.cfg.Out, "save history:", err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := ln.WriteHistory(f); err != nil {
+		fmt.Fprintln(c.cfg.Out, "save history:", err)
+	}
+}
+
+// names returns the commands and methods in the order they complete.
+func (c *Console) names() []string {
+	names := make([]string, 0, len(c.commands)+len(c.abi.Methods))
+	for name := range c.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return append(names, c.methodNames()...)
+}
+
+// methodNames returns the names of the methods in the basic ABI.
+func (c *Console) methodNames() []string {
+	names := make([]string, 0, len(c.abi.Methods))
+	for name := range c.abi.Methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// =============================================================================
+
+// methodUsage returns the method with the names and types of its inputs.
+func methodUsage(method abi.Method) string {
+	usage := method.Name
+	for _, input := range method.Inputs {
+		usage += fmt.Sprintf(" %s:%s", input.Name, input.Type)
+	}
+
+	return usage
+}
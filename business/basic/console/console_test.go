@@ -0,0 +1,132 @@
+package console_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/console"
+)
+
+func TestExecute(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	clt, err := ethereum.NewClient(backend, backend.PrivateKeys[0])
+	if err != nil {
+		t.Fatalf("unable to create ethereum api: %s", err)
+	}
+
+	gasPrice := currency.GWei2Wei(big.NewFloat(39.576))
+
+	transOpts, err := clt.NewTransactOpts(ctx, 1600000, gasPrice, big.NewFloat(0))
+	if err != nil {
+		t.Fatalf("unable to create opts for deploy: %s", err)
+	}
+
+	contractID, tx, _, err := basic.DeployBasic(transOpts, clt.Backend)
+	if err != nil {
+		t.Fatalf("unable to deploy basic: %s", err)
+	}
+
+	if _, err := clt.WaitMined(ctx, tx); err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+
+	// =========================================================================
+
+	var out bytes.Buffer
+
+	cfg := console.Config{
+		Client:     clt,
+		ContractID: contractID,
+		Converter:  currency.NewDefaultConverter(basic.BasicMetaData.ABI),
+		GasLimit:   1600000,
+		GasPrice:   gasPrice,
+		Out:        &out,
+	}
+
+	con, err := console.New(cfg)
+	if err != nil {
+		t.Fatalf("unable to create console: %s", err)
+	}
+
+	run := func(line string) string {
+		out.Reset()
+		if err := con.Execute(ctx, line); err != nil {
+			t.Fatalf("should run %q: %s", line, err)
+		}
+		return out.String()
+	}
+
+	got := run("set bill 1000")
+	for _, want := range []string{"Receipt Details", "ItemSet", "success in block"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("set should print %q, got:\n%s", want, got)
+		}
+	}
+
+	if got := run("get bill jill"); got != "bill: 1000\njill: 0\n" {
+		t.Fatalf("wrong get output, got %q", got)
+	}
+
+	// Methods of the ABI are called by name, writes are sent as transactions.
+	run("SetItem jill 7")
+
+	if got := run("Items jill"); got != "uint256: 7\n" {
+		t.Fatalf("wrong Items output, got %q", got)
+	}
+
+	if got := run("Version"); got != "string: 1.1\n" {
+		t.Fatalf("wrong Version output, got %q", got)
+	}
+
+	if got := run("balance"); !strings.Contains(got, clt.Address().Hex()) {
+		t.Fatalf("balance should show the account, got %q", got)
+	}
+
+	// =========================================================================
+
+	if err := con.Execute(ctx, "Items"); err == nil || !strings.Contains(err.Error(), "usage: Items") {
+		t.Fatalf("should show the usage for missing arguments, got %v", err)
+	}
+
+	if err := con.Execute(ctx, "set bill -1"); err == nil {
+		t.Fatal("should refuse a negative value")
+	}
+
+	if err := con.Execute(ctx, "bogus"); err == nil {
+		t.Fatal("should refuse an unknown command")
+	}
+
+	if err := con.Execute(ctx, "exit"); !errors.Is(err, console.ErrExit) {
+		t.Fatalf("exit should return ErrExit, got %v", err)
+	}
+
+	// =========================================================================
+
+	tests := map[string][]string{
+		"":       {"balance", "exit", "get", "help", "set", "tx", "watch", "Items", "SetItem", "Version"},
+		"S":      {"SetItem"},
+		"se":     {"set"},
+		"get bi": nil,
+	}
+
+	for line, exp := range tests {
+		got := con.Complete(line)
+		if strings.Join(got, ",") != strings.Join(exp, ",") {
+			t.Fatalf("wrong completions for %q, got %v exp %v", line, got, exp)
+		}
+	}
+}
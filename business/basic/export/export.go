@@ -0,0 +1,540 @@
+// Package export provides support for writing the ItemSet events emitted by
+// the basic contract to CSV or JSON Lines files. Exports are split across
+// files once they reach a row limit and keep a cursor next to the files so
+// an interrupted or repeated export picks up where the last one stopped.
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ErrCursorMismatch is returned when the export directory holds a cursor for
+// a different contract or format.
+var ErrCursorMismatch = errors.New("export directory was written for a different contract or format")
+
+// cursorFile is the name of the file holding the cursor in the export
+// directory.
+const cursorFile = "cursor.json"
+
+// Set of supported formats.
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// header is the first line of every CSV file.
+var header = []string{"key", "value", "block", "timestamp", "tx_hash", "sender", "gas_used", "gas_price", "gas_cost"}
+
+// Config represents the settings for an export.
+type Config struct {
+
+	// Format is either FormatCSV or FormatJSONL. An empty value defaults to
+	// FormatCSV.
+	Format string
+
+	// Dir is the directory the files and the cursor are written to.
+	Dir string
+
+	// Prefix starts the name of every file. An empty value defaults to
+	// "itemset".
+	Prefix string
+
+	// MaxRows is the number of rows written to a file before starting the
+	// next one. A value of zero defaults to 100,000.
+	MaxRows int
+
+	// BatchSize is the number of blocks requested with each FilterItemSet
+	// call. The cursor is saved after every batch. A value of zero defaults
+	// to 1000.
+	BatchSize uint64
+
+	// Log receives progress. When nil, the root logger is used.
+	Log log.Logger
+}
+
+// Row represents a single exported event.
+type Row struct {
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	Block     uint64 `json:"block"`
+	Timestamp string `json:"timestamp"`
+	TxHash    string `json:"tx_hash"`
+	Sender    string `json:"sender"`
+	GasUsed   uint64 `json:"gas_used"`
+	GasPrice  string `json:"gas_price"`
+	GasCost   string `json:"gas_cost"`
+}
+
+// Cursor represents how far an export has progressed.
+type Cursor struct {
+	Contract  common.Address `json:"contract"`
+	Format    string         `json:"format"`
+	NextBlock uint64         `json:"next_block"`
+	Part      int            `json:"part"`
+	Rows      int            `json:"rows"`
+	Offset    int64          `json:"offset"`
+}
+
+// Summary represents the result of an export.
+type Summary struct {
+	From  uint64
+	To    uint64
+	Rows  int
+	Files []string
+}
+
+// =============================================================================
+
+// Exporter writes ItemSet events for a contract to files.
+type Exporter struct {
+	backend    ethereum.Backend
+	filterer   *basic.BasicFilterer
+	contractID common.Address
+	signer     types.Signer
+	cfg        Config
+
+	headers map[uint64]*types.Header
+	txs     map[common.Hash]txInfo
+}
+
+// txInfo holds the details of a transaction shared by all its events.
+type txInfo struct {
+	sender   common.Address
+	gasUsed  uint64
+	gasPrice *big.Int
+}
+
+// New constructs an exporter for the basic contract at the specified address.
+func New(backend ethereum.Backend, contractID common.Address, cfg Config) (*Exporter, error) {
+	switch cfg.Format {
+	case "":
+		cfg.Format = FormatCSV
+	case FormatCSV, FormatJSONL:
+	default:
+		return nil, fmt.Errorf("unknown format %q", cfg.Format)
+	}
+
+	if cfg.Dir == "" {
+		return nil, errors.New("export directory is required")
+	}
+
+	if cfg.Prefix == "" {
+		cfg.Prefix = "itemset"
+	}
+
+	if cfg.MaxRows == 0 {
+		cfg.MaxRows = 100_000
+	}
+
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 1000
+	}
+
+	if cfg.Log == nil {
+		cfg.Log = log.Root()
+	}
+
+	filterer, err := basic.NewBasicFilterer(contractID, backend)
+	if err != nil {
+		return nil, fmt.Errorf("new filterer: %w", err)
+	}
+
+	e := Exporter{
+		backend:    backend,
+		filterer:   filterer,
+		contractID: contractID,
+		signer:     types.LatestSignerForChainID(backend.ChainID()),
+		cfg:        cfg,
+		headers:    make(map[uint64]*types.Header),
+		txs:        make(map[common.Hash]txInfo),
+	}
+
+	return &e, nil
+}
+
+// Cursor returns the saved cursor for the export directory. The boolean is
+// false when nothing has been exported yet.
+func (e *Exporter) Cursor() (Cursor, bool, error) {
+	data, err := os.ReadFile(filepath.Join(e.cfg.Dir, cursorFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Cursor{}, false, nil
+		}
+		return Cursor{}, false, fmt.Errorf("read cursor: %w", err)
+	}
+
+	var cur Cursor
+	if err := json.Unmarshal(data, &cur); err != nil {
+		return Cursor{}, false, fmt.Errorf("decode cursor: %w", err)
+	}
+
+	if cur.Contract != e.contractID || cur.Format != e.cfg.Format {
+		return Cursor{}, false, ErrCursorMismatch
+	}
+
+	return cur, true, nil
+}
+
+// Export writes the events in the block range to the export directory. When
+// a cursor exists, the export resumes after the last block it covered and
+// from is only used if it's later. A to of zero exports up to the current
+// head of the chain.
+func (e *Exporter) Export(ctx context.Context, from uint64, to uint64) (Summary, error) {
+	if err := os.MkdirAll(e.cfg.Dir, 0755); err != nil {
+		return Summary{}, fmt.Errorf("create export directory: %w", err)
+	}
+
+	cur, found, err := e.Cursor()
+	if err != nil {
+		return Summary{}, err
+	}
+
+	if !found {
+		cur = Cursor{
+			Contract: e.contractID,
+			Format:   e.cfg.Format,
+			Part:     1,
+		}
+	}
+
+	from = max(from, cur.NextBlock)
+
+	if to == 0 {
+		header, err := e.backend.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return Summary{}, fmt.Errorf("head block: %w", err)
+		}
+		to = header.Number.Uint64()
+	}
+
+	sum := Summary{
+		From: from,
+		To:   to,
+	}
+
+	if from > to {
+		return sum, nil
+	}
+
+	// =========================================================================
+
+	f, err := e.open(cur)
+	if err != nil {
+		return sum, err
+	}
+	defer func() { f.close() }()
+
+	sum.Files = append(sum.Files, f.name)
+
+	for start := from; start <= to; start += e.cfg.BatchSize {
+		end := min(start+e.cfg.BatchSize-1, to)
+
+		rows, err := e.rows(ctx, start, end)
+		if err != nil {
+			return sum, err
+		}
+
+		for _, row := range rows {
+			if cur.Rows == e.cfg.MaxRows {
+				if err := f.close(); err != nil {
+					return sum, err
+				}
+
+				cur.Part++
+				cur.Rows = 0
+				cur.Offset = 0
+
+				if f, err = e.open(cur); err != nil {
+					return sum, err
+				}
+				sum.Files = append(sum.Files, f.name)
+			}
+
+			if err := f.write(row); err != nil {
+				return sum, err
+			}
+
+			cur.Rows++
+			sum.Rows++
+		}
+
+		// The cursor is only saved once the rows are on disk, so a crash
+		// before this point repeats the batch without duplicating rows.
+		if cur.Offset, err = f.sync(); err != nil {
+			return sum, err
+		}
+		cur.NextBlock = end + 1
+
+		if err := e.save(cur); err != nil {
+			return sum, err
+		}
+
+		e.cfg.Log.Info("export", "from", start, "to", end, "rows", len(rows), "file", f.name)
+	}
+
+	return sum, nil
+}
+
+// =============================================================================
+
+// rows returns the rows for the events in the block range.
+func (e *Exporter) rows(ctx context.Context, start uint64, end uint64) ([]Row, error) {
+	iter, err := e.filterer.FilterItemSet(&bind.FilterOpts{Start: start, End: &end, Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("filter %d-%d: %w", start, end, err)
+	}
+	defer iter.Close()
+
+	var rows []Row
+	for iter.Next() {
+		ev := iter.Event
+
+		header, err := e.header(ctx, ev.Raw.BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+
+		tx, err := e.tx(ctx, ev.Raw.TxHash)
+		if err != nil {
+			return nil, err
+		}
+
+		row := Row{
+			Key:       ev.Key,
+			Value:     ev.Value.String(),
+			Block:     ev.Raw.BlockNumber,
+			Timestamp: time.Unix(int64(header.Time), 0).UTC().Format(time.RFC3339),
+			TxHash:    ev.Raw.TxHash.Hex(),
+			Sender:    tx.sender.Hex(),
+			GasUsed:   tx.gasUsed,
+			GasPrice:  tx.gasPrice.String(),
+			GasCost:   new(big.Int).Mul(tx.gasPrice, new(big.Int).SetUint64(tx.gasUsed)).String(),
+		}
+
+		rows = append(rows, row)
+	}
+
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("filter %d-%d: %w", start, end, err)
+	}
+
+	// Only the current batch can share blocks and transactions.
+	clear(e.headers)
+	clear(e.txs)
+
+	return rows, nil
+}
+
+// header returns the block header, caching it for the other events in the
+// same block.
+func (e *Exporter) header(ctx context.Context, number uint64) (*types.Header, error) {
+	if header, exists := e.headers[number]; exists {
+		return header, nil
+	}
+
+	header, err := e.backend.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return nil, fmt.Errorf("header %d: %w", number, err)
+	}
+	e.headers[number] = header
+
+	return header, nil
+}
+
+// tx returns the sender and gas details for the transaction, caching them
+// for the other events in the same transaction.
+func (e *Exporter) tx(ctx context.Context, hash common.Hash) (txInfo, error) {
+	if info, exists := e.txs[hash]; exists {
+		return info, nil
+	}
+
+	tx, _, err := e.backend.TransactionByHash(ctx, hash)
+	if err != nil {
+		return txInfo{}, fmt.Errorf("transaction %s: %w", hash.Hex(), err)
+	}
+
+	sender, err := types.Sender(e.signer, tx)
+	if err != nil {
+		return txInfo{}, fmt.Errorf("sender %s: %w", hash.Hex(), err)
+	}
+
+	receipt, err := e.backend.TransactionReceipt(ctx, hash)
+	if err != nil {
+		return txInfo{}, fmt.Errorf("receipt %s: %w", hash.Hex(), err)
+	}
+
+	gasPrice := receipt.EffectiveGasPrice
+	if gasPrice == nil {
+		gasPrice = tx.GasPrice()
+	}
+
+	info := txInfo{
+		sender:   sender,
+		gasUsed:  receipt.GasUsed,
+		gasPrice: gasPrice,
+	}
+	e.txs[hash] = info
+
+	return info, nil
+}
+
+// save writes the cursor by replacing the file so it's never left half
+// written.
+func (e *Exporter) save(cur Cursor) error {
+	data, err := json.MarshalIndent(cur, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode cursor: %w", err)
+	}
+
+	path := filepath.Join(e.cfg.Dir, cursorFile)
+
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("write cursor: %w", err)
+	}
+
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("replace cursor: %w", err)
+	}
+
+	return nil
+}
+
+// open opens the file for the cursor's part, dropping anything written past
+// the saved offset by an export that didn't finish.
+func (e *Exporter) open(cur Cursor) (*file, error) {
+	name := filepath.Join(e.cfg.Dir, fmt.Sprintf("%s-%06d.%s", e.cfg.Prefix, cur.Part, e.cfg.Format))
+
+	osf, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", name, err)
+	}
+
+	if err := osf.Truncate(cur.Offset); err != nil {
+		osf.Close()
+		return nil, fmt.Errorf("truncate %s: %w", name, err)
+	}
+
+	if _, err := osf.Seek(cur.Offset, io.SeekStart); err != nil {
+		osf.Close()
+		return nil, fmt.Errorf("seek %s: %w", name, err)
+	}
+
+	f := file{
+		name:   name,
+		format: e.cfg.Format,
+		osf:    osf,
+		buf:    bufio.NewWriter(osf),
+	}
+	f.csv = csv.NewWriter(f.buf)
+
+	if e.cfg.Format == FormatCSV && cur.Offset == 0 {
+		if err := f.csv.Write(header); err != nil {
+			f.close()
+			return nil, fmt.Errorf("write header %s: %w", name, err)
+		}
+	}
+
+	return &f, nil
+}
+
+// =============================================================================
+
+// file writes rows in one format to a single file.
+type file struct {
+	name   string
+	format string
+	osf    *os.File
+	buf    *bufio.Writer
+	csv    *csv.Writer
+}
+
+// write adds the row to the file.
+func (f *file) write(row Row) error {
+	switch f.format {
+	case FormatJSONL:
+		data, err := json.Marshal(row)
+		if err != nil {
+			return fmt.Errorf("encode row: %w", err)
+		}
+
+		data = append(data, '\n')
+		if _, err := f.buf.Write(data); err != nil {
+			return fmt.Errorf("write %s: %w", f.name, err)
+		}
+
+	default:
+		record := []string{
+			row.Key,
+			row.Value,
+			strconv.FormatUint(row.Block, 10),
+			row.Timestamp,
+			row.TxHash,
+			row.Sender,
+			strconv.FormatUint(row.GasUsed, 10),
+			row.GasPrice,
+			row.GasCost,
+		}
+
+		if err := f.csv.Write(record); err != nil {
+			return fmt.Errorf("write %s: %w", f.name, err)
+		}
+	}
+
+	return nil
+}
+
+// sync flushes the rows to disk and returns the size of the file.
+func (f *file) sync() (int64, error) {
+	f.csv.Flush()
+	if err := f.csv.Error(); err != nil {
+		return 0, fmt.Errorf("flush %s: %w", f.name, err)
+	}
+
+	if err := f.buf.Flush(); err != nil {
+		return 0, fmt.Errorf("flush %s: %w", f.name, err)
+	}
+
+	if err := f.osf.Sync(); err != nil {
+		return 0, fmt.Errorf("sync %s: %w", f.name, err)
+	}
+
+	offset, err := f.osf.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, fmt.Errorf("seek %s: %w", f.name, err)
+	}
+
+	return offset, nil
+}
+
+// close flushes and closes the file. Calling it more than once is safe.
+func (f *file) close() error {
+	if f.osf == nil {
+		return nil
+	}
+
+	_, err := f.sync()
+	if cerr := f.osf.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("close %s: %w", f.name, cerr)
+	}
+	f.osf = nil
+
+	return err
+}
@@ -0,0 +1,205 @@
+package export_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/export"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	clt, err := ethereum.NewClient(backend, backend.PrivateKeys[0])
+	if err != nil {
+		t.Fatalf("unable to create ethereum api: %s", err)
+	}
+
+	// =========================================================================
+
+	const gasLimit = 1600000
+	valueGwei := big.NewFloat(0.0)
+	gasPrice := currency.GWei2Wei(big.NewFloat(39.576))
+
+	transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+	if err != nil {
+		t.Fatalf("unable to create opts for deploy: %s", err)
+	}
+
+	contractID, tx, testBasic, err := basic.DeployBasic(transOpts, clt.Backend)
+	if err != nil {
+		t.Fatalf("unable to deploy basic: %s", err)
+	}
+
+	if _, err := clt.WaitMined(ctx, tx); err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+
+	setItem := func(key string, value int64) {
+		t.Helper()
+
+		transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+		if err != nil {
+			t.Fatalf("unable to create transaction opts for setItems: %s", err)
+		}
+
+		tx, err := testBasic.SetItem(transOpts, key, big.NewInt(value))
+		if err != nil {
+			t.Fatalf("should be able to set item: %s", err)
+		}
+
+		if _, err := clt.WaitMined(ctx, tx); err != nil {
+			t.Fatalf("waiting for set item: %s", err)
+		}
+	}
+
+	// =========================================================================
+
+	for i := range 5 {
+		setItem("bill", int64(i))
+	}
+
+	dir := t.TempDir()
+
+	cfg := export.Config{
+		Format:    export.FormatCSV,
+		Dir:       dir,
+		MaxRows:   2,
+		BatchSize: 2,
+		Log:       log.NewLogger(log.DiscardHandler()),
+	}
+
+	e, err := export.New(backend, contractID, cfg)
+	if err != nil {
+		t.Fatalf("unable to create exporter: %s", err)
+	}
+
+	sum, err := e.Export(ctx, 0, 0)
+	if err != nil {
+		t.Fatalf("should be able to export: %s", err)
+	}
+
+	if sum.Rows != 5 || len(sum.Files) != 3 {
+		t.Fatalf("wrong summary, got %d rows in %d files", sum.Rows, len(sum.Files))
+	}
+
+	// =========================================================================
+
+	// A second export resumes from the cursor and only adds the new events,
+	// filling the last file before starting another.
+	setItem("jill", 10)
+	setItem("jill", 11)
+
+	e, err = export.New(backend, contractID, cfg)
+	if err != nil {
+		t.Fatalf("unable to create exporter: %s", err)
+	}
+
+	sum, err = e.Export(ctx, 0, 0)
+	if err != nil {
+		t.Fatalf("should be able to resume: %s", err)
+	}
+
+	if sum.Rows != 2 {
+		t.Fatalf("should only export the new events, got %d", sum.Rows)
+	}
+
+	var records [][]string
+	for _, name := range []string{"itemset-000001.csv", "itemset-000002.csv", "itemset-000003.csv", "itemset-000004.csv"} {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("should be able to open %s: %s", name, err)
+		}
+
+		recs, err := csv.NewReader(f).ReadAll()
+		f.Close()
+		if err != nil {
+			t.Fatalf("should be able to read %s: %s", name, err)
+		}
+
+		if len(recs) != 3 && name != "itemset-000004.csv" {
+			t.Fatalf("%s should hold a header and two rows, got %d lines", name, len(recs))
+		}
+
+		records = append(records, recs[1:]...)
+	}
+
+	exp := []string{"0", "1", "2", "3", "4", "10", "11"}
+	if len(records) != len(exp) {
+		t.Fatalf("wrong number of rows, got %d exp %d", len(records), len(exp))
+	}
+
+	for i, rec := range records {
+		if rec[1] != exp[i] {
+			t.Fatalf("wrong value in row %d, got %s exp %s", i, rec[1], exp[i])
+		}
+
+		if rec[5] != clt.Address().Hex() {
+			t.Fatalf("wrong sender in row %d, got %s", i, rec[5])
+		}
+
+		if rec[8] == "0" || rec[3] == "" {
+			t.Fatalf("row %d should have a gas cost and timestamp, got %v", i, rec)
+		}
+	}
+
+	// =========================================================================
+
+	// The same directory can't be reused for another format.
+	cfg.Format = export.FormatJSONL
+	e, err = export.New(backend, contractID, cfg)
+	if err != nil {
+		t.Fatalf("unable to create exporter: %s", err)
+	}
+
+	if _, err := e.Export(ctx, 0, 0); err != export.ErrCursorMismatch {
+		t.Fatalf("should reject a cursor for another format, got %v", err)
+	}
+
+	cfg.Dir = t.TempDir()
+	cfg.MaxRows = 0
+	e, err = export.New(backend, contractID, cfg)
+	if err != nil {
+		t.Fatalf("unable to create exporter: %s", err)
+	}
+
+	if _, err := e.Export(ctx, 0, 0); err != nil {
+		t.Fatalf("should be able to export jsonl: %s", err)
+	}
+
+	f, err := os.Open(filepath.Join(cfg.Dir, "itemset-000001.jsonl"))
+	if err != nil {
+		t.Fatalf("should be able to open jsonl file: %s", err)
+	}
+	defer f.Close()
+
+	var rows []export.Row
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var row export.Row
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			t.Fatalf("should be able to decode row: %s", err)
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) != 7 || rows[6].Key != "jill" || rows[6].Value != "11" {
+		t.Fatalf("wrong jsonl rows, got %+v", rows)
+	}
+}
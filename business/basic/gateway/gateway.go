@@ -0,0 +1,297 @@
+// Package gateway provides a JSON over HTTP API for the basic contract so
+// services that can't use the abigen bindings can read and write items and
+// follow the transactions they submit.
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/metrics"
+	geth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ErrNotFound is returned when the node doesn't know the transaction.
+var ErrNotFound = errors.New("transaction not found")
+
+// Set of transaction statuses.
+const (
+	StatusPending = "pending"
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// Config represents the settings for the gateway.
+type Config struct {
+
+	// GasLimit is used for every SetItem transaction. A value of zero
+	// defaults to 1,600,000.
+	GasLimit uint64
+
+	// GasPriceGWei is the gas price offered for every SetItem transaction.
+	// A nil value defaults to 39.576.
+	GasPriceGWei *big.Float
+
+	// Metrics records the outcome of every submitted transaction when set.
+	Metrics *metrics.Metrics
+
+	// TrackTimeout is how long a submitted transaction is waited on for the
+	// metrics and kept as a submission. The submission is dropped as soon as
+	// the transaction is mined or the timeout passes, whichever comes first.
+	// A value of zero defaults to 10 minutes.
+	TrackTimeout time.Duration
+
+	// Log receives each submitted transaction. When nil, the root logger is
+	// used.
+	Log log.Logger
+}
+
+// Item represents the value stored for a key.
+type Item struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Submission represents a SetItem transaction sent through the gateway.
+type Submission struct {
+	TxID      string    `json:"tx_id"`
+	Key       string    `json:"key"`
+	Value     string    `json:"value"`
+	Nonce     uint64    `json:"nonce"`
+	Submitted time.Time `json:"submitted"`
+}
+
+// Receipt represents the mined outcome of a transaction.
+type Receipt struct {
+	currency.ReceiptDetails
+	BlockNumber uint64             `json:"block_number"`
+	BlockHash   string             `json:"block_hash"`
+	Logs        []currency.LogData `json:"logs"`
+}
+
+// TxStatus represents everything known about a transaction.
+type TxStatus struct {
+	Status      string                      `json:"status"`
+	Transaction currency.TransactionDetails `json:"transaction"`
+	Receipt     *Receipt                    `json:"receipt,omitempty"`
+	Submission  *Submission                 `json:"submission,omitempty"`
+}
+
+// =============================================================================
+
+// Gateway submits and reads items for a single account.
+type Gateway struct {
+	clt        *ethereum.Client
+	contract   *basic.Basic
+	contractID common.Address
+	converter  *currency.Converter
+	cfg        Config
+
+	sendMu sync.Mutex
+
+	mu          sync.RWMutex
+	submissions map[common.Hash]Submission
+}
+
+// New constructs a gateway that sends transactions from the client's account
+// to the basic contract at the specified address.
+func New(clt *ethereum.Client, contractID common.Address, converter *currency.Converter, cfg Config) (*Gateway, error) {
+	if cfg.GasLimit == 0 {
+		cfg.GasLimit = 1600000
+	}
+
+	if cfg.GasPriceGWei == nil {
+		cfg.GasPriceGWei = big.NewFloat(39.576)
+	}
+
+	if cfg.TrackTimeout == 0 {
+		cfg.TrackTimeout = 10 * time.Minute
+	}
+
+	if cfg.Log == nil {
+		cfg.Log = log.Root()
+	}
+
+	contract, err := basic.NewBasic(contractID, clt.Backend)
+	if err != nil {
+		return nil, fmt.Errorf("new contract: %w", err)
+	}
+
+	g := Gateway{
+		clt:         clt,
+		contract:    contract,
+		contractID:  contractID,
+		converter:   converter,
+		cfg:         cfg,
+		submissions: make(map[common.Hash]Submission),
+	}
+
+	return &g, nil
+}
+
+// Version returns the contract's version.
+func (g *Gateway) Version(ctx context.Context) (string, error) {
+	version, err := g.contract.Version(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return "", fmt.Errorf("version: %w", err)
+	}
+
+	return version, nil
+}
+
+// Item returns the value stored for the key.
+func (g *Gateway) Item(ctx context.Context, key string) (Item, error) {
+	value, err := g.contract.Items(&bind.CallOpts{Context: ctx}, key)
+	if err != nil {
+		return Item{}, fmt.Errorf("items %q: %w", key, err)
+	}
+
+	item := Item{
+		Key:   key,
+		Value: value.String(),
+	}
+
+	return item, nil
+}
+
+// SetItem submits a SetItem transaction without waiting for it to be mined.
+func (g *Gateway) SetItem(ctx context.Context, key string, value *big.Int) (Submission, error) {
+
+	// Sends are serialized so concurrent requests don't read the same
+	// pending nonce.
+	g.sendMu.Lock()
+	defer g.sendMu.Unlock()
+
+	tranOpts, err := g.clt.NewTransactOpts(ctx, g.cfg.GasLimit, currency.GWei2Wei(g.cfg.GasPriceGWei), big.NewFloat(0))
+	if err != nil {
+		return Submission{}, fmt.Errorf("new transact opts: %w", err)
+	}
+
+	tx, err := g.contract.SetItem(tranOpts, key, value)
+	if err != nil {
+		return Submission{}, fmt.Errorf("set item: %w", err)
+	}
+
+	sub := Submission{
+		TxID:      tx.Hash().Hex(),
+		Key:       key,
+		Value:     value.String(),
+		Nonce:     tx.Nonce(),
+		Submitted: time.Now().UTC(),
+	}
+
+	g.mu.Lock()
+	g.submissions[tx.Hash()] = sub
+	g.mu.Unlock()
+
+	g.cfg.Log.Info("gateway", "status", "submitted", "tx", sub.TxID, "key", key, "value", sub.Value, "nonce", sub.Nonce)
+
+	go g.track(tx, sub.Submitted)
+
+	return sub, nil
+}
+
+// track waits for the transaction to be mined, recording the outcome in the
+// metrics, and then drops the submission.
+func (g *Gateway) track(tx *types.Transaction, submitted time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), g.cfg.TrackTimeout)
+	defer cancel()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.submissions, tx.Hash())
+		g.mu.Unlock()
+	}()
+
+	var err error
+	switch {
+	case g.cfg.Metrics != nil:
+		_, err = g.cfg.Metrics.Track(ctx, g.clt.Backend, tx, submitted)
+	default:
+		_, err = bind.WaitMined(ctx, g.clt.Backend, tx)
+	}
+
+	if err != nil {
+		g.cfg.Log.Error("gateway", "status", "track", "tx", tx.Hash().Hex(), "ERROR", err)
+	}
+}
+
+// Submissions returns the number of submissions waiting to be mined.
+func (g *Gateway) Submissions() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return len(g.submissions)
+}
+
+// Tx returns the status of the transaction along with its receipt once it
+// has been mined. The submission is included while the transaction sent
+// through the gateway is waiting to be mined. Other transactions are
+// reported too, without a submission.
+func (g *Gateway) Tx(ctx context.Context, hash common.Hash) (TxStatus, error) {
+	tx, pending, err := g.clt.TransactionByHash(ctx, hash)
+	if err != nil {
+		if errors.Is(err, geth.NotFound) {
+			return TxStatus{}, ErrNotFound
+		}
+		return TxStatus{}, fmt.Errorf("transaction %s: %w", hash.Hex(), err)
+	}
+
+	status := TxStatus{
+		Status:      StatusPending,
+		Transaction: g.converter.CalculateTransactionDetails(tx),
+	}
+
+	g.mu.RLock()
+	if sub, exists := g.submissions[hash]; exists {
+		status.Submission = &sub
+	}
+	g.mu.RUnlock()
+
+	if pending {
+		return status, nil
+	}
+
+	receipt, err := g.clt.TransactionReceipt(ctx, hash)
+	if err != nil {
+		if errors.Is(err, geth.NotFound) {
+			return status, nil
+		}
+		return TxStatus{}, fmt.Errorf("receipt %s: %w", hash.Hex(), err)
+	}
+
+	gasPrice := receipt.EffectiveGasPrice
+	if gasPrice == nil {
+		gasPrice = tx.GasPrice()
+	}
+
+	logs, err := currency.ExtractLogData(basic.BasicMetaData.ABI, receipt)
+	if err != nil {
+		return TxStatus{}, fmt.Errorf("extract logs %s: %w", hash.Hex(), err)
+	}
+
+	status.Status = StatusSuccess
+	if receipt.Status == 0 {
+		status.Status = StatusFailed
+	}
+
+	status.Receipt = &Receipt{
+		ReceiptDetails: g.converter.CalculateReceiptDetails(receipt, gasPrice),
+		BlockNumber:    receipt.BlockNumber.Uint64(),
+		BlockHash:      receipt.BlockHash.Hex(),
+		Logs:           logs,
+	}
+
+	return status, nil
+}
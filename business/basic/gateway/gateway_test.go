@@ -0,0 +1,139 @@
+package gateway_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/gateway"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func TestGateway(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	clt, err := ethereum.NewClient(backend, backend.PrivateKeys[0])
+	if err != nil {
+		t.Fatalf("unable to create ethereum api: %s", err)
+	}
+
+	// =========================================================================
+
+	const gasLimit = 1600000
+	valueGwei := big.NewFloat(0.0)
+	gasPrice := currency.GWei2Wei(big.NewFloat(39.576))
+
+	transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+	if err != nil {
+		t.Fatalf("unable to create opts for deploy: %s", err)
+	}
+
+	contractID, tx, _, err := basic.DeployBasic(transOpts, clt.Backend)
+	if err != nil {
+		t.Fatalf("unable to deploy basic: %s", err)
+	}
+
+	if _, err := clt.WaitMined(ctx, tx); err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+
+	// =========================================================================
+
+	converter := currency.NewDefaultConverter(basic.BasicMetaData.ABI)
+
+	g, err := gateway.New(clt, contractID, converter, gateway.Config{Log: log.NewLogger(log.DiscardHandler())})
+	if err != nil {
+		t.Fatalf("unable to create gateway: %s", err)
+	}
+
+	srv := httptest.NewServer(g.Handler())
+	defer srv.Close()
+
+	var version map[string]string
+	call(t, http.MethodGet, srv.URL+"/version", "", http.StatusOK, &version)
+
+	if version["version"] != "1.1" {
+		t.Fatalf("wrong version, got %v", version)
+	}
+
+	var sub gateway.Submission
+	call(t, http.MethodPut, srv.URL+"/items/bill", `{"value": "115792089237316195423570985008687907853269984665640564039457584007913129639935"}`, http.StatusAccepted, &sub)
+
+	if sub.TxID == "" || sub.Key != "bill" {
+		t.Fatalf("wrong submission, got %+v", sub)
+	}
+
+	var status gateway.TxStatus
+	call(t, http.MethodGet, srv.URL+"/tx/"+sub.TxID, "", http.StatusOK, &status)
+
+	if status.Status != gateway.StatusSuccess || status.Receipt == nil {
+		t.Fatalf("wrong status, got %+v", status)
+	}
+
+	// The submission is dropped once the transaction is mined.
+	deadline := time.Now().Add(5 * time.Second)
+	for g.Submissions() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("should drop the mined submission, got %d", g.Submissions())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if len(status.Receipt.Logs) != 1 || status.Receipt.Logs[0].EventName != "ItemSet" {
+		t.Fatalf("should decode the ItemSet log, got %+v", status.Receipt.Logs)
+	}
+
+	var item gateway.Item
+	call(t, http.MethodGet, srv.URL+"/items/bill", "", http.StatusOK, &item)
+
+	if item.Value != sub.Value {
+		t.Fatalf("wrong item, got %+v exp %s", item, sub.Value)
+	}
+
+	// =========================================================================
+
+	var resp map[string]string
+	call(t, http.MethodPut, srv.URL+"/items/bill", `{"value": "-1"}`, http.StatusBadRequest, &resp)
+	call(t, http.MethodPut, srv.URL+"/items/bill", `{"value": "1e3"}`, http.StatusBadRequest, &resp)
+	call(t, http.MethodGet, srv.URL+"/tx/0x1234", "", http.StatusBadRequest, &resp)
+	call(t, http.MethodGet, srv.URL+"/tx/0x"+strings.Repeat("ab", 32), "", http.StatusNotFound, &resp)
+}
+
+// =============================================================================
+
+func call(t *testing.T, method string, url string, body string, statusCode int, v any) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unable to create request: %s", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("should be able to call %s %s: %s", method, url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != statusCode {
+		t.Fatalf("wrong status code for %s %s, got %d exp %d", method, url, resp.StatusCode, statusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("should be able to decode response: %s", err)
+	}
+}
@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// setRequest represents the body for setting an item. The value is a
+// decimal string so values beyond the range of a JSON number survive.
+type setRequest struct {
+	Value string `json:"value"`
+}
+
+// Handler returns the HTTP handler for the gateway.
+//
+//	GET /items/{key}  value stored for the key
+//	PUT /items/{key}  submit SetItem with {"value": "..."}, returns the tx id
+//	GET /tx/{hash}    transaction status and decoded receipt
+//	GET /version      contract version
+func (g *Gateway) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /items/{key}", func(w http.ResponseWriter, r *http.Request) {
+		item, err := g.Item(r.Context(), r.PathValue("key"))
+		if err != nil {
+			respondError(w, http.StatusBadGateway, err)
+			return
+		}
+
+		respond(w, http.StatusOK, item)
+	})
+
+	mux.HandleFunc("PUT /items/{key}", func(w http.ResponseWriter, r *http.Request) {
+		var req setRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		value, ok := new(big.Int).SetString(req.Value, 10)
+		if !ok || value.Sign() < 0 || value.BitLen() > 256 {
+			respondError(w, http.StatusBadRequest, errors.New("value must be an unsigned 256 bit decimal integer"))
+			return
+		}
+
+		sub, err := g.SetItem(r.Context(), r.PathValue("key"), value)
+		if err != nil {
+			respondError(w, http.StatusBadGateway, err)
+			return
+		}
+
+		w.Header().Set("Location", "/tx/"+sub.TxID)
+		respond(w, http.StatusAccepted, sub)
+	})
+
+	mux.HandleFunc("GET /tx/{hash}", func(w http.ResponseWriter, r *http.Request) {
+		hash := r.PathValue("hash")
+		if len(common.FromHex(hash)) != common.HashLength {
+			respondError(w, http.StatusBadRequest, errors.New("invalid transaction hash"))
+			return
+		}
+
+		status, err := g.Tx(r.Context(), common.HexToHash(hash))
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				respondError(w, http.StatusNotFound, err)
+				return
+			}
+			respondError(w, http.StatusBadGateway, err)
+			return
+		}
+
+		respond(w, http.StatusOK, status)
+	})
+
+	mux.HandleFunc("GET /version", func(w http.ResponseWriter, r *http.Request) {
+		version, err := g.Version(r.Context())
+		if err != nil {
+			respondError(w, http.StatusBadGateway, err)
+			return
+		}
+
+		respond(w, http.StatusOK, map[string]string{"version": version, "contract": g.contractID.Hex()})
+	})
+
+	return mux
+}
+
+// respond writes the value as a JSON response.
+func respond(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
+
+// respondError writes the error as a JSON response.
+func respondError(w http.ResponseWriter, statusCode int, err error) {
+	respond(w, statusCode, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,245 @@
+// Package historical reads the basic contract as it was at a past block.
+// Blocks can be named by number, by hash or by time, and a key can be
+// sampled across a range of blocks to see how its value moved. Reads at old
+// blocks need a node that keeps the historical state, such as an archive
+// node, otherwise the node reports the state as missing.
+package historical
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// MaxSamples is the most blocks a single Sample call will read.
+const MaxSamples = 1000
+
+// ErrBeforeGenesis is returned when a time is earlier than the first block.
+var ErrBeforeGenesis = errors.New("time is before the genesis block")
+
+// Backend declares the behavior the reader needs from a node.
+type Backend interface {
+	bind.ContractCaller
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+}
+
+// Sample represents the value of a key at a block.
+type Sample struct {
+	Block   uint64   `json:"block"`
+	Time    uint64   `json:"time"`
+	Value   *big.Int `json:"value"`
+	Changed bool     `json:"changed"`
+}
+
+// Reader provides reads of the contract at past blocks.
+type Reader struct {
+	backend  Backend
+	contract *basic.BasicCaller
+}
+
+// New constructs a reader for the contract at the address.
+func New(backend Backend, contractID common.Address) (*Reader, error) {
+	contract, err := basic.NewBasicCaller(contractID, backend)
+	if err != nil {
+		return nil, fmt.Errorf("new contract: %w", err)
+	}
+
+	r := Reader{
+		backend:  backend,
+		contract: contract,
+	}
+
+	return &r, nil
+}
+
+// =============================================================================
+
+// Head returns the header of the latest block.
+func (r *Reader) Head(ctx context.Context) (*types.Header, error) {
+	header, err := r.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("head block: %w", err)
+	}
+
+	return header, nil
+}
+
+// BlockByNumber returns the header of the block with the number.
+func (r *Reader) BlockByNumber(ctx context.Context, number uint64) (*types.Header, error) {
+	header, err := r.backend.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return nil, fmt.Errorf("block %d: %w", number, err)
+	}
+
+	return header, nil
+}
+
+// BlockByHash returns the header of the block with the hash. Reads are made
+// by block number, so a block that has since been reorged out is refused
+// rather than silently reading its replacement.
+func (r *Reader) BlockByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	header, err := r.backend.HeaderByHash(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("block %s: %w", hash.Hex(), err)
+	}
+
+	canonical, err := r.backend.HeaderByNumber(ctx, header.Number)
+	if err != nil {
+		return nil, fmt.Errorf("block %d: %w", header.Number, err)
+	}
+
+	if canonical.Hash() != hash {
+		return nil, fmt.Errorf("block %s is not on the canonical chain", hash.Hex())
+	}
+
+	return header, nil
+}
+
+// BlockByTime returns the header of the last block mined at or before the
+// time. Block times only move forward, so the block is found with a binary
+// search over the headers between genesis and the head.
+func (r *Reader) BlockByTime(ctx context.Context, t time.Time) (*types.Header, error) {
+	if t.Unix() < 0 {
+		return nil, fmt.Errorf("%s: %w", t.UTC().Format(time.RFC3339), ErrBeforeGenesis)
+	}
+	target := uint64(t.Unix())
+
+	head, err := r.Head(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if head.Time <= target {
+		return head, nil
+	}
+
+	genesis, err := r.BlockByNumber(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if genesis.Time > target {
+		return nil, fmt.Errorf("%s: %w", t.UTC().Format(time.RFC3339), ErrBeforeGenesis)
+	}
+
+	// The answer is always in [lo, hi), lo is known to be at or before the
+	// time and hi is known to be after it.
+	lo, hi := genesis, head
+	for hi.Number.Uint64()-lo.Number.Uint64() > 1 {
+		mid, err := r.BlockByNumber(ctx, (lo.Number.Uint64()+hi.Number.Uint64())/2)
+		if err != nil {
+			return nil, err
+		}
+
+		if mid.Time <= target {
+			lo = mid
+			continue
+		}
+		hi = mid
+	}
+
+	return lo, nil
+}
+
+// =============================================================================
+
+// Item returns the value of the key at the block.
+func (r *Reader) Item(ctx context.Context, header *types.Header, key string) (*big.Int, error) {
+	value, err := r.contract.Items(callOpts(ctx, header), key)
+	if err != nil {
+		return nil, fmt.Errorf("read %s at block %d: %w", key, header.Number, err)
+	}
+
+	return value, nil
+}
+
+// Version returns the version of the contract at the block.
+func (r *Reader) Version(ctx context.Context, header *types.Header) (string, error) {
+	version, err := r.contract.Version(callOpts(ctx, header))
+	if err != nil {
+		return "", fmt.Errorf("read version at block %d: %w", header.Number, err)
+	}
+
+	return version, nil
+}
+
+// Sample reads the key every step blocks from the first block to the last,
+// always including the last block. Each sample notes whether the value
+// changed since the previous one.
+func (r *Reader) Sample(ctx context.Context, key string, from uint64, to uint64, step uint64) ([]Sample, error) {
+	if step == 0 {
+		return nil, errors.New("step must be at least 1")
+	}
+
+	if from > to {
+		return nil, fmt.Errorf("from block %d is after to block %d", from, to)
+	}
+
+	// Every step from the first block, plus the last block when the steps
+	// don't land on it.
+	steps := (to - from) / step
+	if (to-from)%step != 0 {
+		steps++
+	}
+
+	if steps >= MaxSamples {
+		return nil, fmt.Errorf("range would read more than %d blocks, use a larger step", MaxSamples)
+	}
+
+	samples := make([]Sample, 0, steps+1)
+	for number := from; ; {
+		header, err := r.BlockByNumber(ctx, number)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := r.Item(ctx, header, key)
+		if err != nil {
+			return nil, err
+		}
+
+		s := Sample{
+			Block: number,
+			Time:  header.Time,
+			Value: value,
+		}
+
+		if len(samples) > 0 {
+			s.Changed = samples[len(samples)-1].Value.Cmp(value) != 0
+		}
+
+		samples = append(samples, s)
+
+		if number == to {
+			break
+		}
+
+		// Checking the distance left avoids overflowing past the last block.
+		switch {
+		case to-number < step:
+			number = to
+		default:
+			number += step
+		}
+	}
+
+	return samples, nil
+}
+
+// =============================================================================
+
+// callOpts returns the options for a call against the state at the block.
+func callOpts(ctx context.Context, header *types.Header) *bind.CallOpts {
+	return &bind.CallOpts{
+		Context:     ctx,
+		BlockNumber: header.Number,
+	}
+}
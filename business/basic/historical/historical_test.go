@@ -0,0 +1,178 @@
+package historical_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/historical"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+func TestReader(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	clt, err := ethereum.NewClient(backend, backend.PrivateKeys[0])
+	if err != nil {
+		t.Fatalf("unable to create ethereum api: %s", err)
+	}
+
+	transOpts := func() *bind.TransactOpts {
+		opts, err := clt.NewTransactOpts(ctx, 1600000, currency.GWei2Wei(big.NewFloat(39.576)), big.NewFloat(0))
+		if err != nil {
+			t.Fatalf("unable to create transaction opts: %s", err)
+		}
+		return opts
+	}
+
+	// =========================================================================
+
+	contractID, tx, contract, err := basic.DeployBasic(transOpts(), clt.Backend)
+	if err != nil {
+		t.Fatalf("unable to deploy basic: %s", err)
+	}
+
+	if _, err := clt.WaitMined(ctx, tx); err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+
+	// Each write is mined in its own block, so bill is 1 at the first block,
+	// 2 at the second and 3 at the third.
+	var blocks []uint64
+	for i := int64(1); i <= 3; i++ {
+		tx, err := contract.SetItem(transOpts(), "bill", big.NewInt(i))
+		if err != nil {
+			t.Fatalf("unable to set item: %s", err)
+		}
+
+		receipt, err := clt.WaitMined(ctx, tx)
+		if err != nil {
+			t.Fatalf("waiting for set item: %s", err)
+		}
+		blocks = append(blocks, receipt.BlockNumber.Uint64())
+	}
+
+	r, err := historical.New(backend, contractID)
+	if err != nil {
+		t.Fatalf("unable to create reader: %s", err)
+	}
+
+	// =========================================================================
+
+	header, err := r.BlockByNumber(ctx, blocks[0])
+	if err != nil {
+		t.Fatalf("should find the first block: %s", err)
+	}
+
+	value, err := r.Item(ctx, header, "bill")
+	if err != nil {
+		t.Fatalf("should read bill at the first block: %s", err)
+	}
+
+	if value.Int64() != 1 {
+		t.Fatalf("wrong value at block %d, got %v exp 1", blocks[0], value)
+	}
+
+	version, err := r.Version(ctx, header)
+	if err != nil {
+		t.Fatalf("should read the version: %s", err)
+	}
+
+	if version != "1.1" {
+		t.Fatalf("wrong version, got %s", version)
+	}
+
+	byHash, err := r.BlockByHash(ctx, header.Hash())
+	if err != nil {
+		t.Fatalf("should find the block by hash: %s", err)
+	}
+
+	if byHash.Number.Uint64() != blocks[0] {
+		t.Fatalf("wrong block by hash, got %d exp %d", byHash.Number, blocks[0])
+	}
+
+	// =========================================================================
+
+	// Each block's own time resolves to it, and any later time to the head.
+	for _, number := range blocks {
+		want, err := r.BlockByNumber(ctx, number)
+		if err != nil {
+			t.Fatalf("should find block %d: %s", number, err)
+		}
+
+		byTime, err := r.BlockByTime(ctx, time.Unix(int64(want.Time), 0))
+		if err != nil {
+			t.Fatalf("should find the block at %d: %s", want.Time, err)
+		}
+
+		if byTime.Hash() != want.Hash() {
+			t.Fatalf("wrong block at %d, got %d exp %d", want.Time, byTime.Number, number)
+		}
+	}
+
+	head, err := r.BlockByTime(ctx, time.Unix(1<<62, 0))
+	if err != nil {
+		t.Fatalf("should find the head for a future time: %s", err)
+	}
+
+	if head.Number.Uint64() != blocks[2] {
+		t.Fatalf("wrong head, got %d exp %d", head.Number, blocks[2])
+	}
+
+	genesis, err := r.BlockByNumber(ctx, 0)
+	if err != nil {
+		t.Fatalf("should find genesis: %s", err)
+	}
+
+	if _, err := r.BlockByTime(ctx, time.Unix(int64(genesis.Time)-1, 0)); !errors.Is(err, historical.ErrBeforeGenesis) {
+		t.Fatalf("should refuse a time before genesis, got %v", err)
+	}
+
+	// =========================================================================
+
+	samples, err := r.Sample(ctx, "bill", blocks[0]-1, blocks[2], 2)
+	if err != nil {
+		t.Fatalf("should sample bill: %s", err)
+	}
+
+	exp := []struct {
+		block   uint64
+		value   int64
+		changed bool
+	}{
+		{blocks[0] - 1, 0, false},
+		{blocks[1], 2, true},
+		{blocks[2], 3, true},
+	}
+
+	if len(samples) != len(exp) {
+		t.Fatalf("wrong number of samples, got %d exp %d", len(samples), len(exp))
+	}
+
+	for i, e := range exp {
+		s := samples[i]
+		if s.Block != e.block || s.Value.Int64() != e.value || s.Changed != e.changed {
+			t.Fatalf("wrong sample %d, got %+v exp %+v", i, s, e)
+		}
+	}
+
+	if _, err := r.Sample(ctx, "bill", 0, historical.MaxSamples, 1); err == nil {
+		t.Fatal("should refuse a range with too many samples")
+	}
+
+	// The steps stop short of the last block, so it adds one more sample.
+	if _, err := r.Sample(ctx, "bill", 0, 2*historical.MaxSamples-1, 2); err == nil {
+		t.Fatal("should count the sample taken at the last block")
+	}
+}
@@ -0,0 +1,554 @@
+// Package indexer provides support for persisting the ItemSet events emitted
+// by the basic contract, along with the current value of each key, in an
+// embedded pebble database.
+package indexer
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/watch"
+	"github.com/cockroachdb/pebble"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ErrContractMismatch is returned when a database built for one contract is
+// opened for another.
+var ErrContractMismatch = errors.New("database was built for a different contract")
+
+// Set of key prefixes used in the database.
+const (
+	prefixEvent    = 'e' // e + block + log index -> Event
+	prefixHistory  = 'h' // h + key length + key + block + log index -> nothing
+	prefixItem     = 'i' // i + key -> Item
+	prefixMetadata = 'm' // m + name -> value
+)
+
+// Set of metadata entries.
+var (
+	keyCheckpoint = []byte{prefixMetadata, 'c'}
+	keyContract   = []byte{prefixMetadata, 'a'}
+)
+
+// Config represents the settings for the indexer.
+type Config struct {
+
+	// ContractID is the address of the basic contract to index.
+	ContractID common.Address
+
+	// DeployBlock is the block the contract was deployed in. Backfilling
+	// starts here the first time the database is used.
+	DeployBlock uint64
+
+	// BatchSize is the number of blocks requested with each FilterItemSet
+	// call while backfilling. A value of zero defaults to 1000.
+	BatchSize uint64
+
+	// Log receives progress and errors. When nil, the root logger is used.
+	Log log.Logger
+}
+
+// Event represents an ItemSet event that has been indexed.
+type Event struct {
+	Key       string      `json:"key"`
+	Value     *big.Int    `json:"value"`
+	Block     uint64      `json:"block"`
+	BlockHash common.Hash `json:"block_hash"`
+	TxHash    common.Hash `json:"tx_hash"`
+	TxIndex   uint        `json:"tx_index"`
+	LogIndex  uint        `json:"log_index"`
+}
+
+// Item represents the current value for a key and the event that set it.
+type Item struct {
+	Key      string      `json:"key"`
+	Value    *big.Int    `json:"value"`
+	Block    uint64      `json:"block"`
+	TxHash   common.Hash `json:"tx_hash"`
+	LogIndex uint        `json:"log_index"`
+}
+
+// =============================================================================
+
+// Indexer backfills and tails the ItemSet events for the basic contract.
+type Indexer struct {
+	backend  bind.ContractBackend
+	filterer *basic.BasicFilterer
+	watcher  *watch.Watcher
+	cfg      Config
+	db       *pebble.DB
+	live     atomic.Bool
+}
+
+// Open opens, or creates, the database at the specified path for indexing
+// the contract described by the config.
+func Open(path string, backend bind.ContractBackend, cfg Config) (*Indexer, error) {
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 1000
+	}
+
+	if cfg.Log == nil {
+		cfg.Log = log.Root()
+	}
+
+	filterer, err := basic.NewBasicFilterer(cfg.ContractID, backend)
+	if err != nil {
+		return nil, fmt.Errorf("new filterer: %w", err)
+	}
+
+	watcher, err := watch.New(backend, cfg.ContractID, watch.Config{Log: cfg.Log})
+	if err != nil {
+		return nil, fmt.Errorf("new watcher: %w", err)
+	}
+
+	db, err := pebble.Open(path, &pebble.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+
+	contract, err := get(db, keyContract)
+	switch {
+	case err != nil:
+		db.Close()
+		return nil, err
+
+	case contract == nil:
+		if err := db.Set(keyContract, cfg.ContractID.Bytes(), pebble.Sync); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("write contract: %w", err)
+		}
+
+	case common.BytesToAddress(contract) != cfg.ContractID:
+		db.Close()
+		return nil, fmt.Errorf("%w: %s", ErrContractMismatch, common.BytesToAddress(contract))
+	}
+
+	idx := Indexer{
+		backend:  backend,
+		filterer: filterer,
+		watcher:  watcher,
+		cfg:      cfg,
+		db:       db,
+	}
+
+	return &idx, nil
+}
+
+// Close closes the database. Run must have returned before calling Close.
+func (idx *Indexer) Close() error {
+	return idx.db.Close()
+}
+
+// Run indexes events until the context is cancelled. It subscribes to new
+// events, backfills from the checkpoint to the head of the chain, then
+// applies the events from the subscription. Events seen by both are only
+// applied once. If the subscription or the backfill fails, it starts over
+// from the checkpoint.
+func (idx *Indexer) Run(ctx context.Context) error {
+	h := watch.Handler{
+		Start: func(ctx context.Context) error {
+			if err := idx.backfill(ctx); err != nil {
+				return fmt.Errorf("backfill: %w", err)
+			}
+
+			idx.live.Store(true)
+			idx.cfg.Log.Info("indexer", "status", "live")
+
+			return nil
+		},
+		Event: func(ctx context.Context, ev *basic.BasicItemSet) error {
+			return idx.apply([]*basic.BasicItemSet{ev}, ev.Raw.BlockNumber)
+		},
+		Stop: func() {
+			idx.live.Store(false)
+		},
+	}
+
+	return idx.watcher.Run(ctx, h)
+}
+
+// Live reports whether the backfill is complete and events are being applied
+// as they arrive.
+func (idx *Indexer) Live() bool {
+	return idx.live.Load()
+}
+
+// Checkpoint returns the block indexing resumes from after a restart.
+func (idx *Indexer) Checkpoint() (uint64, error) {
+	data, err := get(idx.db, keyCheckpoint)
+	if err != nil {
+		return 0, err
+	}
+
+	if data == nil {
+		return idx.cfg.DeployBlock, nil
+	}
+
+	return binary.BigEndian.Uint64(data), nil
+}
+
+// Item returns the current value for the key. False is returned if the key
+// has never been set.
+func (idx *Indexer) Item(key string) (Item, bool, error) {
+	data, err := get(idx.db, itemKey(key))
+	if err != nil || data == nil {
+		return Item{}, false, err
+	}
+
+	var item Item
+	if err := json.Unmarshal(data, &item); err != nil {
+		return Item{}, false, fmt.Errorf("decode item: %w", err)
+	}
+
+	return item, true, nil
+}
+
+// Items returns the current value for every key, ordered by key.
+func (idx *Indexer) Items() ([]Item, error) {
+	iter, err := idx.db.NewIter(prefixBounds([]byte{prefixItem}))
+	if err != nil {
+		return nil, fmt.Errorf("new iterator: %w", err)
+	}
+	defer iter.Close()
+
+	var items []Item
+	for iter.First(); iter.Valid(); iter.Next() {
+		var item Item
+		if err := json.Unmarshal(iter.Value(), &item); err != nil {
+			return nil, fmt.Errorf("decode item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, iter.Error()
+}
+
+// Count returns the number of keys that have been set.
+func (idx *Indexer) Count() (int, error) {
+	iter, err := idx.db.NewIter(prefixBounds([]byte{prefixItem}))
+	if err != nil {
+		return 0, fmt.Errorf("new iterator: %w", err)
+	}
+	defer iter.Close()
+
+	var n int
+	for iter.First(); iter.Valid(); iter.Next() {
+		n++
+	}
+
+	return n, iter.Error()
+}
+
+// Events returns the events indexed between the from and to blocks
+// inclusive, in the order they were emitted.
+func (idx *Indexer) Events(from uint64, to uint64) ([]Event, error) {
+	iter, err := idx.db.NewIter(&pebble.IterOptions{
+		LowerBound: eventKey(from, 0),
+		UpperBound: eventKey(to+1, 0),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("new iterator: %w", err)
+	}
+	defer iter.Close()
+
+	var events []Event
+	for iter.First(); iter.Valid(); iter.Next() {
+		var ev Event
+		if err := json.Unmarshal(iter.Value(), &ev); err != nil {
+			return nil, fmt.Errorf("decode event: %w", err)
+		}
+		events = append(events, ev)
+	}
+
+	return events, iter.Error()
+}
+
+// History returns every event that set the key, oldest first.
+func (idx *Indexer) History(key string) ([]Event, error) {
+	return history(idx.db, key)
+}
+
+// =============================================================================
+
+// backfill reads the events from the checkpoint to the head of the chain in
+// ranges of BatchSize blocks.
+func (idx *Indexer) backfill(ctx context.Context) error {
+	from, err := idx.Checkpoint()
+	if err != nil {
+		return err
+	}
+
+	header, err := idx.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("head block: %w", err)
+	}
+	head := header.Number.Uint64()
+
+	for from <= head {
+		to := min(from+idx.cfg.BatchSize-1, head)
+
+		iter, err := idx.filterer.FilterItemSet(&bind.FilterOpts{Start: from, End: &to, Context: ctx})
+		if err != nil {
+			return fmt.Errorf("filter %d-%d: %w", from, to, err)
+		}
+
+		var events []*basic.BasicItemSet
+		for iter.Next() {
+			events = append(events, iter.Event)
+		}
+		iter.Close()
+
+		if err := iter.Error(); err != nil {
+			return fmt.Errorf("filter %d-%d: %w", from, to, err)
+		}
+
+		if err := idx.apply(events, to+1); err != nil {
+			return err
+		}
+
+		idx.cfg.Log.Info("indexer", "status", "backfill", "from", from, "to", to, "head", head, "events", len(events))
+
+		from = to + 1
+	}
+
+	return nil
+}
+
+// apply stores the events and moves the checkpoint forward to the specified
+// block in a single atomic write. The checkpoint never moves backwards.
+func (idx *Indexer) apply(events []*basic.BasicItemSet, checkpoint uint64) error {
+	b := idx.db.NewIndexedBatch()
+	defer b.Close()
+
+	for _, ev := range events {
+		var err error
+		switch {
+		case ev.Raw.Removed:
+			err = remove(b, ev)
+		default:
+			err = insert(b, ev)
+		}
+
+		if err != nil {
+			return fmt.Errorf("apply block %d log %d: %w", ev.Raw.BlockNumber, ev.Raw.Index, err)
+		}
+	}
+
+	current, err := get(b, keyCheckpoint)
+	if err != nil {
+		return err
+	}
+
+	if current == nil || binary.BigEndian.Uint64(current) < checkpoint {
+		if err := b.Set(keyCheckpoint, binary.BigEndian.AppendUint64(nil, checkpoint), nil); err != nil {
+			return fmt.Errorf("write checkpoint: %w", err)
+		}
+	}
+
+	if err := b.Commit(pebble.Sync); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// reader is implemented by the database and indexed batches.
+type reader interface {
+	Get(key []byte) ([]byte, io.Closer, error)
+	NewIter(o *pebble.IterOptions) (*pebble.Iterator, error)
+}
+
+// insert stores a new event and updates the key if the event is newer than
+// the one that last set it. Events already stored are ignored.
+func insert(b *pebble.Batch, ev *basic.BasicItemSet) error {
+	ek := eventKey(ev.Raw.BlockNumber, ev.Raw.Index)
+
+	existing, err := get(b, ek)
+	if err != nil || existing != nil {
+		return err
+	}
+
+	event := Event{
+		Key:       ev.Key,
+		Value:     ev.Value,
+		Block:     ev.Raw.BlockNumber,
+		BlockHash: ev.Raw.BlockHash,
+		TxHash:    ev.Raw.TxHash,
+		TxIndex:   ev.Raw.TxIndex,
+		LogIndex:  ev.Raw.Index,
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+
+	if err := b.Set(ek, data, nil); err != nil {
+		return err
+	}
+
+	if err := b.Set(historyKey(ev.Key, ev.Raw.BlockNumber, ev.Raw.Index), nil, nil); err != nil {
+		return err
+	}
+
+	return setItem(b, ev.Key)
+}
+
+// remove deletes an event that is no longer part of the canonical chain and
+// restores the key to the value set by the latest remaining event.
+func remove(b *pebble.Batch, ev *basic.BasicItemSet) error {
+	if err := b.Delete(eventKey(ev.Raw.BlockNumber, ev.Raw.Index), nil); err != nil {
+		return err
+	}
+
+	if err := b.Delete(historyKey(ev.Key, ev.Raw.BlockNumber, ev.Raw.Index), nil); err != nil {
+		return err
+	}
+
+	return setItem(b, ev.Key)
+}
+
+// setItem writes the current value for the key from the latest event in its
+// history, or deletes the key if it has no history.
+func setItem(b *pebble.Batch, key string) error {
+	iter, err := b.NewIter(prefixBounds(historyPrefix(key)))
+	if err != nil {
+		return fmt.Errorf("new iterator: %w", err)
+	}
+	defer iter.Close()
+
+	if !iter.Last() {
+		if err := iter.Error(); err != nil {
+			return err
+		}
+		return b.Delete(itemKey(key), nil)
+	}
+
+	pos := iter.Key()[len(historyPrefix(key)):]
+
+	data, err := get(b, append([]byte{prefixEvent}, pos...))
+	if err != nil {
+		return err
+	}
+
+	if data == nil {
+		return fmt.Errorf("missing event for key %q", key)
+	}
+
+	var event Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return fmt.Errorf("decode event: %w", err)
+	}
+
+	item := Item{
+		Key:      event.Key,
+		Value:    event.Value,
+		Block:    event.Block,
+		TxHash:   event.TxHash,
+		LogIndex: event.LogIndex,
+	}
+
+	data, err = json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("encode item: %w", err)
+	}
+
+	return b.Set(itemKey(key), data, nil)
+}
+
+// history returns every event that set the key, oldest first.
+func history(r reader, key string) ([]Event, error) {
+	prefix := historyPrefix(key)
+
+	iter, err := r.NewIter(prefixBounds(prefix))
+	if err != nil {
+		return nil, fmt.Errorf("new iterator: %w", err)
+	}
+	defer iter.Close()
+
+	var events []Event
+	for iter.First(); iter.Valid(); iter.Next() {
+		data, err := get(r, append([]byte{prefixEvent}, iter.Key()[len(prefix):]...))
+		if err != nil {
+			return nil, err
+		}
+
+		if data == nil {
+			return nil, fmt.Errorf("missing event for key %q", key)
+		}
+
+		var ev Event
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return nil, fmt.Errorf("decode event: %w", err)
+		}
+		events = append(events, ev)
+	}
+
+	return events, iter.Error()
+}
+
+// get returns a copy of the value for the key, or nil if the key is not
+// found.
+func get(r reader, key []byte) ([]byte, error) {
+	data, closer, err := r.Get(key)
+	if err != nil {
+		if errors.Is(err, pebble.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get: %w", err)
+	}
+	defer closer.Close()
+
+	return append([]byte(nil), data...), nil
+}
+
+// =============================================================================
+
+func eventKey(block uint64, logIndex uint) []byte {
+	k := []byte{prefixEvent}
+	k = binary.BigEndian.AppendUint64(k, block)
+	k = binary.BigEndian.AppendUint32(k, uint32(logIndex))
+	return k
+}
+
+func historyPrefix(key string) []byte {
+	k := []byte{prefixHistory}
+	k = binary.BigEndian.AppendUint32(k, uint32(len(key)))
+	k = append(k, key...)
+	return k
+}
+
+func historyKey(key string, block uint64, logIndex uint) []byte {
+	k := historyPrefix(key)
+	k = binary.BigEndian.AppendUint64(k, block)
+	k = binary.BigEndian.AppendUint32(k, uint32(logIndex))
+	return k
+}
+
+func itemKey(key string) []byte {
+	return append([]byte{prefixItem}, key...)
+}
+
+// prefixBounds returns iterator options covering every key with the prefix.
+func prefixBounds(prefix []byte) *pebble.IterOptions {
+	upper := append([]byte(nil), prefix...)
+	for i := len(upper) - 1; i >= 0; i-- {
+		upper[i]++
+		if upper[i] != 0 {
+			return &pebble.IterOptions{LowerBound: prefix, UpperBound: upper[:i+1]}
+		}
+	}
+
+	return &pebble.IterOptions{LowerBound: prefix}
+}
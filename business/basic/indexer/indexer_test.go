@@ -0,0 +1,219 @@
+package indexer_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/indexer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func TestIndexer(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	clt, err := ethereum.NewClient(backend, backend.PrivateKeys[0])
+	if err != nil {
+		t.Fatalf("unable to create ethereum api: %s", err)
+	}
+
+	// =========================================================================
+
+	const gasLimit = 1600000
+	valueGwei := big.NewFloat(0.0)
+	gasPrice := currency.GWei2Wei(big.NewFloat(39.576))
+
+	transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+	if err != nil {
+		t.Fatalf("unable to create opts for deploy: %s", err)
+	}
+
+	contractID, tx, testBasic, err := basic.DeployBasic(transOpts, clt.Backend)
+	if err != nil {
+		t.Fatalf("unable to deploy basic: %s", err)
+	}
+
+	receipt, err := clt.WaitMined(ctx, tx)
+	if err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+
+	setItem := func(key string, value int64) {
+		t.Helper()
+
+		transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+		if err != nil {
+			t.Fatalf("unable to create transaction opts for setItems: %s", err)
+		}
+
+		tx, err := testBasic.SetItem(transOpts, key, big.NewInt(value))
+		if err != nil {
+			t.Fatalf("should be able to set item: %s", err)
+		}
+
+		if _, err := clt.WaitMined(ctx, tx); err != nil {
+			t.Fatalf("waiting for set item: %s", err)
+		}
+	}
+
+	// =========================================================================
+
+	setItem("bill", 1)
+	setItem("jill", 2)
+	setItem("bill", 3)
+	setItem("jack", 4)
+	setItem("bill", 5)
+
+	cfg := indexer.Config{
+		ContractID:  contractID,
+		DeployBlock: receipt.BlockNumber.Uint64(),
+		BatchSize:   2,
+		Log:         log.NewLogger(log.DiscardHandler()),
+	}
+
+	path := t.TempDir()
+
+	idx, stop := start(t, path, backend, cfg)
+
+	waitFor(t, "backfill", idx.Live)
+
+	checkItem(t, idx, "bill", 5)
+	checkItem(t, idx, "jill", 2)
+	checkItem(t, idx, "jack", 4)
+
+	events, err := idx.History("bill")
+	if err != nil {
+		t.Fatalf("should be able to read history: %s", err)
+	}
+
+	if len(events) != 3 || events[0].Value.Int64() != 1 || events[2].Value.Int64() != 5 {
+		t.Fatalf("wrong history for bill, got %+v", events)
+	}
+
+	// =========================================================================
+
+	setItem("jill", 6)
+
+	waitFor(t, "live event", func() bool {
+		item, _, _ := idx.Item("jill")
+		return item.Value != nil && item.Value.Int64() == 6
+	})
+
+	stop()
+
+	// =========================================================================
+
+	setItem("jack", 7)
+	setItem("kate", 8)
+
+	idx, stop = start(t, path, backend, cfg)
+	defer stop()
+
+	waitFor(t, "resume", idx.Live)
+
+	checkItem(t, idx, "jack", 7)
+	checkItem(t, idx, "kate", 8)
+
+	items, err := idx.Items()
+	if err != nil {
+		t.Fatalf("should be able to read items: %s", err)
+	}
+
+	if len(items) != 4 {
+		t.Fatalf("wrong number of items, got %d exp 4", len(items))
+	}
+
+	if n, err := idx.Count(); err != nil || n != 4 {
+		t.Fatalf("wrong count, got %d, %v exp 4", n, err)
+	}
+
+	events, err = idx.Events(0, receipt.BlockNumber.Uint64()+100)
+	if err != nil {
+		t.Fatalf("should be able to read events: %s", err)
+	}
+
+	if len(events) != 8 {
+		t.Fatalf("each event should be stored once, got %d exp 8", len(events))
+	}
+
+	// =========================================================================
+
+	stop()
+
+	cfg.ContractID = common.Address{1}
+
+	if _, err := indexer.Open(path, backend, cfg); !errors.Is(err, indexer.ErrContractMismatch) {
+		t.Fatalf("should not open a database built for another contract, got %v", err)
+	}
+}
+
+// =============================================================================
+
+func start(t *testing.T, path string, backend *ethereum.SimulatedBackend, cfg indexer.Config) (*indexer.Indexer, func()) {
+	t.Helper()
+
+	idx, err := indexer.Open(path, backend, cfg)
+	if err != nil {
+		t.Fatalf("should be able to open indexer: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		idx.Run(ctx)
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			cancel()
+			wg.Wait()
+			if err := idx.Close(); err != nil {
+				t.Errorf("should be able to close indexer: %s", err)
+			}
+		})
+	}
+
+	return idx, stop
+}
+
+func checkItem(t *testing.T, idx *indexer.Indexer, key string, exp int64) {
+	t.Helper()
+
+	item, found, err := idx.Item(key)
+	if err != nil {
+		t.Fatalf("should be able to read %s: %s", key, err)
+	}
+
+	if !found || item.Value.Int64() != exp {
+		t.Fatalf("wrong value for %s, got %v exp %d", key, item.Value, exp)
+	}
+}
+
+func waitFor(t *testing.T, what string, f func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !f() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
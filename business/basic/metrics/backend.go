@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	geth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Backend decorates an ethereum.Backend, recording the latency of every
+// call made to the node.
+type Backend struct {
+	ethereum.Backend
+	latency *prometheus.HistogramVec
+}
+
+// Backend returns the specified backend decorated to record call latencies.
+func (m *Metrics) Backend(backend ethereum.Backend) *Backend {
+	return &Backend{
+		Backend: backend,
+		latency: m.rpcLatency,
+	}
+}
+
+// =============================================================================
+
+// CodeAt returns the code of the given account.
+func (b *Backend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return observe(b, "eth_getCode", func() ([]byte, error) {
+		return b.Backend.CodeAt(ctx, contract, blockNumber)
+	})
+}
+
+// CallContract executes a contract call against the state at the specified
+// block number.
+func (b *Backend) CallContract(ctx context.Context, call geth.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return observe(b, "eth_call", func() ([]byte, error) {
+		return b.Backend.CallContract(ctx, call, blockNumber)
+	})
+}
+
+// PendingCallContract executes a contract call against the pending state.
+func (b *Backend) PendingCallContract(ctx context.Context, call geth.CallMsg) ([]byte, error) {
+	pb, ok := b.Backend.(bind.PendingContractCaller)
+	if !ok {
+		return nil, bind.ErrNoPendingState
+	}
+
+	return observe(b, "eth_call", func() ([]byte, error) {
+		return pb.PendingCallContract(ctx, call)
+	})
+}
+
+// HeaderByNumber returns a block header from the current canonical chain.
+func (b *Backend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return observe(b, "eth_getBlockByNumber", func() (*types.Header, error) {
+		return b.Backend.HeaderByNumber(ctx, number)
+	})
+}
+
+// PendingCodeAt returns the code of the given account in the pending state.
+func (b *Backend) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return observe(b, "eth_getCode", func() ([]byte, error) {
+		return b.Backend.PendingCodeAt(ctx, account)
+	})
+}
+
+// PendingNonceAt retrieves the current pending nonce associated with an account.
+func (b *Backend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return observe(b, "eth_getTransactionCount", func() (uint64, error) {
+		return b.Backend.PendingNonceAt(ctx, account)
+	})
+}
+
+// SuggestGasPrice retrieves the currently suggested gas price.
+func (b *Backend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return observe(b, "eth_gasPrice", func() (*big.Int, error) {
+		return b.Backend.SuggestGasPrice(ctx)
+	})
+}
+
+// SuggestGasTipCap retrieves the currently suggested gas tip cap.
+func (b *Backend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return observe(b, "eth_maxPriorityFeePerGas", func() (*big.Int, error) {
+		return b.Backend.SuggestGasTipCap(ctx)
+	})
+}
+
+// EstimateGas estimates the gas needed to execute the specified call.
+func (b *Backend) EstimateGas(ctx context.Context, call geth.CallMsg) (uint64, error) {
+	return observe(b, "eth_estimateGas", func() (uint64, error) {
+		return b.Backend.EstimateGas(ctx, call)
+	})
+}
+
+// SendTransaction injects the signed transaction into the pending pool.
+func (b *Backend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	_, err := observe(b, "eth_sendRawTransaction", func() (struct{}, error) {
+		return struct{}{}, b.Backend.SendTransaction(ctx, tx)
+	})
+
+	return err
+}
+
+// FilterLogs executes a filter query.
+func (b *Backend) FilterLogs(ctx context.Context, query geth.FilterQuery) ([]types.Log, error) {
+	return observe(b, "eth_getLogs", func() ([]types.Log, error) {
+		return b.Backend.FilterLogs(ctx, query)
+	})
+}
+
+// SubscribeFilterLogs subscribes to the results of a streaming filter query.
+// Only establishing the subscription is timed.
+func (b *Backend) SubscribeFilterLogs(ctx context.Context, query geth.FilterQuery, ch chan<- types.Log) (geth.Subscription, error) {
+	return observe(b, "eth_subscribe", func() (geth.Subscription, error) {
+		return b.Backend.SubscribeFilterLogs(ctx, query, ch)
+	})
+}
+
+// TransactionReceipt returns the receipt of a mined transaction.
+func (b *Backend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return observe(b, "eth_getTransactionReceipt", func() (*types.Receipt, error) {
+		return b.Backend.TransactionReceipt(ctx, txHash)
+	})
+}
+
+// TransactionByHash returns the transaction with the given hash.
+func (b *Backend) TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+	type result struct {
+		tx        *types.Transaction
+		isPending bool
+	}
+
+	r, err := observe(b, "eth_getTransactionByHash", func() (result, error) {
+		tx, isPending, err := b.Backend.TransactionByHash(ctx, txHash)
+		return result{tx: tx, isPending: isPending}, err
+	})
+
+	return r.tx, r.isPending, err
+}
+
+// BalanceAt returns the wei balance of the given account.
+func (b *Backend) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return observe(b, "eth_getBalance", func() (*big.Int, error) {
+		return b.Backend.BalanceAt(ctx, account, blockNumber)
+	})
+}
+
+// =============================================================================
+
+// observe times the call and records it under the JSON-RPC method name.
+func observe[T any](b *Backend, method string, fn func() (T, error)) (T, error) {
+	start := time.Now()
+
+	v, err := fn()
+
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	b.latency.WithLabelValues(method, result).Observe(time.Since(start).Seconds())
+
+	return v, err
+}
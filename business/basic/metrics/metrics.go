@@ -0,0 +1,250 @@
+// Package metrics provides Prometheus instrumentation for basic contract
+// writes, the node calls behind them, and the state held by the indexer.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/business/basic/preflight"
+	"github.com/ardanlabs/smartcontract/foundation/backend/retry"
+	geth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric name.
+const namespace = "basic"
+
+// unknownReason labels reverts that carried no reason.
+const unknownReason = "unknown"
+
+// Metrics holds the collectors for the basic contract.
+type Metrics struct {
+	registry  *prometheus.Registry
+	converter *currency.Converter
+
+	txLatency  prometheus.Histogram
+	txGasUsed  prometheus.Histogram
+	txGasPrice prometheus.Histogram
+	txCostUSD  prometheus.Histogram
+	txs        *prometheus.CounterVec
+	reverts    *prometheus.CounterVec
+	rpcLatency *prometheus.HistogramVec
+}
+
+// New constructs the metrics with their own registry, which also collects
+// the Go runtime and process metrics. The converter is used to price
+// transactions in USD.
+func New(converter *currency.Converter) *Metrics {
+	m := Metrics{
+		registry:  prometheus.NewRegistry(),
+		converter: converter,
+
+		txLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "tx_latency_seconds",
+			Help:      "Time from submitting a transaction to it being mined.",
+			Buckets:   []float64{0.5, 1, 2, 5, 10, 15, 30, 60, 120, 300},
+		}),
+		txGasUsed: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "tx_gas_used",
+			Help:      "Gas used by mined transactions.",
+			Buckets:   prometheus.ExponentialBuckets(21000, 1.5, 10),
+		}),
+		txGasPrice: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "tx_effective_gas_price_gwei",
+			Help:      "Effective gas price paid by mined transactions in GWei.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+		}),
+		txCostUSD: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "tx_cost_usd",
+			Help:      "Cost of mined transactions in USD.",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 2, 5, 10, 25, 50},
+		}),
+		txs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "txs_total",
+			Help:      "Mined transactions by receipt status.",
+		}, []string{"status"}),
+		reverts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reverts_total",
+			Help:      "Reverted transactions and calls by revert reason.",
+		}, []string{"reason"}),
+		rpcLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rpc_duration_seconds",
+			Help:      "Latency of calls made to the node by method and result.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.txLatency,
+		m.txGasUsed,
+		m.txGasPrice,
+		m.txCostUSD,
+		m.txs,
+		m.reverts,
+		m.rpcLatency,
+	)
+
+	return &m
+}
+
+// Registry returns the registry holding the collectors.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler returns the HTTP handler to serve on /metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// IndexedItems registers a gauge reporting the number of items returned by
+// count each time the metrics are scraped. Count errors report -1.
+func (m *Metrics) IndexedItems(count func() (int, error)) {
+	gauge := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "indexed_items",
+		Help:      "Number of keys held by the indexer.",
+	}, func() float64 {
+		n, err := count()
+		if err != nil {
+			return -1
+		}
+		return float64(n)
+	})
+
+	m.registry.MustRegister(gauge)
+}
+
+// RPCStats registers counters reporting the retry and rate limit counters
+// returned by stats each time the metrics are scraped.
+func (m *Metrics) RPCStats(stats func() retry.Stats) {
+	counter := func(name string, help string, value func(retry.Stats) uint64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      name,
+			Help:      help,
+		}, func() float64 {
+			return float64(value(stats()))
+		})
+	}
+
+	m.registry.MustRegister(
+		counter("rpc_retries_total", "Calls to the node retried after a transient error.", func(s retry.Stats) uint64 { return s.Retries }),
+		counter("rpc_throttled_total", "Calls to the node that waited on the client side rate limit.", func(s retry.Stats) uint64 { return s.Throttled }),
+		counter("rpc_failures_total", "Calls to the node that returned an error after every attempt.", func(s retry.Stats) uint64 { return s.Failures }),
+	)
+}
+
+// ObserveRevert counts a revert by its reason.
+func (m *Metrics) ObserveRevert(reason string) {
+	if reason == "" {
+		reason = unknownReason
+	}
+
+	m.reverts.WithLabelValues(reason).Inc()
+}
+
+// ObserveMined records the latency, gas and cost of a mined transaction.
+func (m *Metrics) ObserveMined(submitted time.Time, tx *types.Transaction, receipt *types.Receipt) {
+	status := "success"
+	if receipt.Status == types.ReceiptStatusFailed {
+		status = "failed"
+	}
+	m.txs.WithLabelValues(status).Inc()
+
+	m.txLatency.Observe(time.Since(submitted).Seconds())
+	m.txGasUsed.Observe(float64(receipt.GasUsed))
+
+	gasPrice := receipt.EffectiveGasPrice
+	if gasPrice == nil {
+		gasPrice = tx.GasPrice()
+	}
+
+	gwei, _ := currency.Wei2GWei(gasPrice).Float64()
+	m.txGasPrice.Observe(gwei)
+
+	cost := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(receipt.GasUsed))
+	oneETHToUSD, _ := m.converter.Values()
+
+	usd := new(big.Float).Mul(currency.Wei2GWei(cost), oneETHToUSD)
+	usd.Quo(usd, big.NewFloat(1e9))
+
+	costUSD, _ := usd.Float64()
+	m.txCostUSD.Observe(costUSD)
+}
+
+// Track waits for the transaction submitted at the specified time to be
+// mined and records it. A failed transaction is replayed against the state
+// before its block to count its revert reason.
+func (m *Metrics) Track(ctx context.Context, backend ethereum.Backend, tx *types.Transaction, submitted time.Time) (*types.Receipt, error) {
+	receipt, err := bind.WaitMined(ctx, backend, tx)
+	if err != nil {
+		return nil, fmt.Errorf("wait mined: %w", err)
+	}
+
+	m.ObserveMined(submitted, tx, receipt)
+
+	if receipt.Status == types.ReceiptStatusFailed {
+		m.ObserveRevert(replayReason(ctx, backend, tx, receipt))
+	}
+
+	return receipt, nil
+}
+
+// =============================================================================
+
+// replayReason calls the failed transaction at the state before its block to
+// recover the revert reason.
+func replayReason(ctx context.Context, backend ethereum.Backend, tx *types.Transaction, receipt *types.Receipt) string {
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return ""
+	}
+
+	msg := geth.CallMsg{
+		From:     from,
+		To:       tx.To(),
+		Gas:      tx.Gas(),
+		GasPrice: tx.GasPrice(),
+		Value:    tx.Value(),
+		Data:     tx.Data(),
+	}
+
+	parent := new(big.Int).Sub(receipt.BlockNumber, big.NewInt(1))
+
+	_, err = backend.CallContract(ctx, msg, parent)
+	if err == nil {
+		return ""
+	}
+
+	if reason := preflight.RevertReason(err); reason != "" {
+		return reason
+	}
+
+	var rpcErr interface{ ErrorCode() int }
+	if errors.As(err, &rpcErr) {
+		return err.Error()
+	}
+
+	return ""
+}
@@ -0,0 +1,133 @@
+package metrics_test
+
+import (
+	"context"
+	"io"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/metrics"
+	"github.com/ardanlabs/smartcontract/foundation/backend/retry"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	m := metrics.New(currency.NewDefaultConverter(basic.BasicMetaData.ABI))
+
+	clt, err := ethereum.NewClient(m.Backend(backend), backend.PrivateKeys[0])
+	if err != nil {
+		t.Fatalf("unable to create ethereum api: %s", err)
+	}
+
+	// =========================================================================
+
+	const gasLimit = 1600000
+	valueGwei := big.NewFloat(0.0)
+	gasPrice := currency.GWei2Wei(big.NewFloat(39.576))
+
+	transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+	if err != nil {
+		t.Fatalf("unable to create opts for deploy: %s", err)
+	}
+
+	_, tx, testBasic, err := basic.DeployBasic(transOpts, clt.Backend)
+	if err != nil {
+		t.Fatalf("unable to deploy basic: %s", err)
+	}
+
+	if _, err := m.Track(ctx, clt.Backend, tx, time.Now()); err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+
+	// =========================================================================
+
+	transOpts, err = clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+	if err != nil {
+		t.Fatalf("unable to create transaction opts for setItems: %s", err)
+	}
+
+	tx, err = testBasic.SetItem(transOpts, "bill", big.NewInt(1))
+	if err != nil {
+		t.Fatalf("should be able to set item: %s", err)
+	}
+
+	if _, err := m.Track(ctx, clt.Backend, tx, time.Now()); err != nil {
+		t.Fatalf("waiting for set item: %s", err)
+	}
+
+	// Too little gas fails the transaction once it has been mined.
+	transOpts, err = clt.NewTransactOpts(ctx, 30000, gasPrice, valueGwei)
+	if err != nil {
+		t.Fatalf("unable to create transaction opts for setItems: %s", err)
+	}
+
+	tx, err = testBasic.SetItem(transOpts, "jill", big.NewInt(2))
+	if err != nil {
+		t.Fatalf("should be able to send set item: %s", err)
+	}
+
+	receipt, err := m.Track(ctx, clt.Backend, tx, time.Now())
+	if err != nil {
+		t.Fatalf("waiting for set item: %s", err)
+	}
+
+	if receipt.Status != types.ReceiptStatusFailed {
+		t.Fatal("set item should have failed")
+	}
+
+	m.IndexedItems(func() (int, error) { return 7, nil })
+	m.RPCStats(func() retry.Stats { return retry.Stats{Retries: 4, Throttled: 2} })
+
+	// =========================================================================
+
+	srv := httptest.NewServer(m.Handler())
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatalf("should be able to scrape: %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("should be able to read metrics: %s", err)
+	}
+	text := string(body)
+
+	exp := []string{
+		`basic_txs_total{status="success"} 2`,
+		`basic_txs_total{status="failed"} 1`,
+		`basic_tx_latency_seconds_count 3`,
+		`basic_tx_gas_used_count 3`,
+		`basic_tx_effective_gas_price_gwei_count 3`,
+		`basic_tx_cost_usd_count 3`,
+		`basic_reverts_total{reason=`,
+		`basic_rpc_duration_seconds_count{method="eth_sendRawTransaction",result="ok"} 3`,
+		`basic_rpc_duration_seconds_count{method="eth_getTransactionReceipt",result="ok"}`,
+		`basic_indexed_items 7`,
+		`basic_rpc_retries_total 4`,
+		`basic_rpc_throttled_total 2`,
+		`go_goroutines`,
+	}
+
+	for _, s := range exp {
+		if !strings.Contains(text, s) {
+			t.Errorf("metrics should contain %s", s)
+		}
+	}
+}
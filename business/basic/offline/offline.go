@@ -0,0 +1,319 @@
+// Package offline provides support for signing basic contract transactions
+// on a machine without network access and broadcasting them elsewhere.
+package offline
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/foundation/abiarg"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// MethodDeploy is the method name used in a request to deploy the contract.
+const MethodDeploy = "deploy"
+
+// Request represents an unsigned transaction for the basic contract. Since
+// the signing machine can't talk to a node, the request carries everything
+// needed to build the transaction, including the chain id and nonce.
+type Request struct {
+	ChainID      int64    `json:"chain_id"`
+	Nonce        uint64   `json:"nonce"`
+	GasLimit     uint64   `json:"gas_limit"`
+	GasPriceGWei float64  `json:"gas_price_gwei"`
+	ValueGWei    float64  `json:"value_gwei"`
+	To           string   `json:"to,omitempty"`
+	Method       string   `json:"method"`
+	Args         []string `json:"args,omitempty"`
+}
+
+// SignedTx represents a signed transaction ready to be broadcast along with
+// a decoded summary for review before it is sent.
+type SignedTx struct {
+	Hash    string  `json:"hash"`
+	Raw     string  `json:"raw"`
+	Summary Summary `json:"summary"`
+}
+
+// Summary represents the human readable details of a signed transaction.
+type Summary struct {
+	ChainID      string            `json:"chain_id"`
+	From         string            `json:"from"`
+	To           string            `json:"to"`
+	Nonce        uint64            `json:"nonce"`
+	GasLimit     uint64            `json:"gas_limit"`
+	GasPriceGWei string            `json:"gas_price_gwei"`
+	ValueGWei    string            `json:"value_gwei"`
+	Method       string            `json:"method"`
+	Args         map[string]string `json:"args,omitempty"`
+}
+
+// =============================================================================
+
+// Transaction builds the unsigned transaction described by the request.
+func (r Request) Transaction() (*types.Transaction, error) {
+	basicABI, err := basic.BasicMetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("basic abi: %w", err)
+	}
+
+	if r.ChainID <= 0 {
+		return nil, errors.New("chain id is required")
+	}
+
+	if r.GasLimit == 0 {
+		return nil, errors.New("gas limit is required")
+	}
+
+	var to *common.Address
+	var data []byte
+
+	switch r.Method {
+	case MethodDeploy:
+		if r.To != "" {
+			return nil, errors.New("deploy requests can't specify a to address")
+		}
+
+		data = common.FromHex(basic.BasicMetaData.Bin)
+
+	default:
+		if !common.IsHexAddress(r.To) {
+			return nil, fmt.Errorf("invalid contract address %q", r.To)
+		}
+		address := common.HexToAddress(r.To)
+		to = &address
+
+		method, exists := basicABI.Methods[r.Method]
+		if !exists {
+			return nil, fmt.Errorf("unknown method %q", r.Method)
+		}
+
+		if method.IsConstant() {
+			return nil, fmt.Errorf("method %q does not change state", r.Method)
+		}
+
+		args, err := abiarg.ParseArgs(method.Inputs, r.Args)
+		if err != nil {
+			return nil, fmt.Errorf("method %q: %w", r.Method, err)
+		}
+
+		data, err = basicABI.Pack(r.Method, args...)
+		if err != nil {
+			return nil, fmt.Errorf("pack %q: %w", r.Method, err)
+		}
+	}
+
+	tx := types.NewTx(&types.LegacyTx{
+		Nonce:    r.Nonce,
+		GasPrice: currency.GWei2Wei(big.NewFloat(r.GasPriceGWei)),
+		Gas:      r.GasLimit,
+		To:       to,
+		Value:    currency.GWei2Wei(big.NewFloat(r.ValueGWei)),
+		Data:     data,
+	})
+
+	return tx, nil
+}
+
+// Sign builds the transaction described by the request and signs it with
+// the private key.
+func Sign(r Request, privateKey *ecdsa.PrivateKey) (SignedTx, error) {
+	tx, err := r.Transaction()
+	if err != nil {
+		return SignedTx{}, err
+	}
+
+	signer := types.LatestSignerForChainID(big.NewInt(r.ChainID))
+
+	signedTx, err := types.SignTx(tx, signer, privateKey)
+	if err != nil {
+		return SignedTx{}, fmt.Errorf("signing transaction: %w", err)
+	}
+
+	return NewSignedTx(signedTx)
+}
+
+// NewSignedTx constructs the file representation of a signed transaction.
+func NewSignedTx(tx *types.Transaction) (SignedTx, error) {
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return SignedTx{}, fmt.Errorf("encoding transaction: %w", err)
+	}
+
+	summary, err := Summarize(tx)
+	if err != nil {
+		return SignedTx{}, err
+	}
+
+	stx := SignedTx{
+		Hash:    tx.Hash().Hex(),
+		Raw:     hexutil.Encode(raw),
+		Summary: summary,
+	}
+
+	return stx, nil
+}
+
+// Transaction decodes the raw transaction and checks it matches the hash
+// recorded when it was signed.
+func (s SignedTx) Transaction() (*types.Transaction, error) {
+	raw, err := hexutil.Decode(s.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("decoding raw transaction: %w", err)
+	}
+
+	var tx types.Transaction
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("decoding raw transaction: %w", err)
+	}
+
+	if tx.Hash().Hex() != s.Hash {
+		return nil, fmt.Errorf("transaction hash %s does not match recorded hash %s", tx.Hash().Hex(), s.Hash)
+	}
+
+	return &tx, nil
+}
+
+// Summarize decodes the signed transaction into its human readable details.
+func Summarize(tx *types.Transaction) (Summary, error) {
+	signer := types.LatestSignerForChainID(tx.ChainId())
+
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return Summary{}, fmt.Errorf("recovering sender: %w", err)
+	}
+
+	summary := Summary{
+		ChainID:      tx.ChainId().String(),
+		From:         from.Hex(),
+		Nonce:        tx.Nonce(),
+		GasLimit:     tx.Gas(),
+		GasPriceGWei: currency.Wei2GWei(tx.GasPrice()).String(),
+		ValueGWei:    currency.Wei2GWei(tx.Value()).String(),
+	}
+
+	if tx.To() == nil {
+		summary.To = "contract creation"
+		summary.Method = MethodDeploy
+		return summary, nil
+	}
+	summary.To = tx.To().Hex()
+
+	basicABI, err := basic.BasicMetaData.GetAbi()
+	if err != nil {
+		return Summary{}, fmt.Errorf("basic abi: %w", err)
+	}
+
+	data := tx.Data()
+	if len(data) < 4 {
+		return Summary{}, errors.New("transaction data is missing the method id")
+	}
+
+	method, err := basicABI.MethodById(data[:4])
+	if err != nil {
+		return Summary{}, fmt.Errorf("unknown method: %w", err)
+	}
+
+	values := make(map[string]any)
+	if err := method.Inputs.UnpackIntoMap(values, data[4:]); err != nil {
+		return Summary{}, fmt.Errorf("unpack %q: %w", method.Name, err)
+	}
+
+	summary.Method = method.Name
+	summary.Args = make(map[string]string, len(values))
+	for name, value := range values {
+		summary.Args[name] = fmt.Sprint(value)
+	}
+
+	return summary, nil
+}
+
+// String produces an easy to read format of the summary.
+func (s Summary) String() string {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "\nSigned Transaction\n")
+	fmt.Fprintf(&b, "----------------------------------------------------\n")
+	fmt.Fprintf(&b, "chain id        : %v\n", s.ChainID)
+	fmt.Fprintf(&b, "from            : %v\n", s.From)
+	fmt.Fprintf(&b, "to              : %v\n", s.To)
+	fmt.Fprintf(&b, "nonce           : %v\n", s.Nonce)
+	fmt.Fprintf(&b, "gas limit       : %v\n", s.GasLimit)
+	fmt.Fprintf(&b, "gas offer price : %v GWei\n", s.GasPriceGWei)
+	fmt.Fprintf(&b, "value           : %v GWei\n", s.ValueGWei)
+	fmt.Fprintf(&b, "method          : %v\n", s.Method)
+
+	names := make([]string, 0, len(s.Args))
+	for name := range s.Args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %-14s: %v\n", name, s.Args[name])
+	}
+
+	return b.String()
+}
+
+// =============================================================================
+
+// ReadRequest reads an unsigned request from the specified file.
+func ReadRequest(path string) (Request, error) {
+	var r Request
+	if err := readJSON(path, &r); err != nil {
+		return Request{}, fmt.Errorf("reading request: %w", err)
+	}
+
+	return r, nil
+}
+
+// ReadSignedTx reads a signed transaction from the specified file.
+func ReadSignedTx(path string) (SignedTx, error) {
+	var s SignedTx
+	if err := readJSON(path, &s); err != nil {
+		return SignedTx{}, fmt.Errorf("reading signed transaction: %w", err)
+	}
+
+	return s, nil
+}
+
+// WriteSignedTx writes the signed transaction to the specified file.
+func WriteSignedTx(path string, s SignedTx) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding signed transaction: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("writing signed transaction: %w", err)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// readJSON decodes the JSON document in the file into the value.
+func readJSON(path string, v any) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	d := json.NewDecoder(f)
+	d.DisallowUnknownFields()
+
+	return d.Decode(v)
+}
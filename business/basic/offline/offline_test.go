@@ -0,0 +1,125 @@
+package offline_test
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/offline"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestSignAndBroadcast(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	privateKey := backend.PrivateKeys[0]
+	from := crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	nonce, err := backend.PendingNonceAt(ctx, from)
+	if err != nil {
+		t.Fatalf("unable to read nonce: %s", err)
+	}
+
+	// broadcast signs the request, round trips it through a file like the
+	// sign and broadcast commands do, and sends it.
+	broadcast := func(req offline.Request) {
+		stx, err := offline.Sign(req, privateKey)
+		if err != nil {
+			t.Fatalf("should be able to sign request: %s", err)
+		}
+
+		if stx.Summary.From != from.Hex() || stx.Summary.Method != req.Method {
+			t.Fatalf("wrong summary, got %+v", stx.Summary)
+		}
+
+		path := filepath.Join(t.TempDir(), "tx.json")
+		if err := offline.WriteSignedTx(path, stx); err != nil {
+			t.Fatalf("should be able to write signed tx: %s", err)
+		}
+
+		stx, err = offline.ReadSignedTx(path)
+		if err != nil {
+			t.Fatalf("should be able to read signed tx: %s", err)
+		}
+
+		tx, err := stx.Transaction()
+		if err != nil {
+			t.Fatalf("should be able to decode signed tx: %s", err)
+		}
+
+		if err := backend.SendTransaction(ctx, tx); err != nil {
+			t.Fatalf("should be able to send signed tx: %s", err)
+		}
+
+		receipt, err := bind.WaitMined(ctx, backend, tx)
+		if err != nil {
+			t.Fatalf("waiting for tx: %s", err)
+		}
+
+		if receipt.Status != 1 {
+			t.Fatalf("transaction failed for %s", req.Method)
+		}
+	}
+
+	// =========================================================================
+
+	broadcast(offline.Request{
+		ChainID:      backend.ChainID().Int64(),
+		Nonce:        nonce,
+		GasLimit:     1600000,
+		GasPriceGWei: 39.576,
+		Method:       offline.MethodDeploy,
+	})
+
+	contractID := crypto.CreateAddress(from, nonce)
+
+	broadcast(offline.Request{
+		ChainID:      backend.ChainID().Int64(),
+		Nonce:        nonce + 1,
+		GasLimit:     1600000,
+		GasPriceGWei: 39.576,
+		To:           contractID.Hex(),
+		Method:       "SetItem",
+		Args:         []string{"bill", "1_000_000"},
+	})
+
+	// =========================================================================
+
+	testBasic, err := basic.NewBasic(contractID, backend)
+	if err != nil {
+		t.Fatalf("error creating basic: %s", err)
+	}
+
+	item, err := testBasic.Items(nil, "bill")
+	if err != nil {
+		t.Fatalf("should be able to retrieve item: %s", err)
+	}
+
+	if item.Cmp(big.NewInt(1_000_000)) != 0 {
+		t.Fatalf("wrong value, got %s exp %d", item, 1_000_000)
+	}
+
+	// =========================================================================
+
+	bad := offline.Request{
+		ChainID:  backend.ChainID().Int64(),
+		GasLimit: 1600000,
+		To:       contractID.Hex(),
+		Method:   "Items",
+		Args:     []string{"bill"},
+	}
+
+	if _, err := offline.Sign(bad, privateKey); err == nil {
+		t.Fatal("should not sign a request for a read only method")
+	}
+}
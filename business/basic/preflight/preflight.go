@@ -0,0 +1,243 @@
+// Package preflight provides support for simulating basic contract
+// transactions with eth_call before they are broadcast.
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	geth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Report represents the predicted outcome of a transaction.
+type Report struct {
+	Tx          *types.Transaction
+	From        common.Address
+	GasEstimate uint64
+	FeeWei      *big.Int
+	Return      []byte
+	Revert      string
+	Err         error
+
+	// PredictedEvents are built from the arguments in the calldata, not from
+	// executing the call, since eth_call doesn't return the logs emitted.
+	PredictedEvents []currency.LogData
+}
+
+// Simulate executes the signed, but not broadcast, transaction with eth_call
+// using the same sender, gas, price, value and data. The call runs against
+// the pending state when the backend implements bind.PendingContractCaller,
+// so transactions already queued are accounted for, and against the latest
+// block otherwise. Build the transaction with TransactOpts.NoSend set to true to get
+// the exact transaction that would have been sent. A transaction that would
+// fail is reported through the Err and Revert fields of the report. The
+// returned error is only for problems talking to the node.
+func Simulate(ctx context.Context, backend ethereum.Backend, from common.Address, tx *types.Transaction) (Report, error) {
+	msg := geth.CallMsg{
+		From:  from,
+		To:    tx.To(),
+		Gas:   tx.Gas(),
+		Value: tx.Value(),
+		Data:  tx.Data(),
+	}
+
+	switch tx.Type() {
+	case types.LegacyTxType, types.AccessListTxType:
+		msg.GasPrice = tx.GasPrice()
+	default:
+		msg.GasFeeCap = tx.GasFeeCap()
+		msg.GasTipCap = tx.GasTipCap()
+	}
+
+	report := Report{
+		Tx:   tx,
+		From: from,
+	}
+
+	ret, err := call(ctx, backend, msg)
+	if err != nil {
+		if !isExecutionError(err) {
+			return Report{}, fmt.Errorf("call contract: %w", err)
+		}
+
+		report.Err = err
+		report.Revert = RevertReason(err)
+		return report, nil
+	}
+	report.Return = ret
+
+	gas, err := backend.EstimateGas(ctx, msg)
+	if err != nil {
+		if !isExecutionError(err) {
+			return Report{}, fmt.Errorf("estimate gas: %w", err)
+		}
+
+		report.Err = err
+		report.Revert = RevertReason(err)
+		return report, nil
+	}
+
+	report.GasEstimate = gas
+	report.FeeWei = new(big.Int).Mul(new(big.Int).SetUint64(gas), tx.GasFeeCap())
+	report.PredictedEvents = predictEvents(tx)
+
+	return report, nil
+}
+
+// call executes the message against the pending state when the backend
+// supports it and the latest block when it doesn't.
+func call(ctx context.Context, backend ethereum.Backend, msg geth.CallMsg) ([]byte, error) {
+	if pc, ok := backend.(bind.PendingContractCaller); ok {
+		return pc.PendingCallContract(ctx, msg)
+	}
+
+	return backend.CallContract(ctx, msg, nil)
+}
+
+// Format produces an easy to read format of the report with the fee
+// converted to USD.
+func (r Report) Format(converter *currency.Converter) string {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "\nSimulation Details\n")
+	fmt.Fprintf(&b, "----------------------------------------------------\n")
+	fmt.Fprintf(&b, "from            : %v\n", r.From)
+	fmt.Fprintf(&b, "nonce           : %v\n", r.Tx.Nonce())
+	fmt.Fprintf(&b, "gas limit       : %v\n", r.Tx.Gas())
+
+	if r.Err != nil {
+		fmt.Fprintf(&b, "status          : would fail\n")
+		fmt.Fprintf(&b, "error           : %v\n", r.Err)
+		if r.Revert != "" {
+			fmt.Fprintf(&b, "revert reason   : %v\n", r.Revert)
+		}
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "status          : would succeed\n")
+	fmt.Fprintf(&b, "predicted gas   : %v\n", r.GasEstimate)
+	fmt.Fprintf(&b, "predicted fee   : %v GWei\n", currency.Wei2GWei(r.FeeWei))
+	fmt.Fprintf(&b, "predicted fee   : %v USD\n", converter.Wei2USD(r.FeeWei))
+
+	fmt.Fprintf(&b, "\nPredicted Logs (from the call arguments)\n")
+	fmt.Fprintf(&b, "----------------------------------------------------\n")
+	for _, log := range r.PredictedEvents {
+		fmt.Fprintln(&b, log.EventName)
+		fmt.Fprintln(&b, log.Data)
+	}
+
+	return b.String()
+}
+
+// =============================================================================
+
+// predictEvents returns the events the basic contract emits for the call in
+// the transaction. An eth_call does not report logs, but SetItem always
+// emits an ItemSet event carrying its arguments.
+func predictEvents(tx *types.Transaction) []currency.LogData {
+	basicABI, err := basic.BasicMetaData.GetAbi()
+	if err != nil {
+		return nil
+	}
+
+	data := tx.Data()
+	if tx.To() == nil || len(data) < 4 {
+		return nil
+	}
+
+	method, err := basicABI.MethodById(data[:4])
+	if err != nil || method.Name != "SetItem" {
+		return nil
+	}
+
+	args := make(map[string]any)
+	if err := method.Inputs.UnpackIntoMap(args, data[4:]); err != nil {
+		return nil
+	}
+
+	event := currency.LogData{
+		EventName: basicABI.Events["ItemSet"].Name,
+		Data:      args,
+	}
+
+	return []currency.LogData{event}
+}
+
+// executionReverted is the JSON-RPC error code nodes use for a call that
+// reverted.
+const executionReverted = 3
+
+// executionFailures are the messages the EVM reports for a call that failed
+// while executing, for nodes that don't use the execution reverted code.
+var executionFailures = []string{
+	"execution reverted",
+	"out of gas",
+	"gas required exceeds allowance",
+	"invalid opcode",
+	"invalid jump destination",
+	"stack underflow",
+	"stack limit reached",
+	"write protection",
+}
+
+// isExecutionError reports whether the error came from executing the
+// transaction, like a revert or running out of gas, rather than from
+// talking to the node.
+func isExecutionError(err error) bool {
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+
+	if rpcErr.ErrorCode() == executionReverted {
+		return true
+	}
+
+	msg := strings.ToLower(rpcErr.Error())
+	for _, s := range executionFailures {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RevertReason decodes the revert reason carried in the error data returned
+// by a call that reverted. An empty string means there was no reason.
+func RevertReason(err error) string {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return ""
+	}
+
+	hexData, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return ""
+	}
+
+	data, err := hexutil.Decode(hexData)
+	if err != nil {
+		return ""
+	}
+
+	reason, err := abi.UnpackRevert(data)
+	if err != nil {
+		return ""
+	}
+
+	return reason
+}
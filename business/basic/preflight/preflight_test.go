@@ -0,0 +1,110 @@
+package preflight_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/preflight"
+)
+
+func TestSimulate(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	clt, err := ethereum.NewClient(backend, backend.PrivateKeys[0])
+	if err != nil {
+		t.Fatalf("unable to create ethereum api: %s", err)
+	}
+
+	// =========================================================================
+
+	const gasLimit = 1600000
+	valueGwei := big.NewFloat(0.0)
+	gasPrice := currency.GWei2Wei(big.NewFloat(39.576))
+	transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+	if err != nil {
+		t.Fatalf("unable to create opts for deploy: %s", err)
+	}
+
+	_, tx, testBasic, err := basic.DeployBasic(transOpts, clt.Backend)
+	if err != nil {
+		t.Fatalf("unable to deploy basic: %s", err)
+	}
+
+	if _, err := clt.WaitMined(ctx, tx); err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+
+	// =========================================================================
+
+	transOpts, err = clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+	if err != nil {
+		t.Fatalf("unable to create transaction opts for setItems: %s", err)
+	}
+	transOpts.NoSend = true
+
+	key := "bill"
+	value := big.NewInt(1_000_000)
+
+	tx, err = testBasic.SetItem(transOpts, key, value)
+	if err != nil {
+		t.Fatalf("should be able to build set item: %s", err)
+	}
+
+	report, err := preflight.Simulate(ctx, clt.Backend, clt.Address(), tx)
+	if err != nil {
+		t.Fatalf("should be able to simulate set item: %s", err)
+	}
+
+	if report.Err != nil {
+		t.Fatalf("set item should succeed: %s", report.Err)
+	}
+
+	if report.GasEstimate == 0 || report.GasEstimate > gasLimit {
+		t.Fatalf("wrong gas estimate, got %d", report.GasEstimate)
+	}
+
+	if len(report.PredictedEvents) != 1 || report.PredictedEvents[0].EventName != "ItemSet" {
+		t.Fatalf("should predict a single ItemSet event, got %v", report.PredictedEvents)
+	}
+
+	if report.PredictedEvents[0].Data["key"] != key || report.PredictedEvents[0].Data["value"].(*big.Int).Cmp(value) != 0 {
+		t.Fatalf("wrong event data, got %v", report.PredictedEvents[0].Data)
+	}
+
+	nonce, err := clt.PendingNonceAt(ctx, clt.Address())
+	if err != nil {
+		t.Fatalf("unable to read nonce: %s", err)
+	}
+
+	if nonce != tx.Nonce() {
+		t.Fatalf("transaction should not be broadcast, got nonce %d exp %d", nonce, tx.Nonce())
+	}
+
+	// =========================================================================
+
+	transOpts.GasLimit = 22_000
+
+	tx, err = testBasic.SetItem(transOpts, key, value)
+	if err != nil {
+		t.Fatalf("should be able to build set item: %s", err)
+	}
+
+	report, err = preflight.Simulate(ctx, clt.Backend, clt.Address(), tx)
+	if err != nil {
+		t.Fatalf("should be able to simulate set item: %s", err)
+	}
+
+	if report.Err == nil {
+		t.Fatal("set item should fail without enough gas")
+	}
+}
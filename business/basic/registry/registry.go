@@ -0,0 +1,226 @@
+// Package registry records where the basic contracts are deployed on each
+// network. Every deployment is kept, so the history of a contract on a chain
+// survives redeploys, and the latest entry is the one programs talk to.
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// DefaultFile is where the programs keep the registry.
+const DefaultFile = "zarf/ethereum/deployments.json"
+
+// Set of contract names recorded by the deploy command.
+const (
+	ContractBasic     = "basic"
+	ContractMulticall = "multicall"
+)
+
+// ErrNotFound is returned when a contract has no deployment on a chain.
+var ErrNotFound = errors.New("deployment not found")
+
+// Deployment represents a single deployment of a contract.
+type Deployment struct {
+	Contract     string         `json:"contract"`
+	Network      string         `json:"network"`
+	ChainID      uint64         `json:"chain_id"`
+	Address      common.Address `json:"address"`
+	TxHash       common.Hash    `json:"tx_hash"`
+	Block        uint64         `json:"block"`
+	Deployer     common.Address `json:"deployer"`
+	BytecodeHash common.Hash    `json:"bytecode_hash"`
+	Version      string         `json:"version,omitempty"`
+	Timestamp    time.Time      `json:"timestamp"`
+}
+
+// Describe builds the deployment for a mined contract creation. The bytecode
+// hash is taken from the code stored at the address once the creation was
+// mined. The network is recorded as given and the version is left for the
+// caller to fill in.
+func Describe(ctx context.Context, backend bind.ContractBackend, contract string, network string, tx *types.Transaction, receipt *types.Receipt) (Deployment, error) {
+	if receipt.ContractAddress == (common.Address{}) {
+		return Deployment{}, fmt.Errorf("transaction %s did not create a contract", tx.Hash().Hex())
+	}
+
+	deployer, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return Deployment{}, fmt.Errorf("deployer: %w", err)
+	}
+
+	code, err := backend.CodeAt(ctx, receipt.ContractAddress, receipt.BlockNumber)
+	if err != nil {
+		return Deployment{}, fmt.Errorf("code at: %w", err)
+	}
+
+	d := Deployment{
+		Contract:     contract,
+		Network:      network,
+		ChainID:      tx.ChainId().Uint64(),
+		Address:      receipt.ContractAddress,
+		TxHash:       tx.Hash(),
+		Block:        receipt.BlockNumber.Uint64(),
+		Deployer:     deployer,
+		BytecodeHash: crypto.Keccak256Hash(code),
+		Timestamp:    time.Now().UTC(),
+	}
+
+	return d, nil
+}
+
+// =============================================================================
+
+// document represents the registry file. Deployments are grouped by chain
+// ID and contract name, oldest first.
+type document struct {
+	Chains map[string]map[string][]Deployment `json:"chains"`
+}
+
+// Registry provides access to the deployments recorded in a file.
+type Registry struct {
+	path string
+
+	mu  sync.Mutex
+	doc document
+}
+
+// Open reads the registry at the path. A registry that doesn't exist yet is
+// returned empty and is created by the first Record.
+func Open(path string) (*Registry, error) {
+	r := Registry{
+		path: path,
+		doc:  document{Chains: make(map[string]map[string][]Deployment)},
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return &r, nil
+	case err != nil:
+		return nil, fmt.Errorf("read registry: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &r.doc); err != nil {
+		return nil, fmt.Errorf("decode registry %s: %w", path, err)
+	}
+
+	if r.doc.Chains == nil {
+		r.doc.Chains = make(map[string]map[string][]Deployment)
+	}
+
+	return &r, nil
+}
+
+// Record adds the deployment to the registry and saves it. Earlier
+// deployments of the contract on the chain are kept as history.
+func (r *Registry) Record(d Deployment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	chain := chainKey(d.ChainID)
+
+	contracts, exists := r.doc.Chains[chain]
+	if !exists {
+		contracts = make(map[string][]Deployment)
+		r.doc.Chains[chain] = contracts
+	}
+	contracts[d.Contract] = append(contracts[d.Contract], d)
+
+	return r.save()
+}
+
+// Latest returns the most recent deployment of the contract on the chain.
+func (r *Registry) Latest(chainID *big.Int, contract string) (Deployment, error) {
+	history := r.History(chainID, contract)
+	if len(history) == 0 {
+		return Deployment{}, fmt.Errorf("%s on chain %d, run the deploy command: %w", contract, chainID, ErrNotFound)
+	}
+
+	return history[len(history)-1], nil
+}
+
+// History returns every deployment of the contract on the chain, oldest
+// first.
+func (r *Registry) History(chainID *big.Int, contract string) []Deployment {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	history := r.doc.Chains[chainID.String()][contract]
+
+	return append([]Deployment(nil), history...)
+}
+
+// Chains returns the chain IDs that have deployments, in ascending order.
+func (r *Registry) Chains() []*big.Int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	chains := make([]*big.Int, 0, len(r.doc.Chains))
+	for chain := range r.doc.Chains {
+		if id, ok := new(big.Int).SetString(chain, 10); ok {
+			chains = append(chains, id)
+		}
+	}
+
+	sort.Slice(chains, func(i, j int) bool { return chains[i].Cmp(chains[j]) < 0 })
+
+	return chains
+}
+
+// Contracts returns the names of the contracts deployed on the chain.
+func (r *Registry) Contracts(chainID *big.Int) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.doc.Chains[chainID.String()]))
+	for name := range r.doc.Chains[chainID.String()] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// =============================================================================
+
+// save writes the registry, replacing the file atomically so a failed write
+// never loses the history.
+func (r *Registry) save() error {
+	data, err := json.MarshalIndent(r.doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode registry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("create registry dir: %w", err)
+	}
+
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write registry: %w", err)
+	}
+
+	if err := os.Rename(tmp, r.path); err != nil {
+		return fmt.Errorf("replace registry: %w", err)
+	}
+
+	return nil
+}
+
+// chainKey formats a chain ID as the key used in the file.
+func chainKey(chainID uint64) string {
+	return new(big.Int).SetUint64(chainID).String()
+}
@@ -0,0 +1,118 @@
+package registry_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/registry"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	clt, err := ethereum.NewClient(backend, backend.PrivateKeys[0])
+	if err != nil {
+		t.Fatalf("unable to create ethereum api: %s", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "deployments.json")
+
+	reg, err := registry.Open(path)
+	if err != nil {
+		t.Fatalf("should open a missing registry: %s", err)
+	}
+
+	if _, err := reg.Latest(backend.ChainID(), registry.ContractBasic); !errors.Is(err, registry.ErrNotFound) {
+		t.Fatalf("should not find a deployment in an empty registry, got %v", err)
+	}
+
+	// =========================================================================
+
+	deploy := func() registry.Deployment {
+		transOpts, err := clt.NewTransactOpts(ctx, 1600000, currency.GWei2Wei(big.NewFloat(39.576)), big.NewFloat(0))
+		if err != nil {
+			t.Fatalf("unable to create opts for deploy: %s", err)
+		}
+
+		_, tx, _, err := basic.DeployBasic(transOpts, clt.Backend)
+		if err != nil {
+			t.Fatalf("unable to deploy basic: %s", err)
+		}
+
+		receipt, err := clt.WaitMined(ctx, tx)
+		if err != nil {
+			t.Fatalf("waiting for deploy: %s", err)
+		}
+
+		d, err := registry.Describe(ctx, clt.Backend, registry.ContractBasic, "simulated", tx, receipt)
+		if err != nil {
+			t.Fatalf("should describe the deployment: %s", err)
+		}
+
+		if err := reg.Record(d); err != nil {
+			t.Fatalf("should record the deployment: %s", err)
+		}
+
+		return d
+	}
+
+	first := deploy()
+
+	if first.Deployer != clt.Address() {
+		t.Fatalf("wrong deployer, got %s exp %s", first.Deployer, clt.Address())
+	}
+
+	code, err := clt.Backend.CodeAt(ctx, first.Address, nil)
+	if err != nil {
+		t.Fatalf("unable to read code: %s", err)
+	}
+
+	if first.BytecodeHash != crypto.Keccak256Hash(code) {
+		t.Fatalf("wrong bytecode hash, got %s", first.BytecodeHash)
+	}
+
+	second := deploy()
+
+	// =========================================================================
+
+	// A reopened registry keeps every deployment and serves the latest one.
+	reg, err = registry.Open(path)
+	if err != nil {
+		t.Fatalf("should reopen the registry: %s", err)
+	}
+
+	latest, err := reg.Latest(backend.ChainID(), registry.ContractBasic)
+	if err != nil {
+		t.Fatalf("should find the latest deployment: %s", err)
+	}
+
+	if latest.Address != second.Address || latest.Block != second.Block {
+		t.Fatalf("should serve the second deployment, got %s", latest.Address)
+	}
+
+	history := reg.History(backend.ChainID(), registry.ContractBasic)
+	if len(history) != 2 || history[0].Address != first.Address {
+		t.Fatalf("should keep the first deployment as history, got %v", history)
+	}
+
+	if chains := reg.Chains(); len(chains) != 1 || chains[0].Cmp(backend.ChainID()) != 0 {
+		t.Fatalf("wrong chains, got %v", chains)
+	}
+
+	if _, err := reg.Latest(big.NewInt(5), registry.ContractBasic); !errors.Is(err, registry.ErrNotFound) {
+		t.Fatalf("should not find a deployment on another chain, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/bulk"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ExportConfig represents the settings for an export.
+type ExportConfig struct {
+
+	// DeployBlock is the block the contract was deployed in. Keys are found
+	// from the ItemSet events starting at this block.
+	DeployBlock uint64
+
+	// BlockRange is the number of blocks requested with each FilterItemSet
+	// call. A value of zero defaults to 5000.
+	BlockRange uint64
+
+	// BatchSize is the number of values read in a single call. A value of
+	// zero defaults to 100.
+	BatchSize int
+}
+
+// Export returns the items held by the basic contract at basicID at the
+// block, sorted by key. The contract doesn't list its keys, so they are
+// taken from the ItemSet events up to the block, and the values are read
+// through the multicall contract at multicallID at that block, which must
+// include the multicall deployment. Keys holding zero, which is what the
+// delete command writes, are left out.
+func Export(ctx context.Context, backend bind.ContractBackend, multicallID common.Address, basicID common.Address, block uint64, cfg ExportConfig) ([]Item, error) {
+	if cfg.BlockRange == 0 {
+		cfg.BlockRange = 5000
+	}
+
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 100
+	}
+
+	if cfg.DeployBlock > block {
+		return nil, fmt.Errorf("block %d is before the deploy block %d", block, cfg.DeployBlock)
+	}
+
+	filterer, err := basic.NewBasicFilterer(basicID, backend)
+	if err != nil {
+		return nil, fmt.Errorf("new filterer: %w", err)
+	}
+
+	reader, err := bulk.NewReader(backend, multicallID, basicID)
+	if err != nil {
+		return nil, err
+	}
+
+	// =========================================================================
+
+	seen := make(map[string]bool)
+	for start := cfg.DeployBlock; start <= block; start += cfg.BlockRange {
+		end := min(start+cfg.BlockRange-1, block)
+
+		iter, err := filterer.FilterItemSet(&bind.FilterOpts{Start: start, End: &end, Context: ctx})
+		if err != nil {
+			return nil, fmt.Errorf("filter item set %d-%d: %w", start, end, err)
+		}
+
+		for iter.Next() {
+			seen[iter.Event.Key] = true
+		}
+
+		err = iter.Error()
+		iter.Close()
+		if err != nil {
+			return nil, fmt.Errorf("iterate item set %d-%d: %w", start, end, err)
+		}
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// =========================================================================
+
+	opts := bind.CallOpts{
+		Context:     ctx,
+		BlockNumber: new(big.Int).SetUint64(block),
+	}
+
+	var items []Item
+	for start := 0; start < len(keys); start += cfg.BatchSize {
+		batch := keys[start:min(start+cfg.BatchSize, len(keys))]
+
+		_, values, err := reader.Items(&opts, batch)
+		if err != nil {
+			return nil, fmt.Errorf("read values at block %d: %w", block, err)
+		}
+
+		for _, v := range values {
+			if v.Err != nil {
+				return nil, fmt.Errorf("read %s: %w", v.Key, v.Err)
+			}
+
+			if v.Value.Sign() == 0 {
+				continue
+			}
+
+			items = append(items, Item{Key: v.Key, Value: v.Value})
+		}
+	}
+
+	return items, nil
+}
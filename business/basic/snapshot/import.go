@@ -0,0 +1,285 @@
+package snapshot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/bulk"
+	"github.com/ardanlabs/smartcontract/foundation/sender"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrCheckpointMismatch is returned when the checkpoint was written for a
+// different contract or set of items.
+var ErrCheckpointMismatch = errors.New("checkpoint was written for a different contract or file")
+
+// ImportConfig represents the settings for an import.
+type ImportConfig struct {
+
+	// Checkpoint is the file progress is saved to after every batch. An
+	// import finding a checkpoint for the same contract and items resumes
+	// after the last saved batch. The file is removed once the import is
+	// done. An empty value disables checkpoints.
+	Checkpoint string
+
+	// BatchSize is the number of items read and written at a time. A value
+	// of zero defaults to 100.
+	BatchSize int
+
+	// GasLimit and GasPrice are used for every SetItem transaction. A nil
+	// or zero gas price uses the price suggested by the node.
+	GasLimit uint64
+	GasPrice *big.Int
+
+	// DryRun reads the current values and reports what would be written
+	// without sending any transactions or saving a checkpoint.
+	DryRun bool
+
+	// Progress is called after every batch. It may be nil.
+	Progress func(Progress)
+}
+
+// Progress represents how far an import has got.
+type Progress struct {
+	Total   int `json:"total"`
+	Done    int `json:"done"`
+	Written int `json:"written"`
+	Skipped int `json:"skipped"`
+}
+
+// checkpoint represents the progress saved between runs of an import.
+type checkpoint struct {
+	Contract common.Address `json:"contract"`
+	Digest   common.Hash    `json:"digest"`
+	Progress
+}
+
+// Importer writes items from a file to the basic contract.
+type Importer struct {
+	pool       *sender.Pool
+	reader     *bulk.Reader
+	contract   *basic.Basic
+	contractID common.Address
+	cfg        ImportConfig
+}
+
+// NewImporter constructs an importer for the basic contract at basicID.
+// Current values are read through the multicall contract at multicallID and
+// the writes are sent by the pool.
+func NewImporter(backend bind.ContractBackend, pool *sender.Pool, multicallID common.Address, basicID common.Address, cfg ImportConfig) (*Importer, error) {
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 100
+	}
+
+	reader, err := bulk.NewReader(backend, multicallID, basicID)
+	if err != nil {
+		return nil, err
+	}
+
+	contract, err := basic.NewBasic(basicID, backend)
+	if err != nil {
+		return nil, fmt.Errorf("new contract: %w", err)
+	}
+
+	im := Importer{
+		pool:       pool,
+		reader:     reader,
+		contract:   contract,
+		contractID: basicID,
+		cfg:        cfg,
+	}
+
+	return &im, nil
+}
+
+// Import writes the items whose value on chain differs from the value in
+// the file. Items are handled in batches, the writes of a batch are sent
+// together and all mined before the checkpoint moves past them. A batch
+// that was interrupted is read again when the import resumes, so the writes
+// that made it on chain are skipped.
+func (im *Importer) Import(ctx context.Context, items []Item) (Progress, error) {
+	digest, err := digestOf(items)
+	if err != nil {
+		return Progress{}, err
+	}
+
+	cp := checkpoint{
+		Contract: im.contractID,
+		Digest:   digest,
+		Progress: Progress{Total: len(items)},
+	}
+
+	if im.cfg.Checkpoint != "" && !im.cfg.DryRun {
+		saved, found, err := im.load()
+		if err != nil {
+			return Progress{}, err
+		}
+
+		if found {
+			if saved.Contract != cp.Contract || saved.Digest != cp.Digest {
+				return Progress{}, ErrCheckpointMismatch
+			}
+			cp = saved
+		}
+	}
+
+	// =========================================================================
+
+	for cp.Done < len(items) {
+		end := min(cp.Done+im.cfg.BatchSize, len(items))
+		batch := items[cp.Done:end]
+
+		changed, err := im.changed(ctx, batch)
+		if err != nil {
+			return cp.Progress, err
+		}
+
+		if !im.cfg.DryRun {
+			if err := im.write(ctx, changed); err != nil {
+				return cp.Progress, err
+			}
+		}
+
+		cp.Done = end
+		cp.Written += len(changed)
+		cp.Skipped += len(batch) - len(changed)
+
+		if im.cfg.Checkpoint != "" && !im.cfg.DryRun {
+			if err := im.save(cp); err != nil {
+				return cp.Progress, err
+			}
+		}
+
+		if im.cfg.Progress != nil {
+			im.cfg.Progress(cp.Progress)
+		}
+	}
+
+	if im.cfg.Checkpoint != "" && !im.cfg.DryRun {
+		if err := os.Remove(im.cfg.Checkpoint); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return cp.Progress, fmt.Errorf("remove checkpoint: %w", err)
+		}
+	}
+
+	return cp.Progress, nil
+}
+
+// =============================================================================
+
+// changed returns the items in the batch whose value on chain differs from
+// the value in the file. All the values are read at the same block.
+func (im *Importer) changed(ctx context.Context, batch []Item) ([]Item, error) {
+	keys := make([]string, len(batch))
+	for i, item := range batch {
+		keys[i] = item.Key
+	}
+
+	_, current, err := im.reader.Items(&bind.CallOpts{Context: ctx}, keys)
+	if err != nil {
+		return nil, fmt.Errorf("read current values: %w", err)
+	}
+
+	var changed []Item
+	for i, item := range batch {
+		if current[i].Err != nil {
+			return nil, fmt.Errorf("read %s: %w", item.Key, current[i].Err)
+		}
+
+		if current[i].Value.Cmp(item.Value) != 0 {
+			changed = append(changed, item)
+		}
+	}
+
+	return changed, nil
+}
+
+// write sends a SetItem transaction for every item and waits for all of
+// them to be mined.
+func (im *Importer) write(ctx context.Context, items []Item) error {
+	txs := make([]*types.Transaction, 0, len(items))
+
+	for _, item := range items {
+		tx, err := im.pool.Send(ctx, im.cfg.GasLimit, im.cfg.GasPrice, big.NewFloat(0), func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return im.contract.SetItem(opts, item.Key, item.Value)
+		})
+		if err != nil {
+			return fmt.Errorf("set %s: %w", item.Key, err)
+		}
+		txs = append(txs, tx)
+	}
+
+	for i, tx := range txs {
+		receipt, err := im.pool.WaitMined(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("wait for %s: %w", items[i].Key, err)
+		}
+
+		if receipt.Status == types.ReceiptStatusFailed {
+			return fmt.Errorf("set %s: transaction %s failed", items[i].Key, tx.Hash().Hex())
+		}
+	}
+
+	return nil
+}
+
+// load reads the checkpoint. The boolean is false when there is none.
+func (im *Importer) load() (checkpoint, bool, error) {
+	data, err := os.ReadFile(im.cfg.Checkpoint)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return checkpoint{}, false, nil
+		}
+		return checkpoint{}, false, fmt.Errorf("read checkpoint: %w", err)
+	}
+
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return checkpoint{}, false, fmt.Errorf("decode checkpoint: %w", err)
+	}
+
+	return cp, true, nil
+}
+
+// save writes the checkpoint by replacing the file so it's never left half
+// written.
+func (im *Importer) save(cp checkpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode checkpoint: %w", err)
+	}
+
+	path := im.cfg.Checkpoint
+
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("replace checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// digestOf identifies a set of items, so a checkpoint is only resumed for
+// the items it was written for.
+func digestOf(items []Item) (common.Hash, error) {
+	h := sha256.New()
+
+	enc := json.NewEncoder(h)
+	for _, item := range items {
+		if err := enc.Encode(fileItem{Key: item.Key, Value: item.Value.String()}); err != nil {
+			return common.Hash{}, fmt.Errorf("digest items: %w", err)
+		}
+	}
+
+	return common.BytesToHash(h.Sum(nil)), nil
+}
@@ -0,0 +1,220 @@
+// Package snapshot moves basic items between files and the contract. Files
+// hold key/value pairs as CSV or JSON and are validated as a whole before
+// anything is written. Imports skip keys that already hold the value in the
+// file and keep a checkpoint so an interrupted import resumes where it
+// stopped. Exports write the items held at a pinned block in the same
+// format, so an export can be imported into another environment.
+package snapshot
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Set of supported formats.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// header is the first line of every CSV file.
+var header = []string{"key", "value"}
+
+// Item represents a key and its value.
+type Item struct {
+	Key   string
+	Value *big.Int
+}
+
+// fileItem represents an item in a JSON file. Values are written as strings
+// so 256 bit values survive tools that read numbers as floats.
+type fileItem struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// FormatOf returns the format for the file based on its extension.
+func FormatOf(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".json":
+		return FormatJSON, nil
+	}
+
+	return "", fmt.Errorf("unknown format for %s, use a .csv or .json file", path)
+}
+
+// =============================================================================
+
+// ReadFile reads and validates the items in the file.
+func ReadFile(path string) ([]Item, error) {
+	format, err := FormatOf(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open items: %w", err)
+	}
+	defer f.Close()
+
+	return Read(f, format)
+}
+
+// Read reads and validates the items in the format. Every problem in the
+// input is reported, not just the first, with the line of a CSV row or the
+// index of a JSON element.
+func Read(r io.Reader, format string) ([]Item, error) {
+	var items []Item
+	var errs []error
+
+	add := func(where string, key string, value string) {
+		item, err := parseItem(key, value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", where, err))
+			return
+		}
+		items = append(items, item)
+	}
+
+	switch format {
+	case FormatCSV:
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = len(header)
+
+		records, err := cr.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("read csv: %w", err)
+		}
+
+		if len(records) == 0 || records[0][0] != header[0] || records[0][1] != header[1] {
+			return nil, fmt.Errorf("csv must start with the header %s", strings.Join(header, ","))
+		}
+
+		for i, record := range records[1:] {
+			add(fmt.Sprintf("line %d", i+2), record[0], record[1])
+		}
+
+	case FormatJSON:
+		d := json.NewDecoder(r)
+		d.DisallowUnknownFields()
+
+		// Either a string or a number is accepted for the value.
+		var fileItems []struct {
+			Key   string      `json:"key"`
+			Value json.Number `json:"value"`
+		}
+		if err := d.Decode(&fileItems); err != nil {
+			return nil, fmt.Errorf("read json: %w", err)
+		}
+
+		for i, fi := range fileItems {
+			add(fmt.Sprintf("item %d", i), fi.Key, fi.Value.String())
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+
+	// The same key twice is ambiguous about which value is wanted.
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if seen[item.Key] {
+			errs = append(errs, fmt.Errorf("key %q appears more than once", item.Key))
+		}
+		seen[item.Key] = true
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid items: %w", errors.Join(errs...))
+	}
+
+	return items, nil
+}
+
+// WriteFile writes the items to the file in the format for its extension.
+// The file is replaced atomically so a failed export never leaves a partial
+// file behind.
+func WriteFile(path string, items []Item) error {
+	format, err := FormatOf(path)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	if err := Write(&b, format, items); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b.Bytes(), 0644); err != nil {
+		return fmt.Errorf("write items: %w", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replace items: %w", err)
+	}
+
+	return nil
+}
+
+// Write writes the items in the format.
+func Write(w io.Writer, format string, items []Item) error {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write(header)
+		for _, item := range items {
+			cw.Write([]string{item.Key, item.Value.String()})
+		}
+		cw.Flush()
+
+		if err := cw.Error(); err != nil {
+			return fmt.Errorf("write csv: %w", err)
+		}
+
+		return nil
+
+	case FormatJSON:
+		out := make([]fileItem, len(items))
+		for i, item := range items {
+			out[i] = fileItem{Key: item.Key, Value: item.Value.String()}
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			return fmt.Errorf("write json: %w", err)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// =============================================================================
+
+// parseItem validates a key and value read from a file.
+func parseItem(key string, value string) (Item, error) {
+	if key == "" {
+		return Item{}, errors.New("key is empty")
+	}
+
+	v, ok := new(big.Int).SetString(strings.TrimSpace(value), 10)
+	if !ok || v.Sign() < 0 || v.BitLen() > 256 {
+		return Item{}, fmt.Errorf("value %q for key %q must be an unsigned 256 bit decimal integer", value, key)
+	}
+
+	return Item{Key: key, Value: v}, nil
+}
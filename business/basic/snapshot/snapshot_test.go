@@ -0,0 +1,270 @@
+package snapshot_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/multicall"
+	"github.com/ardanlabs/smartcontract/business/basic/snapshot"
+	"github.com/ardanlabs/smartcontract/foundation/sender"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+func TestRead(t *testing.T) {
+	csvData := "key,value\nbill,1000\njill, 42\n"
+
+	items, err := snapshot.Read(strings.NewReader(csvData), snapshot.FormatCSV)
+	if err != nil {
+		t.Fatalf("should read csv: %s", err)
+	}
+
+	if len(items) != 2 || items[1].Key != "jill" || items[1].Value.Int64() != 42 {
+		t.Fatalf("wrong csv items, got %v", items)
+	}
+
+	jsonData := `[{"key": "bill", "value": "1000"}, {"key": "jill", "value": 42}]`
+
+	items, err = snapshot.Read(strings.NewReader(jsonData), snapshot.FormatJSON)
+	if err != nil {
+		t.Fatalf("should read json: %s", err)
+	}
+
+	if len(items) != 2 || items[0].Value.Int64() != 1000 || items[1].Value.Int64() != 42 {
+		t.Fatalf("wrong json items, got %v", items)
+	}
+
+	// =========================================================================
+
+	// Every problem is reported, not just the first.
+	bad := "key,value\n,1\nbill,-5\njill,abc\nbill,7\nbill,8\n"
+
+	_, err = snapshot.Read(strings.NewReader(bad), snapshot.FormatCSV)
+	if err == nil {
+		t.Fatal("should refuse invalid items")
+	}
+
+	for _, want := range []string{"line 2: key is empty", "line 3", "line 4", `key "bill" appears more than once`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error should mention %q, got %s", want, err)
+		}
+	}
+
+	if _, err := snapshot.Read(strings.NewReader("name,amount\nbill,1\n"), snapshot.FormatCSV); err == nil {
+		t.Fatal("should refuse a csv without the header")
+	}
+
+	if _, err := snapshot.Read(strings.NewReader(`[{"key": "bill", "val": "1"}]`), snapshot.FormatJSON); err == nil {
+		t.Fatal("should refuse unknown json fields")
+	}
+
+	// =========================================================================
+
+	huge, _ := new(big.Int).SetString("115792089237316195423570985008687907853269984665640564039457584007913129639935", 10)
+	items = []snapshot.Item{{Key: "bill", Value: huge}, {Key: "a,b", Value: big.NewInt(1)}}
+
+	for _, format := range []string{snapshot.FormatCSV, snapshot.FormatJSON} {
+		var b bytes.Buffer
+		if err := snapshot.Write(&b, format, items); err != nil {
+			t.Fatalf("should write %s: %s", format, err)
+		}
+
+		got, err := snapshot.Read(&b, format)
+		if err != nil {
+			t.Fatalf("should read back %s: %s", format, err)
+		}
+
+		if len(got) != 2 || got[0].Value.Cmp(huge) != 0 || got[1].Key != "a,b" {
+			t.Fatalf("wrong %s round trip, got %v", format, got)
+		}
+	}
+}
+
+func TestImportExport(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	clt, err := ethereum.NewClient(backend, backend.PrivateKeys[0])
+	if err != nil {
+		t.Fatalf("unable to create ethereum api: %s", err)
+	}
+
+	gasPrice := currency.GWei2Wei(big.NewFloat(39.576))
+
+	transOpts := func() *bind.TransactOpts {
+		opts, err := clt.NewTransactOpts(ctx, 1600000, gasPrice, big.NewFloat(0))
+		if err != nil {
+			t.Fatalf("unable to create transaction opts: %s", err)
+		}
+		return opts
+	}
+
+	multicallID, tx, _, err := multicall.DeployMulticall(transOpts(), clt.Backend)
+	if err != nil {
+		t.Fatalf("unable to deploy multicall: %s", err)
+	}
+
+	if _, err := clt.WaitMined(ctx, tx); err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+
+	basicID, tx, contract, err := basic.DeployBasic(transOpts(), clt.Backend)
+	if err != nil {
+		t.Fatalf("unable to deploy basic: %s", err)
+	}
+
+	receipt, err := clt.WaitMined(ctx, tx)
+	if err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+	deployBlock := receipt.BlockNumber.Uint64()
+
+	// bill already holds the value in the file, so it's never written.
+	tx, err = contract.SetItem(transOpts(), "bill", big.NewInt(1))
+	if err != nil {
+		t.Fatalf("unable to set item: %s", err)
+	}
+
+	if _, err := clt.WaitMined(ctx, tx); err != nil {
+		t.Fatalf("waiting for set item: %s", err)
+	}
+
+	pool, err := sender.New(clt.Backend, sender.RoundRobin, backend.PrivateKeys[0])
+	if err != nil {
+		t.Fatalf("unable to create pool: %s", err)
+	}
+
+	// =========================================================================
+
+	items := []snapshot.Item{
+		{Key: "bill", Value: big.NewInt(1)},
+		{Key: "jill", Value: big.NewInt(2)},
+		{Key: "ale", Value: big.NewInt(3)},
+		{Key: "cesar", Value: big.NewInt(4)},
+		{Key: "pavel", Value: big.NewInt(5)},
+	}
+
+	checkpoint := filepath.Join(t.TempDir(), "items.checkpoint")
+
+	// The first run is stopped after its first batch, as if interrupted.
+	stopCtx, stop := context.WithCancel(ctx)
+
+	cfg := snapshot.ImportConfig{
+		Checkpoint: checkpoint,
+		BatchSize:  2,
+		GasLimit:   1600000,
+		GasPrice:   gasPrice,
+		Progress:   func(snapshot.Progress) { stop() },
+	}
+
+	im, err := snapshot.NewImporter(clt.Backend, pool, multicallID, basicID, cfg)
+	if err != nil {
+		t.Fatalf("unable to create importer: %s", err)
+	}
+
+	if _, err := im.Import(stopCtx, items); err == nil {
+		t.Fatal("should stop when the context is cancelled")
+	}
+
+	if _, err := os.Stat(checkpoint); err != nil {
+		t.Fatalf("should leave a checkpoint behind: %s", err)
+	}
+
+	var seen []snapshot.Progress
+	cfg.Progress = func(p snapshot.Progress) { seen = append(seen, p) }
+
+	im, err = snapshot.NewImporter(clt.Backend, pool, multicallID, basicID, cfg)
+	if err != nil {
+		t.Fatalf("unable to create importer: %s", err)
+	}
+
+	progress, err := im.Import(ctx, items)
+	if err != nil {
+		t.Fatalf("should resume the import: %s", err)
+	}
+
+	if seen[0].Done != 4 {
+		t.Fatalf("should resume after the first batch, got %+v", seen[0])
+	}
+
+	exp := snapshot.Progress{Total: 5, Done: 5, Written: 4, Skipped: 1}
+	if progress != exp {
+		t.Fatalf("wrong progress, got %+v exp %+v", progress, exp)
+	}
+
+	if _, err := os.Stat(checkpoint); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("should remove the checkpoint once done, got %v", err)
+	}
+
+	for _, item := range items {
+		value, err := contract.Items(nil, item.Key)
+		if err != nil || value.Cmp(item.Value) != 0 {
+			t.Fatalf("wrong value for %s, got %v exp %v: %v", item.Key, value, item.Value, err)
+		}
+	}
+
+	progress, err = im.Import(ctx, items)
+	if err != nil {
+		t.Fatalf("should import again: %s", err)
+	}
+
+	if progress.Written != 0 || progress.Skipped != 5 {
+		t.Fatalf("importing again should skip everything, got %+v", progress)
+	}
+
+	// =========================================================================
+
+	head, err := backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		t.Fatalf("unable to read head: %s", err)
+	}
+	pinned := head.Number.Uint64()
+
+	// Changes after the pinned block don't show up in the export.
+	for _, key := range []string{"jill", "ale"} {
+		tx, err := contract.SetItem(transOpts(), key, big.NewInt(0))
+		if err != nil {
+			t.Fatalf("unable to clear item: %s", err)
+		}
+
+		if _, err := clt.WaitMined(ctx, tx); err != nil {
+			t.Fatalf("waiting for clear item: %s", err)
+		}
+	}
+
+	exported, err := snapshot.Export(ctx, clt.Backend, multicallID, basicID, pinned, snapshot.ExportConfig{DeployBlock: deployBlock, BlockRange: 3, BatchSize: 2})
+	if err != nil {
+		t.Fatalf("should export: %s", err)
+	}
+
+	if len(exported) != len(items) || exported[0].Key != "ale" || exported[0].Value.Int64() != 3 {
+		t.Fatalf("wrong export at block %d, got %v", pinned, exported)
+	}
+
+	head, err = backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		t.Fatalf("unable to read head: %s", err)
+	}
+
+	exported, err = snapshot.Export(ctx, clt.Backend, multicallID, basicID, head.Number.Uint64(), snapshot.ExportConfig{DeployBlock: deployBlock})
+	if err != nil {
+		t.Fatalf("should export at the head: %s", err)
+	}
+
+	if len(exported) != 3 {
+		t.Fatalf("cleared keys should be left out, got %v", exported)
+	}
+}
@@ -0,0 +1,515 @@
+// Package stream provides an HTTP server that fans out the ItemSet events
+// emitted by the basic contract to WebSocket and Server-Sent Events clients.
+//
+//	GET /events/ws   upgrades to a WebSocket and sends one JSON event per message
+//	GET /events/sse  streams events as text/event-stream
+//
+// Both endpoints accept any number of key query parameters to only receive
+// events for those keys, and a cursor query parameter holding a block number
+// to replay the events from that block before streaming live ones. SSE
+// clients that reconnect with a Last-Event-ID header resume right after the
+// last event they received.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/watch"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/gorilla/websocket"
+)
+
+// ErrSlowClient is returned to a client that could not keep up with the
+// events being sent to it.
+var ErrSlowClient = errors.New("client too slow, events dropped")
+
+// Config represents the settings for the server.
+type Config struct {
+
+	// ClientBuffer is the number of events buffered for each client. A
+	// client that falls further behind is disconnected and can resume with
+	// a cursor. A value of zero defaults to 256.
+	ClientBuffer int
+
+	// BatchSize is the number of blocks requested at a time when replaying
+	// from a cursor. A value of zero defaults to 1000.
+	BatchSize uint64
+
+	// KeepAlive is how often an idle connection is pinged. A value of zero
+	// defaults to 15 seconds.
+	KeepAlive time.Duration
+
+	// CheckOrigin decides if a WebSocket upgrade from another origin is
+	// allowed. When nil, only same origin requests are allowed.
+	CheckOrigin func(r *http.Request) bool
+
+	// Log receives subscription errors. When nil, the root logger is used.
+	Log log.Logger
+}
+
+// Event represents a decoded ItemSet event sent to clients.
+type Event struct {
+	ID        string      `json:"id"`
+	Key       string      `json:"key"`
+	Value     string      `json:"value"`
+	Block     uint64      `json:"block"`
+	BlockHash common.Hash `json:"block_hash"`
+	TxHash    common.Hash `json:"tx_hash"`
+	LogIndex  uint        `json:"log_index"`
+	Removed   bool        `json:"removed"`
+}
+
+// Cursor represents a position in the stream of events.
+type Cursor struct {
+	Block    uint64
+	LogIndex uint
+}
+
+// ParseCursor converts an event id in the form block-logindex to a cursor.
+func ParseCursor(id string) (Cursor, error) {
+	block, index, found := strings.Cut(id, "-")
+	if !found {
+		return Cursor{}, fmt.Errorf("invalid cursor %q", id)
+	}
+
+	b, err := strconv.ParseUint(block, 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor block %q: %w", id, err)
+	}
+
+	i, err := strconv.ParseUint(index, 10, 32)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor log index %q: %w", id, err)
+	}
+
+	return Cursor{Block: b, LogIndex: uint(i)}, nil
+}
+
+// String returns the cursor in the form used for event ids.
+func (c Cursor) String() string {
+	return fmt.Sprintf("%d-%d", c.Block, c.LogIndex)
+}
+
+// before reports whether the cursor comes before the other cursor.
+func (c Cursor) before(o Cursor) bool {
+	return c.Block < o.Block || (c.Block == o.Block && c.LogIndex < o.LogIndex)
+}
+
+// =============================================================================
+
+// client represents a single connected stream.
+type client struct {
+	keys    map[string]struct{}
+	events  chan Event
+	dropped chan struct{}
+	once    sync.Once
+}
+
+// wants reports whether the client filters in the key.
+func (c *client) wants(key string) bool {
+	if len(c.keys) == 0 {
+		return true
+	}
+
+	_, exists := c.keys[key]
+	return exists
+}
+
+// Server fans out ItemSet events to streaming clients.
+type Server struct {
+	backend  bind.ContractBackend
+	filterer *basic.BasicFilterer
+	watcher  *watch.Watcher
+	cfg      Config
+	upgrader websocket.Upgrader
+	live     atomic.Bool
+
+	mu      sync.RWMutex
+	clients map[*client]struct{}
+}
+
+// New constructs a server for the basic contract at the specified address.
+func New(backend bind.ContractBackend, contractID common.Address, cfg Config) (*Server, error) {
+	if cfg.ClientBuffer == 0 {
+		cfg.ClientBuffer = 256
+	}
+
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 1000
+	}
+
+	if cfg.KeepAlive == 0 {
+		cfg.KeepAlive = 15 * time.Second
+	}
+
+	if cfg.Log == nil {
+		cfg.Log = log.Root()
+	}
+
+	filterer, err := basic.NewBasicFilterer(contractID, backend)
+	if err != nil {
+		return nil, fmt.Errorf("new filterer: %w", err)
+	}
+
+	watcher, err := watch.New(backend, contractID, watch.Config{Log: cfg.Log})
+	if err != nil {
+		return nil, fmt.Errorf("new watcher: %w", err)
+	}
+
+	s := Server{
+		backend:  backend,
+		filterer: filterer,
+		watcher:  watcher,
+		cfg:      cfg,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: cfg.CheckOrigin,
+		},
+		clients: make(map[*client]struct{}),
+	}
+
+	return &s, nil
+}
+
+// Run watches for ItemSet events and fans them out until the context is
+// cancelled.
+func (s *Server) Run(ctx context.Context) error {
+	h := watch.Handler{
+		Start: func(ctx context.Context) error {
+			s.live.Store(true)
+			return nil
+		},
+		Event: func(ctx context.Context, ev *basic.BasicItemSet) error {
+			s.broadcast(newEvent(ev))
+			return nil
+		},
+		Stop: func() {
+			s.live.Store(false)
+		},
+	}
+
+	return s.watcher.Run(ctx, h)
+}
+
+// Live reports whether the event subscription is established.
+func (s *Server) Live() bool {
+	return s.live.Load()
+}
+
+// Clients returns the number of connected clients.
+func (s *Server) Clients() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.clients)
+}
+
+// Handler returns the HTTP handler for the streaming endpoints.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /events/ws", s.serveWebSocket)
+	mux.HandleFunc("GET /events/sse", s.serveSSE)
+
+	return mux
+}
+
+// =============================================================================
+
+// serveWebSocket streams events to a WebSocket connection.
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	keys, from, after, err := parseQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// The client isn't expected to send anything, but reading is required
+	// to process control frames and notice when the client goes away.
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(ev Event) error {
+		conn.SetWriteDeadline(time.Now().Add(s.cfg.KeepAlive))
+		return conn.WriteJSON(ev)
+	}
+
+	ping := func() error {
+		return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(s.cfg.KeepAlive))
+	}
+
+	err = s.stream(ctx, keys, from, after, send, ping)
+
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	if err != nil && !errors.Is(err, context.Canceled) {
+		msg = websocket.FormatCloseMessage(websocket.CloseTryAgainLater, err.Error())
+	}
+	conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+}
+
+// serveSSE streams events as Server-Sent Events.
+func (s *Server) serveSSE(w http.ResponseWriter, r *http.Request) {
+	keys, from, after, err := parseQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		cursor, err := ParseCursor(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		from = &cursor.Block
+		after = &cursor
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	send := func(ev Event) error {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintf(w, "id: %s\nevent: ItemSet\ndata: %s\n\n", ev.ID, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+
+		return nil
+	}
+
+	ping := func() error {
+		if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+			return err
+		}
+		flusher.Flush()
+
+		return nil
+	}
+
+	if err := s.stream(r.Context(), keys, from, after, send, ping); err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintf(w, "event: error\ndata: %s\n\n", err)
+		flusher.Flush()
+	}
+}
+
+// stream registers a client, replays the events from the cursor block if
+// one was provided, and then sends live events until the context is
+// cancelled or sending fails. Live events already sent during the replay
+// are skipped.
+func (s *Server) stream(ctx context.Context, keys map[string]struct{}, from *uint64, after *Cursor, send func(Event) error, ping func() error) error {
+	c := client{
+		keys:    keys,
+		events:  make(chan Event, s.cfg.ClientBuffer),
+		dropped: make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	s.clients[&c] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, &c)
+		s.mu.Unlock()
+	}()
+
+	var last *Cursor
+	if from != nil {
+		var err error
+		if last, err = s.replay(ctx, &c, *from, after, send); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(s.cfg.KeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case ev := <-c.events:
+			pos := Cursor{Block: ev.Block, LogIndex: ev.LogIndex}
+			if !ev.Removed && last != nil && !last.before(pos) {
+				continue
+			}
+
+			if err := send(ev); err != nil {
+				return err
+			}
+
+		case <-ticker.C:
+			if err := ping(); err != nil {
+				return err
+			}
+
+		case <-c.dropped:
+			return ErrSlowClient
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// replay sends the events for the client from the specified block to the
+// head of the chain, skipping events up to and including the after cursor.
+// It returns the position of the last event it covered.
+func (s *Server) replay(ctx context.Context, c *client, from uint64, after *Cursor, send func(Event) error) (*Cursor, error) {
+	header, err := s.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("head block: %w", err)
+	}
+	head := header.Number.Uint64()
+
+	last := after
+	for from <= head {
+		to := min(from+s.cfg.BatchSize-1, head)
+
+		iter, err := s.filterer.FilterItemSet(&bind.FilterOpts{Start: from, End: &to, Context: ctx})
+		if err != nil {
+			return nil, fmt.Errorf("filter %d-%d: %w", from, to, err)
+		}
+
+		for iter.Next() {
+			ev := newEvent(iter.Event)
+			pos := Cursor{Block: ev.Block, LogIndex: ev.LogIndex}
+
+			if after != nil && !after.before(pos) {
+				continue
+			}
+			last = &pos
+
+			if !c.wants(ev.Key) {
+				continue
+			}
+
+			if err := send(ev); err != nil {
+				iter.Close()
+				return nil, err
+			}
+		}
+		iter.Close()
+
+		if err := iter.Error(); err != nil {
+			return nil, fmt.Errorf("filter %d-%d: %w", from, to, err)
+		}
+
+		from = to + 1
+	}
+
+	// Even without events, everything up to the head has been covered.
+	if last == nil || last.Block < head {
+		last = &Cursor{Block: head, LogIndex: ^uint(0)}
+	}
+
+	return last, nil
+}
+
+// broadcast hands the event to every client that wants it. A client whose
+// buffer is full is marked as dropped rather than blocking everyone else.
+func (s *Server) broadcast(ev Event) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for c := range s.clients {
+		if !c.wants(ev.Key) {
+			continue
+		}
+
+		select {
+		case c.events <- ev:
+		default:
+			c.once.Do(func() { close(c.dropped) })
+		}
+	}
+}
+
+// =============================================================================
+
+// newEvent converts the contract event into the event sent to clients.
+func newEvent(ev *basic.BasicItemSet) Event {
+	cursor := Cursor{Block: ev.Raw.BlockNumber, LogIndex: ev.Raw.Index}
+
+	return Event{
+		ID:        cursor.String(),
+		Key:       ev.Key,
+		Value:     ev.Value.String(),
+		Block:     ev.Raw.BlockNumber,
+		BlockHash: ev.Raw.BlockHash,
+		TxHash:    ev.Raw.TxHash,
+		LogIndex:  ev.Raw.Index,
+		Removed:   ev.Raw.Removed,
+	}
+}
+
+// parseQuery extracts the key filter and cursor block from the request. A
+// cursor can be a block number, or an event id to resume right after it.
+func parseQuery(r *http.Request) (map[string]struct{}, *uint64, *Cursor, error) {
+	query := r.URL.Query()
+
+	var keys map[string]struct{}
+	if values := query["key"]; len(values) > 0 {
+		keys = make(map[string]struct{}, len(values))
+		for _, key := range values {
+			keys[key] = struct{}{}
+		}
+	}
+
+	value := query.Get("cursor")
+	if value == "" {
+		return keys, nil, nil, nil
+	}
+
+	if strings.Contains(value, "-") {
+		cursor, err := ParseCursor(value)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return keys, &cursor.Block, &cursor, nil
+	}
+
+	block, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid cursor %q: %w", value, err)
+	}
+
+	return keys, &block, nil, nil
+}
@@ -0,0 +1,213 @@
+package stream_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/stream"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/gorilla/websocket"
+)
+
+func TestStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	clt, err := ethereum.NewClient(backend, backend.PrivateKeys[0])
+	if err != nil {
+		t.Fatalf("unable to create ethereum api: %s", err)
+	}
+
+	// =========================================================================
+
+	const gasLimit = 1600000
+	valueGwei := big.NewFloat(0.0)
+	gasPrice := currency.GWei2Wei(big.NewFloat(39.576))
+
+	transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+	if err != nil {
+		t.Fatalf("unable to create opts for deploy: %s", err)
+	}
+
+	contractID, tx, testBasic, err := basic.DeployBasic(transOpts, clt.Backend)
+	if err != nil {
+		t.Fatalf("unable to deploy basic: %s", err)
+	}
+
+	if _, err := clt.WaitMined(ctx, tx); err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+
+	setItem := func(key string, value int64) {
+		t.Helper()
+
+		transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+		if err != nil {
+			t.Fatalf("unable to create transaction opts for setItems: %s", err)
+		}
+
+		tx, err := testBasic.SetItem(transOpts, key, big.NewInt(value))
+		if err != nil {
+			t.Fatalf("should be able to set item: %s", err)
+		}
+
+		if _, err := clt.WaitMined(ctx, tx); err != nil {
+			t.Fatalf("waiting for set item: %s", err)
+		}
+	}
+
+	// =========================================================================
+
+	setItem("bill", 1)
+	setItem("jill", 2)
+	setItem("bill", 3)
+
+	s, err := stream.New(backend, contractID, stream.Config{Log: log.NewLogger(log.DiscardHandler())})
+	if err != nil {
+		t.Fatalf("unable to create server: %s", err)
+	}
+
+	go s.Run(ctx)
+
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	waitFor(t, "subscription", s.Live)
+
+	// =========================================================================
+
+	// The SSE client replays bill from the start and then sees live events.
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events/sse?key=bill&cursor=0", nil)
+	if err != nil {
+		t.Fatalf("unable to create request: %s", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("should be able to connect sse: %s", err)
+	}
+	defer resp.Body.Close()
+
+	events := sseEvents(resp)
+
+	checkEvent(t, next(t, events), "bill", "1")
+	last := checkEvent(t, next(t, events), "bill", "3")
+
+	// The WebSocket client starts live with no cursor and only wants jill.
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/events/ws?key=jill"
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("should be able to connect websocket: %s", err)
+	}
+	defer conn.Close()
+
+	waitFor(t, "clients", func() bool { return s.Clients() == 2 })
+
+	setItem("jill", 4)
+	setItem("bill", 5)
+
+	var ev stream.Event
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.ReadJSON(&ev); err != nil {
+		t.Fatalf("should be able to read websocket event: %s", err)
+	}
+	checkEvent(t, ev, "jill", "4")
+
+	checkEvent(t, next(t, events), "bill", "5")
+
+	// =========================================================================
+
+	// A reconnecting SSE client resumes right after the last event it saw.
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events/sse", nil)
+	if err != nil {
+		t.Fatalf("unable to create request: %s", err)
+	}
+	req.Header.Set("Last-Event-ID", last.ID)
+
+	resp2, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("should be able to reconnect sse: %s", err)
+	}
+	defer resp2.Body.Close()
+
+	resumed := sseEvents(resp2)
+
+	checkEvent(t, next(t, resumed), "jill", "4")
+	checkEvent(t, next(t, resumed), "bill", "5")
+}
+
+// =============================================================================
+
+// sseEvents decodes the data lines of the response onto a channel.
+func sseEvents(resp *http.Response) <-chan stream.Event {
+	ch := make(chan stream.Event, 10)
+
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, found := strings.CutPrefix(scanner.Text(), "data: ")
+			if !found {
+				continue
+			}
+
+			var ev stream.Event
+			if err := json.Unmarshal([]byte(data), &ev); err == nil {
+				ch <- ev
+			}
+		}
+	}()
+
+	return ch
+}
+
+func next(t *testing.T, ch <-chan stream.Event) stream.Event {
+	t.Helper()
+
+	select {
+	case ev := <-ch:
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for sse event")
+	}
+
+	return stream.Event{}
+}
+
+func checkEvent(t *testing.T, ev stream.Event, key string, value string) stream.Event {
+	t.Helper()
+
+	if ev.Key != key || ev.Value != value {
+		t.Fatalf("wrong event, got %s=%s exp %s=%s", ev.Key, ev.Value, key, value)
+	}
+
+	return ev
+}
+
+func waitFor(t *testing.T, what string, f func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !f() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,135 @@
+// Package verify checks that the code deployed at an address is the code the
+// contract compiles to. The solidity compiler appends a CBOR encoded metadata
+// section to the runtime code, which changes with the source path, comments
+// and compiler settings, so a difference confined to that section is reported
+// apart from a real mismatch.
+package verify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Set of outcomes of a verification.
+const (
+	StatusMatch    = "match"
+	StatusMetadata = "metadata-only"
+	StatusMismatch = "mismatch"
+)
+
+// Result represents the outcome of verifying a single address.
+type Result struct {
+	Address          common.Address `json:"address"`
+	Status           string         `json:"status"`
+	OnChainSize      int            `json:"onchain_size"`
+	CompiledSize     int            `json:"compiled_size"`
+	OnChainHash      common.Hash    `json:"onchain_hash"`
+	CompiledHash     common.Hash    `json:"compiled_hash"`
+	OnChainMetadata  hexutil.Bytes  `json:"onchain_metadata,omitempty"`
+	CompiledMetadata hexutil.Bytes  `json:"compiled_metadata,omitempty"`
+}
+
+// Contract compares the code at the address with the runtime code produced
+// by the creation bytecode. The bin is the hex encoded creation bytecode as
+// generated by abigen, such as basic.BasicMetaData.Bin.
+func Contract(ctx context.Context, backend bind.ContractCaller, address common.Address, bin string) (Result, error) {
+	compiled, err := RuntimeCode(bin)
+	if err != nil {
+		return Result{}, err
+	}
+
+	onChain, err := backend.CodeAt(ctx, address, nil)
+	if err != nil {
+		return Result{}, fmt.Errorf("code at %s: %w", address.Hex(), err)
+	}
+
+	_, onChainMetadata := StripMetadata(onChain)
+	_, compiledMetadata := StripMetadata(compiled)
+
+	r := Result{
+		Address:          address,
+		Status:           Compare(onChain, compiled),
+		OnChainSize:      len(onChain),
+		CompiledSize:     len(compiled),
+		OnChainHash:      crypto.Keccak256Hash(onChain),
+		CompiledHash:     crypto.Keccak256Hash(compiled),
+		OnChainMetadata:  onChainMetadata,
+		CompiledMetadata: compiledMetadata,
+	}
+
+	return r, nil
+}
+
+// RuntimeCode runs the constructor in the creation bytecode against an empty
+// in-memory state and returns the code it leaves behind, which is what a
+// deployment stores on chain.
+func RuntimeCode(bin string) ([]byte, error) {
+	creation, err := hexutil.Decode(bin)
+	if err != nil {
+		return nil, fmt.Errorf("decode bytecode: %w", err)
+	}
+
+	// The compiler may emit opcodes from the latest forks, which are only
+	// active in the EVM once the merge has happened.
+	cfg := runtime.Config{
+		ChainConfig: params.AllDevChainProtocolChanges,
+		BlockNumber: big.NewInt(1),
+		Random:      &common.Hash{},
+	}
+
+	code, _, _, err := runtime.Create(creation, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("run constructor: %w", err)
+	}
+
+	return code, nil
+}
+
+// StripMetadata splits the runtime code from the CBOR metadata the compiler
+// appends to it. The last two bytes hold the length of the metadata, which
+// is encoded as a CBOR map. Code without a metadata section is returned as
+// is with no metadata.
+func StripMetadata(code []byte) ([]byte, []byte) {
+	if len(code) < 2 {
+		return code, nil
+	}
+
+	size := int(code[len(code)-2])<<8 | int(code[len(code)-1])
+	start := len(code) - 2 - size
+	if size == 0 || start < 0 {
+		return code, nil
+	}
+
+	// CBOR major type 5 is a map, the first byte is 0xa0 to 0xbf.
+	if code[start]&0xe0 != 0xa0 {
+		return code, nil
+	}
+
+	return code[:start], code[start:]
+}
+
+// Compare reports whether the on chain code is the compiled code, differs
+// only in the metadata section or doesn't match.
+func Compare(onChain []byte, compiled []byte) string {
+	if len(onChain) > 0 && bytes.Equal(onChain, compiled) {
+		return StatusMatch
+	}
+
+	onChainCode, onChainMetadata := StripMetadata(onChain)
+	compiledCode, compiledMetadata := StripMetadata(compiled)
+
+	if onChainMetadata != nil && compiledMetadata != nil && len(onChainCode) > 0 && bytes.Equal(onChainCode, compiledCode) {
+		return StatusMetadata
+	}
+
+	return StatusMismatch
+}
@@ -0,0 +1,123 @@
+package verify_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/multicall"
+	"github.com/ardanlabs/smartcontract/business/basic/verify"
+)
+
+func TestContract(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	clt, err := ethereum.NewClient(backend, backend.PrivateKeys[0])
+	if err != nil {
+		t.Fatalf("unable to create ethereum api: %s", err)
+	}
+
+	// =========================================================================
+
+	transOpts, err := clt.NewTransactOpts(ctx, 1600000, currency.GWei2Wei(big.NewFloat(39.576)), big.NewFloat(0))
+	if err != nil {
+		t.Fatalf("unable to create opts for deploy: %s", err)
+	}
+
+	basicAddress, tx, _, err := basic.DeployBasic(transOpts, clt.Backend)
+	if err != nil {
+		t.Fatalf("unable to deploy basic: %s", err)
+	}
+
+	if _, err := clt.WaitMined(ctx, tx); err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+
+	transOpts, err = clt.NewTransactOpts(ctx, 1600000, currency.GWei2Wei(big.NewFloat(39.576)), big.NewFloat(0))
+	if err != nil {
+		t.Fatalf("unable to create opts for deploy: %s", err)
+	}
+
+	multicallAddress, tx, _, err := multicall.DeployMulticall(transOpts, clt.Backend)
+	if err != nil {
+		t.Fatalf("unable to deploy multicall: %s", err)
+	}
+
+	if _, err := clt.WaitMined(ctx, tx); err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+
+	// =========================================================================
+
+	r, err := verify.Contract(ctx, clt.Backend, basicAddress, basic.BasicMetaData.Bin)
+	if err != nil {
+		t.Fatalf("should verify basic: %s", err)
+	}
+
+	if r.Status != verify.StatusMatch || r.OnChainHash != r.CompiledHash {
+		t.Fatalf("deployed basic should match, got %+v", r)
+	}
+
+	if len(r.OnChainMetadata) == 0 {
+		t.Fatal("should find the metadata section")
+	}
+
+	r, err = verify.Contract(ctx, clt.Backend, multicallAddress, basic.BasicMetaData.Bin)
+	if err != nil {
+		t.Fatalf("should verify multicall: %s", err)
+	}
+
+	if r.Status != verify.StatusMismatch {
+		t.Fatalf("multicall should not match basic, got %s", r.Status)
+	}
+
+	r, err = verify.Contract(ctx, clt.Backend, clt.Address(), basic.BasicMetaData.Bin)
+	if err != nil {
+		t.Fatalf("should verify an account: %s", err)
+	}
+
+	if r.Status != verify.StatusMismatch || r.OnChainSize != 0 {
+		t.Fatalf("an account without code should not match, got %+v", r)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	compiled, err := verify.RuntimeCode(basic.BasicMetaData.Bin)
+	if err != nil {
+		t.Fatalf("should derive the runtime code: %s", err)
+	}
+
+	code, metadata := verify.StripMetadata(compiled)
+	if len(code)+len(metadata) != len(compiled) || len(metadata) == 0 {
+		t.Fatalf("wrong split, code %d metadata %d of %d", len(code), len(metadata), len(compiled))
+	}
+
+	// Changing a byte of the metadata hash is what a rebuild from a different
+	// path or with different comments looks like.
+	rebuilt := append([]byte(nil), compiled...)
+	rebuilt[len(code)+10] ^= 0xff
+
+	if status := verify.Compare(rebuilt, compiled); status != verify.StatusMetadata {
+		t.Fatalf("should only differ in metadata, got %s", status)
+	}
+
+	changed := append([]byte(nil), compiled...)
+	changed[len(code)/2] ^= 0xff
+
+	if status := verify.Compare(changed, compiled); status != verify.StatusMismatch {
+		t.Fatalf("should not match changed code, got %s", status)
+	}
+
+	if status := verify.Compare(compiled, compiled); status != verify.StatusMatch {
+		t.Fatalf("should match itself, got %s", status)
+	}
+}
@@ -0,0 +1,449 @@
+// Package watch provides a drop in replacement for the generated
+// WatchItemSet that keeps working against endpoints that can't push
+// notifications, like plain HTTP, by polling eth_getLogs instead. Run keeps
+// a subscription established for long running consumers of the events.
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Config represents the settings for the watcher.
+type Config struct {
+
+	// ForcePolling skips trying a subscription and always polls.
+	ForcePolling bool
+
+	// MinInterval is the shortest time between polls once caught up with the
+	// head of the chain. A value of zero defaults to 1 second.
+	MinInterval time.Duration
+
+	// MaxInterval is the longest time between polls when no new blocks are
+	// being produced. A value of zero defaults to 15 seconds.
+	MaxInterval time.Duration
+
+	// InitialRange is the number of blocks requested in the first
+	// eth_getLogs call. A value of zero defaults to 100.
+	InitialRange uint64
+
+	// MaxRange caps the number of blocks requested in a single eth_getLogs
+	// call. A value of zero defaults to 5000.
+	MaxRange uint64
+
+	// ResubscribeDelay is how long Run waits before trying to establish a
+	// new subscription after the previous one dropped. A value of zero
+	// defaults to 1 second.
+	ResubscribeDelay time.Duration
+
+	// Log receives notice of the polling fallback and of dropped
+	// subscriptions. When nil, the root logger is used.
+	Log log.Logger
+}
+
+// Handler represents the functions Run calls while a subscription is
+// established. Any of them can be nil.
+type Handler struct {
+
+	// Start is called each time a subscription is established, before any
+	// event is handed to Event. Events that arrive in the meantime are held,
+	// so this is where to backfill what was missed while no subscription was
+	// established. An error drops the subscription.
+	Start func(ctx context.Context) error
+
+	// Event is called for each event in the order they arrive. An error
+	// drops the subscription.
+	Event func(ctx context.Context, ev *basic.BasicItemSet) error
+
+	// Stop is called when a subscription that was started is dropped.
+	Stop func()
+}
+
+// =============================================================================
+
+// Watcher watches for ItemSet events using a subscription when the
+// transport supports it and polling when it doesn't.
+type Watcher struct {
+	backend  bind.ContractBackend
+	filterer *basic.BasicFilterer
+	cfg      Config
+}
+
+// New constructs a watcher for the basic contract at the specified address.
+func New(backend bind.ContractBackend, contractID common.Address, cfg Config) (*Watcher, error) {
+	if cfg.MinInterval == 0 {
+		cfg.MinInterval = time.Second
+	}
+
+	if cfg.MaxInterval == 0 {
+		cfg.MaxInterval = 15 * time.Second
+	}
+
+	if cfg.MaxInterval < cfg.MinInterval {
+		cfg.MaxInterval = cfg.MinInterval
+	}
+
+	if cfg.MaxRange == 0 {
+		cfg.MaxRange = 5000
+	}
+
+	if cfg.InitialRange == 0 {
+		cfg.InitialRange = 100
+	}
+	cfg.InitialRange = min(cfg.InitialRange, cfg.MaxRange)
+
+	if cfg.ResubscribeDelay == 0 {
+		cfg.ResubscribeDelay = time.Second
+	}
+
+	if cfg.Log == nil {
+		cfg.Log = log.Root()
+	}
+
+	filterer, err := basic.NewBasicFilterer(contractID, backend)
+	if err != nil {
+		return nil, fmt.Errorf("new filterer: %w", err)
+	}
+
+	w := Watcher{
+		backend:  backend,
+		filterer: filterer,
+		cfg:      cfg,
+	}
+
+	return &w, nil
+}
+
+// WatchItemSet has the same behavior as the generated BasicFilterer method.
+// If the transport does not support subscriptions, logs are polled with
+// eth_getLogs starting at opts.Start, or the block after the current head
+// when opts.Start is nil. Polled logs are never marked as removed, so pair
+// this with the reorg package when reorgs matter.
+func (w *Watcher) WatchItemSet(opts *bind.WatchOpts, sink chan<- *basic.BasicItemSet) (event.Subscription, error) {
+	if opts == nil {
+		opts = new(bind.WatchOpts)
+	}
+
+	if !w.cfg.ForcePolling {
+		sub, err := w.filterer.WatchItemSet(opts, sink)
+		if !errors.Is(err, rpc.ErrNotificationsUnsupported) {
+			return sub, err
+		}
+
+		w.cfg.Log.Info("watch", "status", "subscriptions unsupported, polling")
+	}
+
+	return w.poll(opts, sink)
+}
+
+// Range hands the ItemSet events from the first block to the last, or to the
+// current head when to is nil, to the function in order. Blocks are requested
+// in ranges that grow while the node accepts them and shrink when it rejects
+// a range as too large, the same way polling does.
+func (w *Watcher) Range(ctx context.Context, from uint64, to *uint64, fn func(ev *basic.BasicItemSet) error) error {
+	var last uint64
+	switch to {
+	case nil:
+		header, err := w.backend.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("head block: %w", err)
+		}
+		last = header.Number.Uint64()
+
+	default:
+		last = *to
+	}
+
+	p := poller{
+		w:        w,
+		emit:     func(ctx context.Context, ev *basic.BasicItemSet) error { return fn(ev) },
+		next:     from,
+		span:     w.cfg.InitialRange,
+		interval: w.cfg.MinInterval,
+	}
+
+	for p.next <= last {
+		if _, err := p.step(ctx, last); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run keeps an ItemSet subscription established and hands the events to the
+// handler until the context is cancelled. When the subscription drops, or
+// the handler fails, it waits ResubscribeDelay and starts over.
+func (w *Watcher) Run(ctx context.Context, h Handler) error {
+	for {
+		err := w.run(ctx, h)
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		w.cfg.Log.Error("watch", "status", "resubscribing", "err", err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(w.cfg.ResubscribeDelay):
+		}
+	}
+}
+
+// =============================================================================
+
+// run performs a single subscribe, start and consume cycle.
+func (w *Watcher) run(ctx context.Context, h Handler) error {
+	sink := make(chan *basic.BasicItemSet, 100)
+
+	sub, err := w.WatchItemSet(&bind.WatchOpts{Context: ctx}, sink)
+	if err != nil {
+		return fmt.Errorf("watch: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	if h.Start != nil {
+		if err := h.Start(ctx); err != nil {
+			return err
+		}
+	}
+
+	if h.Stop != nil {
+		defer h.Stop()
+	}
+
+	for {
+		select {
+		case ev := <-sink:
+			if h.Event == nil {
+				continue
+			}
+
+			if err := h.Event(ctx, ev); err != nil {
+				return err
+			}
+
+		case err := <-sub.Err():
+			return fmt.Errorf("subscription: %w", err)
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// poll starts the polling loop behind a subscription.
+func (w *Watcher) poll(opts *bind.WatchOpts, sink chan<- *basic.BasicItemSet) (event.Subscription, error) {
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var next uint64
+	switch opts.Start {
+	case nil:
+		header, err := w.backend.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("head block: %w", err)
+		}
+		next = header.Number.Uint64() + 1
+
+	default:
+		next = *opts.Start
+	}
+
+	sub := event.NewSubscription(func(quit <-chan struct{}) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		go func() {
+			select {
+			case <-quit:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		emit := func(ctx context.Context, ev *basic.BasicItemSet) error {
+			select {
+			case sink <- ev:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		p := poller{
+			w:        w,
+			emit:     emit,
+			next:     next,
+			span:     w.cfg.InitialRange,
+			interval: w.cfg.MinInterval,
+		}
+
+		err := p.run(ctx)
+		if ctx.Err() != nil {
+			select {
+			case <-quit:
+				return nil
+			default:
+			}
+		}
+
+		return err
+	})
+
+	return sub, nil
+}
+
+// poller maintains the state of a single polling subscription or range.
+type poller struct {
+	w        *Watcher
+	emit     func(ctx context.Context, ev *basic.BasicItemSet) error
+	next     uint64
+	span     uint64
+	interval time.Duration
+}
+
+// run polls until the context is cancelled or a call fails with an error
+// that shrinking the range can't fix.
+func (p *poller) run(ctx context.Context) error {
+	for {
+		header, err := p.w.backend.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("head block: %w", err)
+		}
+
+		caughtUp, err := p.step(ctx, header.Number.Uint64())
+		if err != nil {
+			return err
+		}
+
+		if !caughtUp {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(p.interval):
+		}
+	}
+}
+
+// step requests the logs for the next range of blocks up to the head and
+// adjusts the range and interval from the result. It reports whether the
+// head has been reached.
+func (p *poller) step(ctx context.Context, head uint64) (bool, error) {
+	cfg := p.w.cfg
+
+	// No new blocks, so back off.
+	if head < p.next {
+		p.interval = min(p.interval*2, cfg.MaxInterval)
+		return true, nil
+	}
+
+	to := min(p.next+p.span-1, head)
+
+	iter, err := p.w.filterer.FilterItemSet(&bind.FilterOpts{Start: p.next, End: &to, Context: ctx})
+	if err != nil {
+		if tooLarge(err) && p.span > 1 {
+			p.span = max(p.span/2, 1)
+			return false, nil
+		}
+		return false, fmt.Errorf("filter %d-%d: %w", p.next, to, err)
+	}
+
+	// The logs are fetched by FilterItemSet, so a failure here isn't the
+	// node rejecting the range and retrying would hand events on twice.
+	if err := p.send(ctx, iter); err != nil {
+		return false, fmt.Errorf("filter %d-%d: %w", p.next, to, err)
+	}
+
+	p.next = to + 1
+
+	// New blocks were found, so poll more often.
+	p.interval = max(p.interval/2, cfg.MinInterval)
+
+	if to < head {
+		p.span = min(p.span*2, cfg.MaxRange)
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// send hands the events in the iterator on in order.
+func (p *poller) send(ctx context.Context, iter *basic.BasicItemSetIterator) error {
+	defer iter.Close()
+
+	for iter.Next() {
+		if err := p.emit(ctx, iter.Event); err != nil {
+			return err
+		}
+	}
+
+	return iter.Error()
+}
+
+// tooLarge reports whether the node rejected an eth_getLogs call because it
+// covered too many blocks or returned too many logs. Providers also use
+// -32005 and "limit exceeded" for rate limits, which shrinking the range
+// doesn't help, so those are never treated as too large.
+func tooLarge(err error) bool {
+	msg := strings.ToLower(err.Error())
+
+	for _, s := range rateLimited {
+		if strings.Contains(msg, s) {
+			return false
+		}
+	}
+
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32005 {
+		return true
+	}
+
+	for _, s := range rangeRejected {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rangeRejected holds the messages nodes and providers use when an
+// eth_getLogs call covers too many blocks or returns too many logs.
+var rangeRejected = []string{
+	"query returned more than",
+	"block range",
+	"exceed maximum block range",
+	"log response size exceeded",
+	"response size exceeded",
+	"too many blocks",
+	"too many logs",
+	"is limited to a",
+}
+
+// rateLimited holds the messages providers use for rate limits.
+var rateLimited = []string{
+	"rate limit",
+	"request rate",
+	"too many requests",
+	"request limit",
+	"compute units",
+	"capacity",
+}
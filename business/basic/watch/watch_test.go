@@ -0,0 +1,305 @@
+package watch_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/watch"
+	geth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// httpBackend behaves like a node reached over HTTP that limits the block
+// range of eth_getLogs.
+type httpBackend struct {
+	bind.ContractBackend
+	maxRange uint64
+	rejected atomic.Int32
+}
+
+func (b *httpBackend) SubscribeFilterLogs(ctx context.Context, query geth.FilterQuery, ch chan<- types.Log) (geth.Subscription, error) {
+	return nil, rpc.ErrNotificationsUnsupported
+}
+
+func (b *httpBackend) FilterLogs(ctx context.Context, query geth.FilterQuery) ([]types.Log, error) {
+	if query.FromBlock != nil && query.ToBlock != nil {
+		if query.ToBlock.Uint64()-query.FromBlock.Uint64()+1 > b.maxRange {
+			b.rejected.Add(1)
+			return nil, errors.New("block range is too large")
+		}
+	}
+
+	return b.ContractBackend.FilterLogs(ctx, query)
+}
+
+// limitedBackend behaves like a node reached over HTTP that rate limits
+// every call to eth_getLogs.
+type limitedBackend struct {
+	bind.ContractBackend
+	calls atomic.Int32
+}
+
+func (b *limitedBackend) SubscribeFilterLogs(ctx context.Context, query geth.FilterQuery, ch chan<- types.Log) (geth.Subscription, error) {
+	return nil, rpc.ErrNotificationsUnsupported
+}
+
+func (b *limitedBackend) FilterLogs(ctx context.Context, query geth.FilterQuery) ([]types.Log, error) {
+	b.calls.Add(1)
+	return nil, errors.New("daily request limit exceeded")
+}
+
+// =============================================================================
+
+func TestPolling(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	clt, err := ethereum.NewClient(backend, backend.PrivateKeys[0])
+	if err != nil {
+		t.Fatalf("unable to create ethereum api: %s", err)
+	}
+
+	// =========================================================================
+
+	const gasLimit = 1600000
+	valueGwei := big.NewFloat(0.0)
+	gasPrice := currency.GWei2Wei(big.NewFloat(39.576))
+
+	transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+	if err != nil {
+		t.Fatalf("unable to create opts for deploy: %s", err)
+	}
+
+	contractID, tx, testBasic, err := basic.DeployBasic(transOpts, clt.Backend)
+	if err != nil {
+		t.Fatalf("unable to deploy basic: %s", err)
+	}
+
+	if _, err := clt.WaitMined(ctx, tx); err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+
+	setItem := func(key string, value int64) {
+		t.Helper()
+
+		transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+		if err != nil {
+			t.Fatalf("unable to create transaction opts for setItems: %s", err)
+		}
+
+		tx, err := testBasic.SetItem(transOpts, key, big.NewInt(value))
+		if err != nil {
+			t.Fatalf("should be able to set item: %s", err)
+		}
+
+		if _, err := clt.WaitMined(ctx, tx); err != nil {
+			t.Fatalf("waiting for set item: %s", err)
+		}
+	}
+
+	// =========================================================================
+
+	// Each write mines a block, so these land in blocks 2 through 7.
+	for i := range 6 {
+		setItem("bill", int64(i))
+	}
+
+	hb := httpBackend{ContractBackend: backend, maxRange: 2}
+
+	cfg := watch.Config{
+		MinInterval:  10 * time.Millisecond,
+		MaxInterval:  50 * time.Millisecond,
+		InitialRange: 8,
+		Log:          log.NewLogger(log.DiscardHandler()),
+	}
+
+	w, err := watch.New(&hb, contractID, cfg)
+	if err != nil {
+		t.Fatalf("unable to create watcher: %s", err)
+	}
+
+	sink := make(chan *basic.BasicItemSet, 10)
+
+	start := uint64(0)
+	sub, err := w.WatchItemSet(&bind.WatchOpts{Start: &start, Context: ctx}, sink)
+	if err != nil {
+		t.Fatalf("should fall back to polling: %s", err)
+	}
+	defer sub.Unsubscribe()
+
+	// The history is replayed in order even though the first ranges are
+	// rejected by the node.
+	for i := range 6 {
+		checkEvent(t, sink, sub, "bill", int64(i))
+	}
+
+	if hb.rejected.Load() == 0 {
+		t.Fatal("should have shrunk the range after a rejection")
+	}
+
+	// Range reads the same history in pages the node accepts.
+	var values []int64
+	err = w.Range(ctx, 0, nil, func(ev *basic.BasicItemSet) error {
+		values = append(values, ev.Value.Int64())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("should be able to range over the history: %s", err)
+	}
+
+	if len(values) != 6 || values[0] != 0 || values[5] != 5 {
+		t.Fatalf("wrong history, got %v", values)
+	}
+
+	setItem("jill", 10)
+	checkEvent(t, sink, sub, "jill", 10)
+
+	sub.Unsubscribe()
+
+	select {
+	case err := <-sub.Err():
+		if err != nil {
+			t.Fatalf("should unsubscribe cleanly: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for unsubscribe")
+	}
+
+	// =========================================================================
+
+	// Against a backend that supports subscriptions, forcing polling only
+	// sees events from after the watch started.
+	cfg.ForcePolling = true
+	w, err = watch.New(backend, contractID, cfg)
+	if err != nil {
+		t.Fatalf("unable to create watcher: %s", err)
+	}
+
+	sink = make(chan *basic.BasicItemSet, 10)
+
+	sub, err = w.WatchItemSet(&bind.WatchOpts{Context: ctx}, sink)
+	if err != nil {
+		t.Fatalf("should be able to poll: %s", err)
+	}
+	defer sub.Unsubscribe()
+
+	setItem("jill", 11)
+	checkEvent(t, sink, sub, "jill", 11)
+
+	// =========================================================================
+
+	// A rate limit fails the subscription rather than shrinking the range.
+	lb := limitedBackend{ContractBackend: backend}
+
+	w, err = watch.New(&lb, contractID, cfg)
+	if err != nil {
+		t.Fatalf("unable to create watcher: %s", err)
+	}
+
+	sub, err = w.WatchItemSet(&bind.WatchOpts{Start: &start, Context: ctx}, make(chan *basic.BasicItemSet, 10))
+	if err != nil {
+		t.Fatalf("should fall back to polling: %s", err)
+	}
+	defer sub.Unsubscribe()
+
+	select {
+	case err := <-sub.Err():
+		if err == nil {
+			t.Fatal("should fail the subscription on a rate limit")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the rate limit")
+	}
+
+	if lb.calls.Load() != 1 {
+		t.Fatalf("should not retry a rate limit with a smaller range, got %d calls", lb.calls.Load())
+	}
+
+	// =========================================================================
+
+	// Run starts the handler on each subscription and hands it every event.
+	cfg.ForcePolling = false
+	w, err = watch.New(backend, contractID, cfg)
+	if err != nil {
+		t.Fatalf("unable to create watcher: %s", err)
+	}
+
+	runCtx, runCancel := context.WithCancel(ctx)
+
+	started := make(chan struct{})
+	events := make(chan *basic.BasicItemSet, 10)
+
+	h := watch.Handler{
+		Start: func(ctx context.Context) error {
+			close(started)
+			return nil
+		},
+		Event: func(ctx context.Context, ev *basic.BasicItemSet) error {
+			events <- ev
+			return nil
+		},
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(runCtx, h)
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the handler to start")
+	}
+
+	setItem("jack", 12)
+
+	select {
+	case ev := <-events:
+		if ev.Key != "jack" || ev.Value.Int64() != 12 {
+			t.Fatalf("wrong event, got %s=%d", ev.Key, ev.Value)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the event")
+	}
+
+	runCancel()
+
+	if err := <-done; err != nil {
+		t.Fatalf("run should stop cleanly: %s", err)
+	}
+}
+
+// =============================================================================
+
+func checkEvent(t *testing.T, sink <-chan *basic.BasicItemSet, sub interface{ Err() <-chan error }, key string, value int64) {
+	t.Helper()
+
+	select {
+	case ev := <-sink:
+		if ev.Key != key || ev.Value.Int64() != value {
+			t.Fatalf("wrong event, got %s=%d exp %s=%d", ev.Key, ev.Value, key, value)
+		}
+
+	case err := <-sub.Err():
+		t.Fatalf("subscription failed: %v", err)
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s=%d", key, value)
+	}
+}
@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// hookRequest represents the body for registering or removing a URL.
+type hookRequest struct {
+	URL string `json:"url"`
+}
+
+// Handler returns the HTTP handler exposing delivery status and hook
+// registration.
+//
+//	GET    /hooks        registered urls
+//	POST   /hooks        register {"url": "..."}
+//	DELETE /hooks        unregister {"url": "..."}
+//	GET    /deliveries   deliveries in progress and recently completed
+//	GET    /deadletters  deliveries that failed every attempt
+func (d *Dispatcher) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /hooks", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, d.Hooks())
+	})
+
+	mux.HandleFunc("POST /hooks", func(w http.ResponseWriter, r *http.Request) {
+		var req hookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respond(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		if err := d.Register(req.URL); err != nil {
+			respond(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		respond(w, http.StatusCreated, d.Hooks())
+	})
+
+	mux.HandleFunc("DELETE /hooks", func(w http.ResponseWriter, r *http.Request) {
+		var req hookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respond(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		d.Unregister(req.URL)
+
+		respond(w, http.StatusOK, d.Hooks())
+	})
+
+	mux.HandleFunc("GET /deliveries", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, d.Deliveries())
+	})
+
+	mux.HandleFunc("GET /deadletters", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, d.DeadLetters())
+	})
+
+	return mux
+}
+
+// respond writes the value as a JSON response.
+func respond(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
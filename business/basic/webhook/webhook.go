@@ -0,0 +1,600 @@
+// Package webhook provides support for delivering the ItemSet events emitted
+// by the basic contract to HTTP endpoints as signed JSON payloads.
+//
+// Each request carries an envelope holding the payload and a signature
+// produced with ethereum.SignAny. A receiver written in Go can decode the
+// payload into a Payload value and recover the signing address with
+// ethereum.FromAddressAny. Other receivers can hash the raw payload bytes
+// the same way SignBytes does.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/watch"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Set of delivery states.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// Set of headers added to each request.
+const (
+	HeaderSignature = "X-Basic-Signature"
+	HeaderSigner    = "X-Basic-Signer"
+	HeaderDelivery  = "X-Basic-Delivery"
+)
+
+// Config represents the settings for the dispatcher.
+type Config struct {
+
+	// MaxAttempts is the number of times a delivery is attempted before it
+	// is dead lettered. A value of zero defaults to 5.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry. Each subsequent retry
+	// doubles the delay. A value of zero defaults to 1 second.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between retries. A value of zero defaults to
+	// 1 minute.
+	MaxDelay time.Duration
+
+	// Timeout bounds a single request. A value of zero defaults to 10
+	// seconds.
+	Timeout time.Duration
+
+	// History is the number of completed deliveries kept for status
+	// reporting. A value of zero defaults to 1000.
+	History int
+
+	// DeadLetters is the number of dead letters kept in memory, dropping
+	// the oldest first. A value of zero defaults to 1000.
+	DeadLetters int
+
+	// DeadLetterFile, when set, receives a JSON line for every delivery
+	// that is dead lettered so it can be replayed later. The file keeps
+	// every dead letter, including the ones dropped from memory.
+	DeadLetterFile string
+
+	// Log receives delivery errors. When nil, the root logger is used.
+	Log log.Logger
+}
+
+// Payload represents the event data that is signed and delivered.
+type Payload struct {
+	ID        string `json:"id"`
+	Event     string `json:"event"`
+	Contract  string `json:"contract"`
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	Block     uint64 `json:"block"`
+	BlockHash string `json:"block_hash"`
+	TxHash    string `json:"tx_hash"`
+	LogIndex  uint   `json:"log_index"`
+	Removed   bool   `json:"removed"`
+}
+
+// Envelope represents the body of each request.
+type Envelope struct {
+	Payload   json.RawMessage `json:"payload"`
+	Signature string          `json:"signature"`
+	Signer    string          `json:"signer"`
+}
+
+// Delivery represents the state of delivering a payload to a single URL.
+type Delivery struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	PayloadID  string    `json:"payload_id"`
+	Status     string    `json:"status"`
+	Attempts   int       `json:"attempts"`
+	StatusCode int       `json:"status_code,omitempty"`
+	LastError  string    `json:"last_error,omitempty"`
+	Created    time.Time `json:"created"`
+	Updated    time.Time `json:"updated"`
+	Envelope   Envelope  `json:"envelope"`
+}
+
+// =============================================================================
+
+// Dispatcher watches for ItemSet events and delivers them to the registered
+// URLs.
+type Dispatcher struct {
+	contractID common.Address
+	watcher    *watch.Watcher
+	privateKey *ecdsa.PrivateKey
+	signer     string
+	cfg        Config
+	client     *http.Client
+
+	mu         sync.Mutex
+	hooks      map[string]struct{}
+	deliveries map[string]*Delivery
+	completed  []string
+	dead       []Delivery
+	seq        uint64
+
+	// last is the position of the last event dispatched, which is where
+	// the backfill starts after the subscription drops.
+	last position
+
+	wg sync.WaitGroup
+}
+
+// New constructs a dispatcher for the basic contract at the specified
+// address. Payloads are signed with the private key.
+func New(backend bind.ContractBackend, contractID common.Address, privateKey *ecdsa.PrivateKey, cfg Config) (*Dispatcher, error) {
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = 5
+	}
+
+	if cfg.BaseDelay == 0 {
+		cfg.BaseDelay = time.Second
+	}
+
+	if cfg.MaxDelay == 0 {
+		cfg.MaxDelay = time.Minute
+	}
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	if cfg.History == 0 {
+		cfg.History = 1000
+	}
+
+	if cfg.DeadLetters == 0 {
+		cfg.DeadLetters = 1000
+	}
+
+	if cfg.Log == nil {
+		cfg.Log = log.Root()
+	}
+
+	watcher, err := watch.New(backend, contractID, watch.Config{Log: cfg.Log})
+	if err != nil {
+		return nil, fmt.Errorf("new watcher: %w", err)
+	}
+
+	d := Dispatcher{
+		contractID: contractID,
+		watcher:    watcher,
+		privateKey: privateKey,
+		signer:     crypto.PubkeyToAddress(privateKey.PublicKey).Hex(),
+		cfg:        cfg,
+		client:     &http.Client{Timeout: cfg.Timeout},
+		hooks:      make(map[string]struct{}),
+		deliveries: make(map[string]*Delivery),
+	}
+
+	return &d, nil
+}
+
+// Register adds a URL that receives every event from now on.
+func (d *Dispatcher) Register(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("parse url: %w", err)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url %q must be an absolute http or https url", rawURL)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.hooks[u.String()] = struct{}{}
+
+	return nil
+}
+
+// Unregister removes a URL. Deliveries already in progress continue.
+func (d *Dispatcher) Unregister(rawURL string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.hooks, rawURL)
+}
+
+// Hooks returns the registered URLs.
+func (d *Dispatcher) Hooks() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	hooks := make([]string, 0, len(d.hooks))
+	for hook := range d.hooks {
+		hooks = append(hooks, hook)
+	}
+	sort.Strings(hooks)
+
+	return hooks
+}
+
+// Run watches for ItemSet events and dispatches them until the context is
+// cancelled. When the subscription drops, the events mined since the last
+// one dispatched are backfilled once a new subscription is established. It
+// waits for deliveries in progress to finish before returning. Deliveries
+// still waiting to retry when the context is cancelled are dead lettered.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	defer d.wg.Wait()
+
+	h := watch.Handler{
+		Start: d.backfill,
+		Event: func(ctx context.Context, ev *basic.BasicItemSet) error {
+			d.dispatch(ctx, ev)
+			return nil
+		},
+	}
+
+	return d.watcher.Run(ctx, h)
+}
+
+// Dispatch signs the event and starts delivering it to every registered URL.
+// Deliveries stop retrying when the context is cancelled.
+func (d *Dispatcher) Dispatch(ctx context.Context, ev *basic.BasicItemSet) error {
+	id := fmt.Sprintf("%s-%d", ev.Raw.TxHash.Hex(), ev.Raw.Index)
+	if ev.Raw.Removed {
+		id = fmt.Sprintf("%s-%s-removed", id, ev.Raw.BlockHash.Hex())
+	}
+
+	payload := Payload{
+		ID:        id,
+		Event:     "ItemSet",
+		Contract:  d.contractID.Hex(),
+		Key:       ev.Key,
+		Value:     ev.Value.String(),
+		Block:     ev.Raw.BlockNumber,
+		BlockHash: ev.Raw.BlockHash.Hex(),
+		TxHash:    ev.Raw.TxHash.Hex(),
+		LogIndex:  ev.Raw.Index,
+		Removed:   ev.Raw.Removed,
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode payload: %w", err)
+	}
+
+	signature, err := ethereum.SignAny(payload, d.privateKey)
+	if err != nil {
+		return fmt.Errorf("sign payload: %w", err)
+	}
+
+	env := Envelope{
+		Payload:   data,
+		Signature: signature,
+		Signer:    d.signer,
+	}
+
+	for _, hook := range d.Hooks() {
+		dlv := d.newDelivery(hook, payload.ID, env)
+
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.deliver(ctx, dlv)
+		}()
+	}
+
+	return nil
+}
+
+// Deliveries returns the deliveries in progress and the most recently
+// completed ones, newest first.
+func (d *Dispatcher) Deliveries() []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	deliveries := make([]Delivery, 0, len(d.deliveries))
+	for _, dlv := range d.deliveries {
+		deliveries = append(deliveries, *dlv)
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].Created.After(deliveries[j].Created)
+	})
+
+	return deliveries
+}
+
+// DeadLetters returns the deliveries that failed every attempt, oldest
+// first.
+func (d *Dispatcher) DeadLetters() []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]Delivery(nil), d.dead...)
+}
+
+// =============================================================================
+
+// position represents where an event sits in the chain.
+type position struct {
+	block uint64
+	index uint
+}
+
+// after reports whether the event comes after the position.
+func (p position) after(ev *basic.BasicItemSet) bool {
+	if ev.Raw.BlockNumber != p.block {
+		return ev.Raw.BlockNumber > p.block
+	}
+
+	return ev.Raw.Index > p.index
+}
+
+// backfill dispatches the events mined after the last one dispatched. It
+// does nothing until an event has been dispatched, since there is no
+// position to start from.
+func (d *Dispatcher) backfill(ctx context.Context) error {
+	d.mu.Lock()
+	last := d.last
+	d.mu.Unlock()
+
+	if last == (position{}) {
+		return nil
+	}
+
+	err := d.watcher.Range(ctx, last.block, nil, func(ev *basic.BasicItemSet) error {
+		d.dispatch(ctx, ev)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("backfill from block %d: %w", last.block, err)
+	}
+
+	return nil
+}
+
+// dispatch dispatches the event and moves the position forward. Events at
+// or before the position were already dispatched, by the backfill or the
+// previous subscription, and are skipped. Removed events are always
+// dispatched since they undo an event dispatched earlier.
+func (d *Dispatcher) dispatch(ctx context.Context, ev *basic.BasicItemSet) {
+	d.mu.Lock()
+	last := d.last
+	d.mu.Unlock()
+
+	if !ev.Raw.Removed && !last.after(ev) {
+		return
+	}
+
+	if err := d.Dispatch(ctx, ev); err != nil {
+		d.cfg.Log.Error("webhook", "status", "dispatch", "tx", ev.Raw.TxHash, "err", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch {
+	case !ev.Raw.Removed:
+		d.last = position{block: ev.Raw.BlockNumber, index: ev.Raw.Index}
+
+	// The replacement chain can put new events at the same position, so
+	// move back to the end of the block before the removed one.
+	case !d.last.after(ev) && ev.Raw.BlockNumber > 0:
+		d.last = position{block: ev.Raw.BlockNumber - 1, index: ^uint(0)}
+	}
+}
+
+// newDelivery records a pending delivery.
+func (d *Dispatcher) newDelivery(hook string, payloadID string, env Envelope) *Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.seq++
+	now := time.Now().UTC()
+
+	dlv := Delivery{
+		ID:        fmt.Sprintf("%d", d.seq),
+		URL:       hook,
+		PayloadID: payloadID,
+		Status:    StatusPending,
+		Created:   now,
+		Updated:   now,
+		Envelope:  env,
+	}
+	d.deliveries[dlv.ID] = &dlv
+
+	return &dlv
+}
+
+// deliver attempts the delivery until it succeeds, fails permanently or runs
+// out of attempts.
+func (d *Dispatcher) deliver(ctx context.Context, dlv *Delivery) {
+	body, err := json.Marshal(dlv.Envelope)
+	if err != nil {
+		d.finish(dlv, StatusFailed, 0, err)
+		return
+	}
+
+	for attempt := 1; ; attempt++ {
+		code, err := d.post(ctx, dlv, body)
+
+		d.mu.Lock()
+		dlv.Attempts = attempt
+		dlv.StatusCode = code
+		dlv.Updated = time.Now().UTC()
+		if err != nil {
+			dlv.LastError = err.Error()
+		}
+		d.mu.Unlock()
+
+		if err == nil {
+			d.finish(dlv, StatusDelivered, code, nil)
+			return
+		}
+
+		if !retryable(code) || attempt >= d.cfg.MaxAttempts {
+			d.finish(dlv, StatusFailed, code, err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			d.finish(dlv, StatusFailed, code, fmt.Errorf("shutdown before retry: %w", err))
+			return
+		case <-time.After(d.backoff(attempt)):
+		}
+	}
+}
+
+// post sends a single request and returns the status code of the response.
+func (d *Dispatcher) post(ctx context.Context, dlv *Delivery, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dlv.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderSignature, dlv.Envelope.Signature)
+	req.Header.Set(HeaderSigner, dlv.Envelope.Signer)
+	req.Header.Set(HeaderDelivery, dlv.ID)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver returned %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// finish records the final state of the delivery, dead lettering it if it
+// failed.
+func (d *Dispatcher) finish(dlv *Delivery, status string, code int, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	dlv.Status = status
+	dlv.StatusCode = code
+	dlv.Updated = time.Now().UTC()
+	if err != nil {
+		dlv.LastError = err.Error()
+	}
+
+	d.completed = append(d.completed, dlv.ID)
+	for len(d.completed) > d.cfg.History {
+		delete(d.deliveries, d.completed[0])
+		d.completed = d.completed[1:]
+	}
+
+	if status != StatusFailed {
+		return
+	}
+
+	d.dead = append(d.dead, *dlv)
+	if len(d.dead) > d.cfg.DeadLetters {
+		d.dead = d.dead[len(d.dead)-d.cfg.DeadLetters:]
+	}
+	d.cfg.Log.Error("webhook", "status", "dead letter", "url", dlv.URL, "payload", dlv.PayloadID, "attempts", dlv.Attempts, "err", dlv.LastError)
+
+	if d.cfg.DeadLetterFile != "" {
+		if err := appendJSON(d.cfg.DeadLetterFile, dlv); err != nil {
+			d.cfg.Log.Error("webhook", "status", "dead letter file", "err", err)
+		}
+	}
+}
+
+// backoff returns the delay before the retry following the attempt using
+// exponential backoff with full jitter.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.cfg.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > d.cfg.MaxDelay {
+		delay = d.cfg.MaxDelay
+	}
+
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// retryable reports whether a response with the status code is worth
+// retrying. A zero code means the request never got a response.
+func retryable(code int) bool {
+	switch {
+	case code == 0:
+		return true
+	case code == http.StatusRequestTimeout, code == http.StatusTooManyRequests:
+		return true
+	case code >= 500:
+		return true
+	}
+
+	return false
+}
+
+// appendJSON writes the value as a single JSON line at the end of the file.
+func appendJSON(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// =============================================================================
+
+// Verify checks the envelope's signature and returns the decoded payload
+// and the address that signed it.
+func Verify(env Envelope) (Payload, string, error) {
+	var payload Payload
+	if err := json.Unmarshal(env.Payload, &payload); err != nil {
+		return Payload{}, "", fmt.Errorf("decode payload: %w", err)
+	}
+
+	address, err := ethereum.FromAddressAny(payload, env.Signature)
+	if err != nil {
+		return Payload{}, "", fmt.Errorf("recover signer: %w", err)
+	}
+
+	if env.Signer != "" && !common.IsHexAddress(env.Signer) {
+		return Payload{}, "", fmt.Errorf("invalid signer %q", env.Signer)
+	}
+
+	if env.Signer != "" && common.HexToAddress(env.Signer) != common.HexToAddress(address) {
+		return Payload{}, "", errors.New("signature does not match signer")
+	}
+
+	return payload, address, nil
+}
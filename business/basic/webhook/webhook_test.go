@@ -0,0 +1,273 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/business/basic/webhook"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func TestDispatch(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	clt, err := ethereum.NewClient(backend, backend.PrivateKeys[0])
+	if err != nil {
+		t.Fatalf("unable to create ethereum api: %s", err)
+	}
+
+	// =========================================================================
+
+	const gasLimit = 1600000
+	valueGwei := big.NewFloat(0.0)
+	gasPrice := currency.GWei2Wei(big.NewFloat(39.576))
+
+	transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+	if err != nil {
+		t.Fatalf("unable to create opts for deploy: %s", err)
+	}
+
+	contractID, tx, testBasic, err := basic.DeployBasic(transOpts, clt.Backend)
+	if err != nil {
+		t.Fatalf("unable to deploy basic: %s", err)
+	}
+
+	if _, err := clt.WaitMined(ctx, tx); err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+
+	transOpts, err = clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+	if err != nil {
+		t.Fatalf("unable to create transaction opts for setItems: %s", err)
+	}
+
+	tx, err = testBasic.SetItem(transOpts, "bill", big.NewInt(1_000_000))
+	if err != nil {
+		t.Fatalf("should be able to set item: %s", err)
+	}
+
+	if _, err := clt.WaitMined(ctx, tx); err != nil {
+		t.Fatalf("waiting for set item: %s", err)
+	}
+
+	iter, err := testBasic.FilterItemSet(&bind.FilterOpts{Context: ctx})
+	if err != nil {
+		t.Fatalf("should be able to filter events: %s", err)
+	}
+	defer iter.Close()
+
+	if !iter.Next() {
+		t.Fatal("should find the ItemSet event")
+	}
+	ev := iter.Event
+
+	// =========================================================================
+
+	var mu sync.Mutex
+	var received []webhook.Envelope
+
+	// flaky fails twice before accepting the delivery.
+	var flakyCalls atomic.Int32
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if flakyCalls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var env webhook.Envelope
+		if err := json.NewDecoder(r.Body).Decode(&env); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		received = append(received, env)
+		mu.Unlock()
+	}))
+	defer flaky.Close()
+
+	// rejecting refuses the delivery, which is not worth retrying.
+	var rejectingCalls atomic.Int32
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rejectingCalls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer rejecting.Close()
+
+	// broken never recovers.
+	var brokenCalls atomic.Int32
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		brokenCalls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	// =========================================================================
+
+	cfg := webhook.Config{
+		MaxAttempts:    3,
+		BaseDelay:      10 * time.Millisecond,
+		MaxDelay:       20 * time.Millisecond,
+		DeadLetterFile: filepath.Join(t.TempDir(), "dead.jsonl"),
+		Log:            log.NewLogger(log.DiscardHandler()),
+	}
+
+	d, err := webhook.New(backend, contractID, backend.PrivateKeys[0], cfg)
+	if err != nil {
+		t.Fatalf("unable to create dispatcher: %s", err)
+	}
+
+	for _, url := range []string{flaky.URL, rejecting.URL, broken.URL} {
+		if err := d.Register(url); err != nil {
+			t.Fatalf("should be able to register %s: %s", url, err)
+		}
+	}
+
+	if err := d.Register("ftp://example.com"); err == nil {
+		t.Fatal("should not register a non http url")
+	}
+
+	if err := d.Dispatch(ctx, ev); err != nil {
+		t.Fatalf("should be able to dispatch: %s", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		pending := 0
+		for _, dlv := range d.Deliveries() {
+			if dlv.Status == webhook.StatusPending {
+				pending++
+			}
+		}
+
+		if pending == 0 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for deliveries")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// =========================================================================
+
+	if len(received) != 1 {
+		t.Fatalf("flaky receiver should get one delivery, got %d", len(received))
+	}
+
+	payload, signer, err := webhook.Verify(received[0])
+	if err != nil {
+		t.Fatalf("should be able to verify the delivery: %s", err)
+	}
+
+	if signer != clt.Address().Hex() {
+		t.Fatalf("wrong signer, got %s exp %s", signer, clt.Address().Hex())
+	}
+
+	if payload.Key != "bill" || payload.Value != "1000000" || payload.TxHash != tx.Hash().Hex() {
+		t.Fatalf("wrong payload, got %+v", payload)
+	}
+
+	address, err := ethereum.FromAddressBytes(received[0].Payload, received[0].Signature)
+	if err != nil || address != signer {
+		t.Fatalf("raw payload should verify, got %s, %v", address, err)
+	}
+
+	if flakyCalls.Load() != 3 || rejectingCalls.Load() != 1 || brokenCalls.Load() != 3 {
+		t.Fatalf("wrong attempts, got flaky %d rejecting %d broken %d", flakyCalls.Load(), rejectingCalls.Load(), brokenCalls.Load())
+	}
+
+	dead := d.DeadLetters()
+	if len(dead) != 2 {
+		t.Fatalf("should dead letter two deliveries, got %d", len(dead))
+	}
+
+	for _, dlv := range dead {
+		if dlv.Status != webhook.StatusFailed || dlv.LastError == "" {
+			t.Fatalf("wrong dead letter, got %+v", dlv)
+		}
+	}
+
+	// =========================================================================
+
+	srv := httptest.NewServer(d.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/deadletters")
+	if err != nil {
+		t.Fatalf("should be able to get dead letters: %s", err)
+	}
+	defer resp.Body.Close()
+
+	var listed []webhook.Delivery
+	if err := json.NewDecoder(resp.Body).Decode(&listed); err != nil {
+		t.Fatalf("should be able to decode dead letters: %s", err)
+	}
+
+	if len(listed) != 2 {
+		t.Fatalf("wrong number of dead letters listed, got %d", len(listed))
+	}
+
+	// =========================================================================
+
+	for _, url := range d.Hooks() {
+		d.Unregister(url)
+	}
+
+	removals := make(chan webhook.Envelope, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var env webhook.Envelope
+		if err := json.NewDecoder(r.Body).Decode(&env); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		removals <- env
+	}))
+	defer collector.Close()
+
+	if err := d.Register(collector.URL); err != nil {
+		t.Fatalf("should be able to register the collector: %s", err)
+	}
+
+	removed := *ev
+	removed.Raw.Removed = true
+
+	if err := d.Dispatch(ctx, &removed); err != nil {
+		t.Fatalf("should be able to dispatch the removal: %s", err)
+	}
+
+	var env webhook.Envelope
+	select {
+	case env = <-removals:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the removal")
+	}
+
+	removal, _, err := webhook.Verify(env)
+	if err != nil {
+		t.Fatalf("should be able to verify the removal: %s", err)
+	}
+
+	if !removal.Removed || removal.ID == payload.ID {
+		t.Fatalf("removal should have its own id, got %q for %q", removal.ID, payload.ID)
+	}
+}
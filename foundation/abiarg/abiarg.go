@@ -0,0 +1,111 @@
+// Package abiarg converts arguments given as text, on a command line or in a
+// request file, into the Go values the abi package packs for a method's
+// inputs.
+package abiarg
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ParseArgs converts the arguments into the values for the inputs. The
+// number of arguments must match the number of inputs.
+func ParseArgs(inputs abi.Arguments, args []string) ([]any, error) {
+	if len(args) != len(inputs) {
+		return nil, fmt.Errorf("got %d args, expected %d", len(args), len(inputs))
+	}
+
+	values := make([]any, len(args))
+	for i, input := range inputs {
+		v, err := Parse(input.Type, args[i])
+		if err != nil {
+			return nil, fmt.Errorf("arg %q: %w", input.Name, err)
+		}
+		values[i] = v
+	}
+
+	return values, nil
+}
+
+// Parse converts a single argument into the value for the type. Integers
+// can be decimal or 0x prefixed hex and must fit the type, so a negative
+// value is refused for an unsigned type rather than wrapping around.
+func Parse(typ abi.Type, arg string) (any, error) {
+	switch typ.T {
+	case abi.StringTy:
+		return arg, nil
+
+	case abi.BoolTy:
+		b, err := strconv.ParseBool(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a bool", arg)
+		}
+		return b, nil
+
+	case abi.AddressTy:
+		if !common.IsHexAddress(arg) {
+			return nil, fmt.Errorf("%q is not an address", arg)
+		}
+		return common.HexToAddress(arg), nil
+
+	case abi.BytesTy:
+		b, err := hexutil.Decode(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not hex encoded bytes: %w", arg, err)
+		}
+		return b, nil
+
+	case abi.UintTy, abi.IntTy:
+		return parseInt(typ, arg)
+	}
+
+	return nil, fmt.Errorf("arguments of type %s are not supported", typ)
+}
+
+// parseInt converts an integer argument, checking it fits the type.
+func parseInt(typ abi.Type, arg string) (any, error) {
+	n, ok := new(big.Int).SetString(arg, 0)
+	if !ok {
+		return nil, fmt.Errorf("%q is not an integer", arg)
+	}
+
+	if !fits(typ, n) {
+		return nil, fmt.Errorf("%q is out of range for %s", arg, typ)
+	}
+
+	goType := typ.GetType()
+	if goType == reflect.TypeOf(n) {
+		return n, nil
+	}
+
+	v := reflect.New(goType).Elem()
+	switch typ.T {
+	case abi.UintTy:
+		v.SetUint(n.Uint64())
+	default:
+		v.SetInt(n.Int64())
+	}
+
+	return v.Interface(), nil
+}
+
+// fits reports whether the integer can be represented by the type.
+func fits(typ abi.Type, n *big.Int) bool {
+	if typ.T == abi.UintTy {
+		return n.Sign() >= 0 && n.BitLen() <= typ.Size
+	}
+
+	// A signed integer of size bits holds -2^(size-1) to 2^(size-1)-1.
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(typ.Size-1))
+	if n.Sign() < 0 {
+		return n.CmpAbs(limit) <= 0
+	}
+
+	return n.Cmp(limit) < 0
+}
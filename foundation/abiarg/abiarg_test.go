@@ -0,0 +1,65 @@
+package abiarg_test
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ardanlabs/smartcontract/foundation/abiarg"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+func TestParse(t *testing.T) {
+	maxUint256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+	tests := []struct {
+		typ string
+		arg string
+		exp any
+	}{
+		{"uint256", "42", big.NewInt(42)},
+		{"uint256", "0x2a", big.NewInt(42)},
+		{"uint256", maxUint256.String(), maxUint256},
+		{"uint256", "-1", nil},
+		{"uint256", new(big.Int).Add(maxUint256, big.NewInt(1)).String(), nil},
+		{"int256", "-1", big.NewInt(-1)},
+		{"uint8", "255", uint8(255)},
+		{"uint8", "256", nil},
+		{"int8", "-128", int8(-128)},
+		{"int8", "128", nil},
+		{"bool", "true", true},
+		{"bool", "yes", nil},
+		{"address", "0x6327A38415C53FFb36c11db55Ea74cc9cB4976Fd", nil},
+		{"string", "bill", "bill"},
+	}
+
+	for _, tt := range tests {
+		typ, err := abi.NewType(tt.typ, "", nil)
+		if err != nil {
+			t.Fatalf("unable to create type %s: %s", tt.typ, err)
+		}
+
+		got, err := abiarg.Parse(typ, tt.arg)
+
+		switch {
+		case tt.typ == "address":
+			if err != nil {
+				t.Fatalf("should parse address %s: %s", tt.arg, err)
+			}
+
+		case tt.exp == nil:
+			if err == nil {
+				t.Fatalf("should refuse %s for %s, got %v", tt.arg, tt.typ, got)
+			}
+
+		default:
+			if err != nil {
+				t.Fatalf("should parse %s as %s: %s", tt.arg, tt.typ, err)
+			}
+
+			if fmt.Sprintf("%T %v", got, got) != fmt.Sprintf("%T %v", tt.exp, tt.exp) {
+				t.Fatalf("wrong value for %s as %s, got %T %v exp %T %v", tt.arg, tt.typ, got, got, tt.exp, tt.exp)
+			}
+		}
+	}
+}
@@ -0,0 +1,20 @@
+// Package backend provides the support shared by the ethereum.Backend
+// decorators in the packages below it.
+package backend
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ChainReader represents the block lookups a dialed node supports beyond
+// the ethereum.Backend interface. Decorators pass these calls through when
+// the backend they wrap implements them and return errors.ErrUnsupported
+// when it doesn't.
+type ChainReader interface {
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+}
@@ -0,0 +1,629 @@
+// Package failover provides an ethereum.Backend implementation that spreads
+// work across several nodes on the same chain and fails over between them
+// when a node stops responding.
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/smartcontract/foundation/backend"
+	geth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Set of errors returned by the failover backend.
+var (
+	ErrNoEndpoints        = errors.New("no endpoints provided")
+	ErrNoHealthyEndpoint  = errors.New("no healthy endpoint available")
+	ErrChainIDMismatch    = errors.New("endpoint reports a different chain id")
+	ErrChainIDUnavailable = errors.New("unable to determine chain id from any endpoint")
+)
+
+// Config represents the settings for the failover backend.
+type Config struct {
+
+	// MaxBlockLag is the number of blocks an endpoint can fall behind the
+	// best known head before it is considered unhealthy.
+	MaxBlockLag uint64
+
+	// CheckInterval is how often the endpoints are health checked in the
+	// background. A value of zero disables background checks.
+	CheckInterval time.Duration
+
+	// CheckTimeout bounds the time a single endpoint has to answer a health
+	// check. A value of zero defaults to 5 seconds.
+	CheckTimeout time.Duration
+}
+
+// Status represents the health information for a single endpoint.
+type Status struct {
+	Network string
+	Healthy bool
+	Head    uint64
+	Checked time.Time
+	Err     error
+}
+
+// endpoint maintains the state of a single node behind the backend.
+type endpoint struct {
+	network string
+	backend ethereum.Backend
+	healthy bool
+	head    uint64
+	checked time.Time
+	err     error
+}
+
+// =============================================================================
+
+// Backend implements the ethereum.Backend interface on top of a set of nodes
+// that all report the same chain id. Calls are routed to the current primary
+// endpoint and fail over to the next healthy endpoint when a transport level
+// error occurs. Errors reported by a node, like an execution revert, are
+// returned to the caller as is.
+type Backend struct {
+	cfg       Config
+	chainID   *big.Int
+	dial      bool
+	mu        sync.RWMutex
+	endpoints []*endpoint
+	primary   int
+	shutdown  chan struct{}
+	wg        sync.WaitGroup
+}
+
+// CreateBackend dials each of the specified networks and constructs a backend
+// that fails over between them. Networks that can't be dialed are retried
+// during each health check.
+func CreateBackend(ctx context.Context, cfg Config, networks ...string) (*Backend, error) {
+	if len(networks) == 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	endpoints := make([]*endpoint, len(networks))
+	for i, network := range networks {
+		ep := endpoint{
+			network: network,
+		}
+
+		backend, err := ethereum.CreateDialedBackend(ctx, network)
+		switch {
+		case err != nil:
+			ep.err = fmt.Errorf("dial: %w", err)
+		default:
+			ep.backend = backend
+		}
+
+		endpoints[i] = &ep
+	}
+
+	return newBackend(ctx, cfg, endpoints, true)
+}
+
+// NewBackend constructs a backend that fails over between the specified
+// backends, which must already be connected.
+func NewBackend(ctx context.Context, cfg Config, backends ...ethereum.Backend) (*Backend, error) {
+	if len(backends) == 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	endpoints := make([]*endpoint, len(backends))
+	for i, backend := range backends {
+		endpoints[i] = &endpoint{
+			network: backend.Network(),
+			backend: backend,
+		}
+	}
+
+	return newBackend(ctx, cfg, endpoints, false)
+}
+
+// newBackend establishes the chain id for the set of endpoints, performs the
+// first health check, and starts the background monitor.
+func newBackend(ctx context.Context, cfg Config, endpoints []*endpoint, dial bool) (*Backend, error) {
+	if cfg.CheckTimeout == 0 {
+		cfg.CheckTimeout = 5 * time.Second
+	}
+
+	b := Backend{
+		cfg:       cfg,
+		dial:      dial,
+		endpoints: endpoints,
+		shutdown:  make(chan struct{}),
+	}
+
+	// Only close the endpoints on failure when this package dialed them.
+	fail := func(err error) (*Backend, error) {
+		if dial {
+			b.closeEndpoints()
+		}
+		return nil, err
+	}
+
+	for _, ep := range endpoints {
+		if ep.backend == nil {
+			continue
+		}
+
+		chainID, err := b.queryChainID(ctx, ep.backend)
+		if err != nil {
+			ep.err = fmt.Errorf("chain id: %w", err)
+			continue
+		}
+
+		switch {
+		case b.chainID == nil:
+			b.chainID = chainID
+		case b.chainID.Cmp(chainID) != 0:
+			return fail(fmt.Errorf("%w: %s reports %d, expected %d", ErrChainIDMismatch, ep.network, chainID, b.chainID))
+		}
+	}
+
+	if b.chainID == nil {
+		return fail(ErrChainIDUnavailable)
+	}
+
+	if err := b.Check(ctx); err != nil {
+		return fail(err)
+	}
+
+	if cfg.CheckInterval > 0 {
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			b.monitor()
+		}()
+	}
+
+	return &b, nil
+}
+
+// Close stops the background health checks and closes every endpoint.
+func (b *Backend) Close() {
+	close(b.shutdown)
+	b.wg.Wait()
+
+	b.closeEndpoints()
+}
+
+// Network returns the network of the current primary endpoint.
+func (b *Backend) Network() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.endpoints[b.primary].network
+}
+
+// ChainID returns the chain id shared by every endpoint.
+func (b *Backend) ChainID() *big.Int {
+	return new(big.Int).Set(b.chainID)
+}
+
+// Status returns the health information for every endpoint.
+func (b *Backend) Status() []Status {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	status := make([]Status, len(b.endpoints))
+	for i, ep := range b.endpoints {
+		status[i] = Status{
+			Network: ep.network,
+			Healthy: ep.healthy,
+			Head:    ep.head,
+			Checked: ep.checked,
+			Err:     ep.err,
+		}
+	}
+
+	return status
+}
+
+// Check health checks every endpoint. An endpoint is healthy when it reports
+// the expected chain id and its head block is within MaxBlockLag of the best
+// head across all endpoints. An error is returned if no endpoint is healthy.
+func (b *Backend) Check(ctx context.Context) error {
+	type result struct {
+		backend ethereum.Backend
+		head    uint64
+		err     error
+	}
+
+	b.mu.RLock()
+	results := make([]result, len(b.endpoints))
+	for i, ep := range b.endpoints {
+		results[i].backend = ep.backend
+	}
+	b.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(r *result, network string) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, b.cfg.CheckTimeout)
+			defer cancel()
+
+			r.head, r.err = b.checkEndpoint(ctx, network, &r.backend)
+		}(&results[i], b.endpoints[i].network)
+	}
+	wg.Wait()
+
+	var best uint64
+	for _, r := range results {
+		if r.err == nil && r.head > best {
+			best = r.head
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	var healthy int
+	for i, ep := range b.endpoints {
+		r := results[i]
+
+		ep.backend = r.backend
+		ep.head = r.head
+		ep.checked = now
+		ep.err = r.err
+
+		switch {
+		case r.err != nil:
+			ep.healthy = false
+		case best-r.head > b.cfg.MaxBlockLag:
+			ep.healthy = false
+			ep.err = fmt.Errorf("head %d lags best head %d", r.head, best)
+		default:
+			ep.healthy = true
+			healthy++
+		}
+	}
+
+	if healthy == 0 {
+		return ErrNoHealthyEndpoint
+	}
+
+	if !b.endpoints[b.primary].healthy {
+		for i, ep := range b.endpoints {
+			if ep.healthy {
+				b.primary = i
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// CodeAt returns the code of the given account.
+func (b *Backend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return do(ctx, b, func(eb ethereum.Backend) ([]byte, error) {
+		return eb.CodeAt(ctx, contract, blockNumber)
+	})
+}
+
+// CallContract executes a contract call against the state at the specified
+// block number.
+func (b *Backend) CallContract(ctx context.Context, call geth.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return do(ctx, b, func(eb ethereum.Backend) ([]byte, error) {
+		return eb.CallContract(ctx, call, blockNumber)
+	})
+}
+
+// PendingCallContract executes a contract call against the pending state.
+func (b *Backend) PendingCallContract(ctx context.Context, call geth.CallMsg) ([]byte, error) {
+	return do(ctx, b, func(eb ethereum.Backend) ([]byte, error) {
+		pb, ok := eb.(bind.PendingContractCaller)
+		if !ok {
+			return nil, bind.ErrNoPendingState
+		}
+		return pb.PendingCallContract(ctx, call)
+	})
+}
+
+// HeaderByNumber returns a block header from the current canonical chain.
+func (b *Backend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return do(ctx, b, func(eb ethereum.Backend) (*types.Header, error) {
+		return eb.HeaderByNumber(ctx, number)
+	})
+}
+
+// HeaderByHash returns the block header with the given hash.
+func (b *Backend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return do(ctx, b, func(eb ethereum.Backend) (*types.Header, error) {
+		cr, ok := eb.(backend.ChainReader)
+		if !ok {
+			return nil, errors.ErrUnsupported
+		}
+		return cr.HeaderByHash(ctx, hash)
+	})
+}
+
+// BlockByNumber returns a block from the current canonical chain.
+func (b *Backend) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	return do(ctx, b, func(eb ethereum.Backend) (*types.Block, error) {
+		cr, ok := eb.(backend.ChainReader)
+		if !ok {
+			return nil, errors.ErrUnsupported
+		}
+		return cr.BlockByNumber(ctx, number)
+	})
+}
+
+// PendingCodeAt returns the code of the given account in the pending state.
+func (b *Backend) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return do(ctx, b, func(eb ethereum.Backend) ([]byte, error) {
+		return eb.PendingCodeAt(ctx, account)
+	})
+}
+
+// PendingNonceAt retrieves the current pending nonce associated with an account.
+func (b *Backend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return do(ctx, b, func(eb ethereum.Backend) (uint64, error) {
+		return eb.PendingNonceAt(ctx, account)
+	})
+}
+
+// SuggestGasPrice retrieves the currently suggested gas price.
+func (b *Backend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return do(ctx, b, func(eb ethereum.Backend) (*big.Int, error) {
+		return eb.SuggestGasPrice(ctx)
+	})
+}
+
+// SuggestGasTipCap retrieves the currently suggested gas tip cap.
+func (b *Backend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return do(ctx, b, func(eb ethereum.Backend) (*big.Int, error) {
+		return eb.SuggestGasTipCap(ctx)
+	})
+}
+
+// EstimateGas estimates the gas needed to execute the specified call.
+func (b *Backend) EstimateGas(ctx context.Context, call geth.CallMsg) (uint64, error) {
+	return do(ctx, b, func(eb ethereum.Backend) (uint64, error) {
+		return eb.EstimateGas(ctx, call)
+	})
+}
+
+// SendTransaction injects the signed transaction into the pending pool. If
+// the transaction reached a node before that node failed, the next endpoint
+// may already know about it, which is treated as success.
+func (b *Backend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	_, err := do(ctx, b, func(eb ethereum.Backend) (struct{}, error) {
+		err := eb.SendTransaction(ctx, tx)
+		if err != nil && strings.Contains(err.Error(), "already known") {
+			return struct{}{}, nil
+		}
+		return struct{}{}, err
+	})
+
+	return err
+}
+
+// FilterLogs executes a filter query.
+func (b *Backend) FilterLogs(ctx context.Context, query geth.FilterQuery) ([]types.Log, error) {
+	return do(ctx, b, func(eb ethereum.Backend) ([]types.Log, error) {
+		return eb.FilterLogs(ctx, query)
+	})
+}
+
+// SubscribeFilterLogs subscribes to the results of a streaming filter query.
+// The subscription stays with the endpoint that accepted it. When that
+// endpoint fails the subscription reports an error and the caller can
+// subscribe again to be routed to a healthy endpoint.
+func (b *Backend) SubscribeFilterLogs(ctx context.Context, query geth.FilterQuery, ch chan<- types.Log) (geth.Subscription, error) {
+	return do(ctx, b, func(eb ethereum.Backend) (geth.Subscription, error) {
+		return eb.SubscribeFilterLogs(ctx, query, ch)
+	})
+}
+
+// TransactionReceipt returns the receipt of a mined transaction.
+func (b *Backend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return do(ctx, b, func(eb ethereum.Backend) (*types.Receipt, error) {
+		return eb.TransactionReceipt(ctx, txHash)
+	})
+}
+
+// TransactionByHash returns the transaction with the given hash.
+func (b *Backend) TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+	type result struct {
+		tx        *types.Transaction
+		isPending bool
+	}
+
+	r, err := do(ctx, b, func(eb ethereum.Backend) (result, error) {
+		tx, isPending, err := eb.TransactionByHash(ctx, txHash)
+		return result{tx: tx, isPending: isPending}, err
+	})
+
+	return r.tx, r.isPending, err
+}
+
+// BalanceAt returns the wei balance of the given account.
+func (b *Backend) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return do(ctx, b, func(eb ethereum.Backend) (*big.Int, error) {
+		return eb.BalanceAt(ctx, account, blockNumber)
+	})
+}
+
+// =============================================================================
+
+// do executes the function against the primary endpoint and moves through
+// the remaining endpoints when a transport level error occurs. Healthy
+// endpoints are tried first, then the unhealthy ones as a last resort.
+func do[T any](ctx context.Context, b *Backend, fn func(ethereum.Backend) (T, error)) (T, error) {
+	var zero T
+	var lastErr error
+
+	for _, idx := range b.candidates() {
+		b.mu.RLock()
+		backend := b.endpoints[idx].backend
+		b.mu.RUnlock()
+
+		v, err := fn(backend)
+		if err == nil || !shouldFailover(ctx, err) {
+			if err == nil {
+				b.promote(idx)
+			}
+			return v, err
+		}
+
+		b.demote(idx, err)
+		lastErr = err
+	}
+
+	if lastErr == nil {
+		return zero, ErrNoHealthyEndpoint
+	}
+
+	return zero, fmt.Errorf("%w: %w", ErrNoHealthyEndpoint, lastErr)
+}
+
+// candidates returns the index of every usable endpoint starting with the
+// primary, followed by the other healthy endpoints and then the unhealthy
+// endpoints that reported the expected chain id.
+func (b *Backend) candidates() []int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	idxs := make([]int, 0, len(b.endpoints))
+	var unhealthy []int
+
+	for i := range b.endpoints {
+		idx := (b.primary + i) % len(b.endpoints)
+		ep := b.endpoints[idx]
+
+		switch {
+		case ep.backend == nil || errors.Is(ep.err, ErrChainIDMismatch):
+		case ep.healthy:
+			idxs = append(idxs, idx)
+		default:
+			unhealthy = append(unhealthy, idx)
+		}
+	}
+
+	return append(idxs, unhealthy...)
+}
+
+// promote makes the specified endpoint the primary endpoint.
+func (b *Backend) promote(idx int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.primary = idx
+	b.endpoints[idx].healthy = true
+}
+
+// demote marks the specified endpoint as unhealthy until the next health
+// check says otherwise.
+func (b *Backend) demote(idx int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.endpoints[idx].healthy = false
+	b.endpoints[idx].err = err
+}
+
+// checkEndpoint validates the chain id and captures the head block for the
+// endpoint. If the endpoint was never dialed, a dial is attempted first.
+func (b *Backend) checkEndpoint(ctx context.Context, network string, backend *ethereum.Backend) (uint64, error) {
+	if *backend == nil {
+		if !b.dial {
+			return 0, errors.New("endpoint not connected")
+		}
+
+		dialed, err := ethereum.CreateDialedBackend(ctx, network)
+		if err != nil {
+			return 0, fmt.Errorf("dial: %w", err)
+		}
+		*backend = dialed
+	}
+
+	chainID, err := b.queryChainID(ctx, *backend)
+	if err != nil {
+		return 0, fmt.Errorf("chain id: %w", err)
+	}
+
+	if chainID.Cmp(b.chainID) != 0 {
+		return 0, fmt.Errorf("%w: reports %d, expected %d", ErrChainIDMismatch, chainID, b.chainID)
+	}
+
+	header, err := (*backend).HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("head block: %w", err)
+	}
+
+	return header.Number.Uint64(), nil
+}
+
+// queryChainID asks a dialed node for its chain id. Other backends report
+// the chain id they were constructed with.
+func (b *Backend) queryChainID(ctx context.Context, backend ethereum.Backend) (*big.Int, error) {
+	if dialed, ok := backend.(*ethereum.DialedBackend); ok {
+		return dialed.Client.ChainID(ctx)
+	}
+
+	return backend.ChainID(), nil
+}
+
+// monitor performs the health checks on the configured interval until the
+// backend is closed.
+func (b *Backend) monitor() {
+	ticker := time.NewTicker(b.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.Check(context.Background())
+
+		case <-b.shutdown:
+			return
+		}
+	}
+}
+
+// closeEndpoints closes every endpoint that supports being closed.
+func (b *Backend) closeEndpoints() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, ep := range b.endpoints {
+		switch c := ep.backend.(type) {
+		case interface{ Close() }:
+			c.Close()
+		case interface{ Close() error }:
+			c.Close()
+		}
+	}
+}
+
+// shouldFailover reports whether the error is a transport level problem
+// that another endpoint may not have. Errors returned by the node itself,
+// like an execution revert, and context cancellations are not retried.
+func shouldFailover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if errors.Is(err, geth.NotFound) || errors.Is(err, bind.ErrNoPendingState) || errors.Is(err, errors.ErrUnsupported) {
+		return false
+	}
+
+	var rpcErr rpc.Error
+	return !errors.As(err, &rpcErr)
+}
@@ -0,0 +1,195 @@
+package failover_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/foundation/backend/failover"
+	geth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// faultyBackend wraps a backend so a test can take it offline.
+type faultyBackend struct {
+	ethereum.Backend
+	network string
+	chainID *big.Int
+	down    atomic.Bool
+}
+
+var errConnRefused = errors.New("connection refused")
+
+func (fb *faultyBackend) Network() string {
+	return fb.network
+}
+
+func (fb *faultyBackend) ChainID() *big.Int {
+	if fb.chainID != nil {
+		return fb.chainID
+	}
+	return fb.Backend.ChainID()
+}
+
+func (fb *faultyBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if fb.down.Load() {
+		return nil, errConnRefused
+	}
+	return fb.Backend.HeaderByNumber(ctx, number)
+}
+
+func (fb *faultyBackend) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	if fb.down.Load() {
+		return nil, errConnRefused
+	}
+	return fb.Backend.BalanceAt(ctx, account, blockNumber)
+}
+
+func (fb *faultyBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	if fb.down.Load() {
+		return 0, errConnRefused
+	}
+	return fb.Backend.PendingNonceAt(ctx, account)
+}
+
+func (fb *faultyBackend) PendingCallContract(ctx context.Context, call geth.CallMsg) ([]byte, error) {
+	if fb.down.Load() {
+		return nil, errConnRefused
+	}
+	return fb.Backend.(bind.PendingContractCaller).PendingCallContract(ctx, call)
+}
+
+func (fb *faultyBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if fb.down.Load() {
+		return errConnRefused
+	}
+	return fb.Backend.SendTransaction(ctx, tx)
+}
+
+// =============================================================================
+
+func TestFailover(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	primary := faultyBackend{Backend: backend, network: "primary"}
+	secondary := faultyBackend{Backend: backend, network: "secondary"}
+
+	fb, err := failover.NewBackend(ctx, failover.Config{}, &primary, &secondary)
+	if err != nil {
+		t.Fatalf("unable to create failover backend: %s", err)
+	}
+
+	if fb.Network() != "primary" {
+		t.Fatalf("should start on the primary endpoint, got %s", fb.Network())
+	}
+
+	clt, err := ethereum.NewClient(fb, backend.PrivateKeys[0])
+	if err != nil {
+		t.Fatalf("unable to create ethereum api: %s", err)
+	}
+
+	// =========================================================================
+
+	primary.down.Store(true)
+
+	if _, err := clt.Balance(ctx); err != nil {
+		t.Fatalf("should be able to read balance through the secondary: %s", err)
+	}
+
+	if fb.Network() != "secondary" {
+		t.Fatalf("should have failed over to the secondary endpoint, got %s", fb.Network())
+	}
+
+	status := fb.Status()
+	if status[0].Healthy || !status[1].Healthy {
+		t.Fatalf("wrong health status, got primary %v secondary %v", status[0].Healthy, status[1].Healthy)
+	}
+
+	// =========================================================================
+
+	const gasLimit = 1600000
+	gasPrice := currency.GWei2Wei(big.NewFloat(39.576))
+	transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, big.NewFloat(0))
+	if err != nil {
+		t.Fatalf("unable to create opts for deploy: %s", err)
+	}
+
+	contractID, tx, _, err := basic.DeployBasic(transOpts, clt.Backend)
+	if err != nil {
+		t.Fatalf("unable to deploy basic: %s", err)
+	}
+
+	if _, err := clt.WaitMined(ctx, tx); err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+
+	testBasic, err := basic.NewBasic(contractID, clt.Backend)
+	if err != nil {
+		t.Fatalf("error creating basic: %s", err)
+	}
+
+	// =========================================================================
+
+	primary.down.Store(false)
+	secondary.down.Store(true)
+
+	if err := fb.Check(ctx); err != nil {
+		t.Fatalf("should have a healthy endpoint: %s", err)
+	}
+
+	if fb.Network() != "primary" {
+		t.Fatalf("should have failed back to the primary endpoint, got %s", fb.Network())
+	}
+
+	callOpts, err := clt.NewCallOpts(ctx)
+	if err != nil {
+		t.Fatalf("unable to create call opts: %s", err)
+	}
+
+	ver, err := testBasic.Version(callOpts)
+	if err != nil {
+		t.Fatalf("should be able to get the version: %s", err)
+	}
+
+	if ver != "1.1" {
+		t.Fatalf("should be able to get the correct version, got %s exp %s", ver, "1.1")
+	}
+
+	// =========================================================================
+
+	primary.down.Store(true)
+
+	if _, err := clt.Balance(ctx); !errors.Is(err, failover.ErrNoHealthyEndpoint) {
+		t.Fatalf("should fail when every endpoint is down, got %v", err)
+	}
+}
+
+func TestChainIDMismatch(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	mainnet := faultyBackend{Backend: backend, network: "mainnet"}
+	other := faultyBackend{Backend: backend, network: "other", chainID: big.NewInt(5)}
+
+	if _, err := failover.NewBackend(ctx, failover.Config{}, &mainnet, &other); !errors.Is(err, failover.ErrChainIDMismatch) {
+		t.Fatalf("should refuse endpoints with different chain ids, got %v", err)
+	}
+}
@@ -0,0 +1,411 @@
+// Package retry provides an ethereum.Backend decorator that retries transient
+// node errors with exponential backoff and enforces a client side request
+// budget.
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/smartcontract/foundation/backend"
+	geth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"golang.org/x/time/rate"
+)
+
+// Config represents the retry and rate limit settings.
+type Config struct {
+
+	// MaxAttempts is the total number of times a call is attempted. A value
+	// of zero defaults to 5.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry. Each subsequent retry
+	// doubles the delay. A value of zero defaults to 100 milliseconds.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between retries. A value of zero defaults to
+	// 5 seconds.
+	MaxDelay time.Duration
+
+	// RequestsPerSecond is the client side budget for calls made to the node,
+	// including retries. A value of zero disables rate limiting.
+	RequestsPerSecond float64
+
+	// Burst is the number of calls that can be made at once before the rate
+	// limit applies. A value of zero defaults to 1.
+	Burst int
+
+	// Retryable decides if an error should be retried. When nil, the
+	// Retryable function from this package is used.
+	Retryable func(err error) bool
+}
+
+// Stats represents the counters maintained by the backend.
+type Stats struct {
+	Calls     uint64 // Calls made by the caller.
+	Attempts  uint64 // Attempts made against the node, including retries.
+	Retries   uint64 // Attempts that were retries of a failed attempt.
+	Throttled uint64 // Attempts that had to wait on the rate limit.
+	Failures  uint64 // Calls that returned an error to the caller.
+}
+
+// =============================================================================
+
+// Backend decorates an ethereum.Backend with retries and rate limiting.
+type Backend struct {
+	ethereum.Backend
+	cfg     Config
+	limiter *rate.Limiter
+
+	calls     atomic.Uint64
+	attempts  atomic.Uint64
+	retries   atomic.Uint64
+	throttled atomic.Uint64
+	failures  atomic.Uint64
+}
+
+// NewBackend constructs a backend that retries the calls made to the
+// specified backend based on the configuration.
+func NewBackend(backend ethereum.Backend, cfg Config) *Backend {
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.BaseDelay == 0 {
+		cfg.BaseDelay = 100 * time.Millisecond
+	}
+	if cfg.MaxDelay == 0 {
+		cfg.MaxDelay = 5 * time.Second
+	}
+	if cfg.Burst == 0 {
+		cfg.Burst = 1
+	}
+	if cfg.Retryable == nil {
+		cfg.Retryable = Retryable
+	}
+
+	b := Backend{
+		Backend: backend,
+		cfg:     cfg,
+	}
+
+	if cfg.RequestsPerSecond > 0 {
+		b.limiter = rate.NewLimiter(rate.Limit(cfg.RequestsPerSecond), cfg.Burst)
+	}
+
+	return &b
+}
+
+// Stats returns a snapshot of the counters.
+func (b *Backend) Stats() Stats {
+	return Stats{
+		Calls:     b.calls.Load(),
+		Attempts:  b.attempts.Load(),
+		Retries:   b.retries.Load(),
+		Throttled: b.throttled.Load(),
+		Failures:  b.failures.Load(),
+	}
+}
+
+// =============================================================================
+
+// CodeAt returns the code of the given account.
+func (b *Backend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return do(ctx, b, func() ([]byte, error) {
+		return b.Backend.CodeAt(ctx, contract, blockNumber)
+	})
+}
+
+// CallContract executes a contract call against the state at the specified
+// block number.
+func (b *Backend) CallContract(ctx context.Context, call geth.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return do(ctx, b, func() ([]byte, error) {
+		return b.Backend.CallContract(ctx, call, blockNumber)
+	})
+}
+
+// PendingCallContract executes a contract call against the pending state.
+func (b *Backend) PendingCallContract(ctx context.Context, call geth.CallMsg) ([]byte, error) {
+	pb, ok := b.Backend.(bind.PendingContractCaller)
+	if !ok {
+		return nil, bind.ErrNoPendingState
+	}
+
+	return do(ctx, b, func() ([]byte, error) {
+		return pb.PendingCallContract(ctx, call)
+	})
+}
+
+// HeaderByNumber returns a block header from the current canonical chain.
+func (b *Backend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return do(ctx, b, func() (*types.Header, error) {
+		return b.Backend.HeaderByNumber(ctx, number)
+	})
+}
+
+// HeaderByHash returns the block header with the given hash.
+func (b *Backend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	cr, ok := b.Backend.(backend.ChainReader)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+
+	return do(ctx, b, func() (*types.Header, error) {
+		return cr.HeaderByHash(ctx, hash)
+	})
+}
+
+// BlockByNumber returns a block from the current canonical chain.
+func (b *Backend) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	cr, ok := b.Backend.(backend.ChainReader)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+
+	return do(ctx, b, func() (*types.Block, error) {
+		return cr.BlockByNumber(ctx, number)
+	})
+}
+
+// PendingCodeAt returns the code of the given account in the pending state.
+func (b *Backend) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return do(ctx, b, func() ([]byte, error) {
+		return b.Backend.PendingCodeAt(ctx, account)
+	})
+}
+
+// PendingNonceAt retrieves the current pending nonce associated with an account.
+func (b *Backend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return do(ctx, b, func() (uint64, error) {
+		return b.Backend.PendingNonceAt(ctx, account)
+	})
+}
+
+// SuggestGasPrice retrieves the currently suggested gas price.
+func (b *Backend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return do(ctx, b, func() (*big.Int, error) {
+		return b.Backend.SuggestGasPrice(ctx)
+	})
+}
+
+// SuggestGasTipCap retrieves the currently suggested gas tip cap.
+func (b *Backend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return do(ctx, b, func() (*big.Int, error) {
+		return b.Backend.SuggestGasTipCap(ctx)
+	})
+}
+
+// EstimateGas estimates the gas needed to execute the specified call.
+func (b *Backend) EstimateGas(ctx context.Context, call geth.CallMsg) (uint64, error) {
+	return do(ctx, b, func() (uint64, error) {
+		return b.Backend.EstimateGas(ctx, call)
+	})
+}
+
+// SendTransaction injects the signed transaction into the pending pool. A
+// retried send can find the node already knows about the transaction from
+// the failed attempt, which is treated as success.
+func (b *Backend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	var attempt int
+
+	_, err := do(ctx, b, func() (struct{}, error) {
+		attempt++
+
+		err := b.Backend.SendTransaction(ctx, tx)
+		if err != nil && attempt > 1 && strings.Contains(err.Error(), "already known") {
+			return struct{}{}, nil
+		}
+		return struct{}{}, err
+	})
+
+	return err
+}
+
+// FilterLogs executes a filter query.
+func (b *Backend) FilterLogs(ctx context.Context, query geth.FilterQuery) ([]types.Log, error) {
+	return do(ctx, b, func() ([]types.Log, error) {
+		return b.Backend.FilterLogs(ctx, query)
+	})
+}
+
+// SubscribeFilterLogs subscribes to the results of a streaming filter query.
+// Only establishing the subscription is retried.
+func (b *Backend) SubscribeFilterLogs(ctx context.Context, query geth.FilterQuery, ch chan<- types.Log) (geth.Subscription, error) {
+	return do(ctx, b, func() (geth.Subscription, error) {
+		return b.Backend.SubscribeFilterLogs(ctx, query, ch)
+	})
+}
+
+// TransactionReceipt returns the receipt of a mined transaction.
+func (b *Backend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return do(ctx, b, func() (*types.Receipt, error) {
+		return b.Backend.TransactionReceipt(ctx, txHash)
+	})
+}
+
+// TransactionByHash returns the transaction with the given hash.
+func (b *Backend) TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+	type result struct {
+		tx        *types.Transaction
+		isPending bool
+	}
+
+	r, err := do(ctx, b, func() (result, error) {
+		tx, isPending, err := b.Backend.TransactionByHash(ctx, txHash)
+		return result{tx: tx, isPending: isPending}, err
+	})
+
+	return r.tx, r.isPending, err
+}
+
+// BalanceAt returns the wei balance of the given account.
+func (b *Backend) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return do(ctx, b, func() (*big.Int, error) {
+		return b.Backend.BalanceAt(ctx, account, blockNumber)
+	})
+}
+
+// =============================================================================
+
+// Retryable reports whether the error is likely transient. Network failures,
+// HTTP 429 and 5xx responses, and node errors about rate limits or missing
+// state that is still being imported are retried. Errors about the call
+// itself, like a revert or a bad nonce, are not.
+func Retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, geth.NotFound) || errors.Is(err, bind.ErrNoPendingState) {
+		return false
+	}
+
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.ErrorCode() {
+		case -32005, -32002:
+			return true
+		}
+
+		msg := strings.ToLower(rpcErr.Error())
+		for _, s := range []string{"rate limit", "too many requests", "header not found", "busy", "timeout"} {
+			if strings.Contains(msg, s) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// do executes the function, retrying retryable errors with exponential
+// backoff and jitter while honoring the rate limit.
+func do[T any](ctx context.Context, b *Backend, fn func() (T, error)) (T, error) {
+	b.calls.Add(1)
+
+	var v T
+	var err error
+
+	for attempt := 0; attempt < b.cfg.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			if sErr := sleep(ctx, b.backoff(attempt)); sErr != nil {
+				err = sErr
+				break
+			}
+
+			b.retries.Add(1)
+		}
+
+		if wErr := b.wait(ctx); wErr != nil {
+			err = wErr
+			break
+		}
+
+		b.attempts.Add(1)
+
+		v, err = fn()
+		if err == nil {
+			return v, nil
+		}
+
+		if !b.cfg.Retryable(err) {
+			break
+		}
+	}
+
+	b.failures.Add(1)
+
+	return v, err
+}
+
+// wait blocks until the rate limit allows another attempt.
+func (b *Backend) wait(ctx context.Context) error {
+	if b.limiter == nil {
+		return nil
+	}
+
+	if b.limiter.Allow() {
+		return nil
+	}
+
+	b.throttled.Add(1)
+
+	return b.limiter.Wait(ctx)
+}
+
+// backoff calculates the delay before the specified attempt using full
+// jitter: a random duration between zero and the exponential delay.
+func (b *Backend) backoff(attempt int) time.Duration {
+	delay := b.cfg.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > b.cfg.MaxDelay {
+		delay = b.cfg.MaxDelay
+	}
+
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// sleep waits for the duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,128 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/smartcontract/foundation/backend/retry"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// flakyBackend fails the configured number of balance calls before passing
+// the call through to the wrapped backend.
+type flakyBackend struct {
+	ethereum.Backend
+	failures int
+	err      error
+	calls    int
+}
+
+func (fb *flakyBackend) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	fb.calls++
+	if fb.calls <= fb.failures {
+		return nil, fb.err
+	}
+	return fb.Backend.BalanceAt(ctx, account, blockNumber)
+}
+
+// =============================================================================
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	cfg := retry.Config{
+		MaxAttempts: 4,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    5 * time.Millisecond,
+	}
+
+	// =========================================================================
+
+	flaky := flakyBackend{Backend: backend, failures: 2, err: io.ErrUnexpectedEOF}
+	rb := retry.NewBackend(&flaky, cfg)
+
+	clt, err := ethereum.NewClient(rb, backend.PrivateKeys[0])
+	if err != nil {
+		t.Fatalf("unable to create ethereum api: %s", err)
+	}
+
+	if _, err := clt.Balance(ctx); err != nil {
+		t.Fatalf("should be able to read balance after retries: %s", err)
+	}
+
+	stats := rb.Stats()
+	if stats.Calls != 1 || stats.Attempts != 3 || stats.Retries != 2 || stats.Failures != 0 {
+		t.Fatalf("wrong stats, got %+v", stats)
+	}
+
+	// =========================================================================
+
+	errRevert := errors.New("execution reverted")
+	flaky = flakyBackend{Backend: backend, failures: 1, err: errRevert}
+	rb = retry.NewBackend(&flaky, cfg)
+
+	if _, err := rb.BalanceAt(ctx, common.Address{}, nil); !errors.Is(err, errRevert) {
+		t.Fatalf("should not retry a non transient error, got %v", err)
+	}
+
+	stats = rb.Stats()
+	if stats.Attempts != 1 || stats.Failures != 1 {
+		t.Fatalf("wrong stats, got %+v", stats)
+	}
+
+	// =========================================================================
+
+	flaky = flakyBackend{Backend: backend, failures: 10, err: io.EOF}
+	rb = retry.NewBackend(&flaky, cfg)
+
+	if _, err := rb.BalanceAt(ctx, common.Address{}, nil); !errors.Is(err, io.EOF) {
+		t.Fatalf("should return the last error once attempts run out, got %v", err)
+	}
+
+	if stats := rb.Stats(); stats.Attempts != uint64(cfg.MaxAttempts) {
+		t.Fatalf("wrong number of attempts, got %d exp %d", stats.Attempts, cfg.MaxAttempts)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	cfg := retry.Config{
+		RequestsPerSecond: 100,
+		Burst:             1,
+	}
+	rb := retry.NewBackend(backend, cfg)
+
+	const calls = 5
+	for range calls {
+		if _, err := rb.BalanceAt(ctx, common.Address{}, nil); err != nil {
+			t.Fatalf("should be able to read balance: %s", err)
+		}
+	}
+
+	stats := rb.Stats()
+	if stats.Throttled == 0 {
+		t.Fatalf("should have throttled calls beyond the burst, got %+v", stats)
+	}
+
+	if stats.Calls != calls || stats.Attempts != calls {
+		t.Fatalf("wrong stats, got %+v", stats)
+	}
+}
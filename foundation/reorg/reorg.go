@@ -0,0 +1,384 @@
+// Package reorg provides support for processing contract logs safely across
+// chain reorganizations. Recent block hashes are tracked so state derived
+// from logs that leave the canonical chain can be rolled back and the new
+// canonical branch replayed.
+package reorg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+
+	geth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Handler is implemented by the consumer of the logs. Apply is called for
+// each log in canonical order. Revert is called for a log that is no longer
+// canonical, newest first, so the handler can undo exactly what Apply did.
+type Handler interface {
+	Apply(log types.Log) error
+	Revert(log types.Log) error
+}
+
+// Backend represents the node behaviour the processor needs. Any
+// bind.ContractBackend, including ethereum.Backend, satisfies it.
+type Backend interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	FilterLogs(ctx context.Context, query geth.FilterQuery) ([]types.Log, error)
+	SubscribeFilterLogs(ctx context.Context, query geth.FilterQuery, ch chan<- types.Log) (geth.Subscription, error)
+}
+
+// Config represents the settings for the processor.
+type Config struct {
+
+	// Depth is the number of recent blocks whose hashes are tracked. A
+	// reorg deeper than this is treated as starting at the oldest tracked
+	// block. A value of zero defaults to 64.
+	Depth uint64
+
+	// SyncInterval is how often Run checks the tracked hashes against the
+	// chain while the subscription is live. A value of zero defaults to
+	// 5 seconds.
+	SyncInterval time.Duration
+}
+
+// Stats represents the counters maintained by the processor.
+type Stats struct {
+	Applied  uint64 // Logs applied.
+	Reverted uint64 // Logs reverted.
+	Reorgs   uint64 // Reorgs detected.
+}
+
+// =============================================================================
+
+// Processor feeds logs to a handler, rolling back and replaying when the
+// chain reorganizes.
+type Processor struct {
+	backend Backend
+	query   geth.FilterQuery
+	handler Handler
+	cfg     Config
+
+	mu      sync.Mutex
+	hashes  map[uint64]common.Hash
+	logs    map[uint64][]types.Log
+	head    uint64
+	started bool
+	stats   Stats
+}
+
+// New constructs a processor for the logs matching the query. The query's
+// FromBlock is where the first Sync starts; when nil it starts at the head
+// of the chain. The query's ToBlock and BlockHash are ignored.
+func New(backend Backend, query geth.FilterQuery, handler Handler, cfg Config) *Processor {
+	if cfg.Depth == 0 {
+		cfg.Depth = 64
+	}
+
+	if cfg.SyncInterval == 0 {
+		cfg.SyncInterval = 5 * time.Second
+	}
+
+	query.ToBlock = nil
+	query.BlockHash = nil
+
+	p := Processor{
+		backend: backend,
+		query:   query,
+		handler: handler,
+		cfg:     cfg,
+		hashes:  make(map[uint64]common.Hash),
+		logs:    make(map[uint64][]types.Log),
+	}
+
+	return &p
+}
+
+// Run subscribes to the logs and processes them until the context is
+// cancelled or the subscription fails. Sync is called before the
+// subscription is consumed and then every SyncInterval, so reorgs that the
+// subscription does not report are still detected.
+func (p *Processor) Run(ctx context.Context) error {
+	ch := make(chan types.Log, 100)
+
+	sub, err := p.backend.SubscribeFilterLogs(ctx, p.query, ch)
+	if err != nil {
+		return fmt.Errorf("subscribe: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	if err := p.Sync(ctx); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(p.cfg.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case log := <-ch:
+			if err := p.Handle(ctx, log); err != nil {
+				return err
+			}
+
+		case <-ticker.C:
+			if err := p.Sync(ctx); err != nil {
+				return err
+			}
+
+		case err := <-sub.Err():
+			return fmt.Errorf("subscription: %w", err)
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Handle processes a single log as delivered by a subscription. A removed
+// log rolls back its block and every block after it. A log from a block
+// whose hash differs from the tracked one, or that arrives after later
+// blocks were processed, rolls back to its block and replays the canonical
+// logs from there. Logs that were already applied are ignored.
+func (p *Processor) Handle(ctx context.Context, log types.Log) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if log.Removed {
+		if !p.applied(log) {
+			return nil
+		}
+
+		p.stats.Reorgs++
+		return p.rollback(log.BlockNumber)
+	}
+
+	if p.applied(log) {
+		return nil
+	}
+
+	hash, tracked := p.hashes[log.BlockNumber]
+
+	switch {
+	case tracked && hash != log.BlockHash:
+		p.stats.Reorgs++
+		return p.replay(ctx, log.BlockNumber)
+
+	case log.BlockNumber < p.head:
+		return p.replay(ctx, log.BlockNumber)
+	}
+
+	return p.apply(log)
+}
+
+// Sync compares the tracked block hashes with the chain, rolls back to the
+// most recent block that is still canonical and applies the logs from there
+// to the head of the chain.
+func (p *Processor) Sync(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.started {
+		from, err := p.start(ctx)
+		if err != nil {
+			return err
+		}
+		p.started = true
+
+		return p.replay(ctx, from)
+	}
+
+	numbers := p.tracked()
+	if len(numbers) == 0 {
+		return p.replay(ctx, p.head+1)
+	}
+
+	// Walk back from the newest tracked block to find the fork point. If
+	// none of the tracked blocks are canonical, everything tracked is
+	// replayed and older blocks are considered final.
+	fork := numbers[0] - 1
+	for i := len(numbers) - 1; i >= 0; i-- {
+		number := numbers[i]
+
+		header, err := p.backend.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil && !errors.Is(err, geth.NotFound) {
+			return fmt.Errorf("header %d: %w", number, err)
+		}
+
+		if header != nil && header.Hash() == p.hashes[number] {
+			fork = number
+			break
+		}
+	}
+
+	if numbers[len(numbers)-1] > fork {
+		p.stats.Reorgs++
+	}
+
+	return p.replay(ctx, fork+1)
+}
+
+// Head returns the highest block processed.
+func (p *Processor) Head() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.head
+}
+
+// Stats returns a snapshot of the counters.
+func (p *Processor) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.stats
+}
+
+// =============================================================================
+
+// start returns the block the first sync begins at.
+func (p *Processor) start(ctx context.Context) (uint64, error) {
+	if p.query.FromBlock != nil {
+		return p.query.FromBlock.Uint64(), nil
+	}
+
+	header, err := p.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("head block: %w", err)
+	}
+
+	return header.Number.Uint64(), nil
+}
+
+// replay rolls back every block from the specified block on and applies
+// the canonical logs from that block to the head of the chain. The head
+// block's hash is tracked even if it holds no logs so the next sync can
+// find the fork point.
+func (p *Processor) replay(ctx context.Context, from uint64) error {
+	if err := p.rollback(from); err != nil {
+		return err
+	}
+
+	header, err := p.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("head block: %w", err)
+	}
+	to := header.Number.Uint64()
+
+	if from > to {
+		return nil
+	}
+
+	query := p.query
+	query.FromBlock = new(big.Int).SetUint64(from)
+	query.ToBlock = new(big.Int).SetUint64(to)
+
+	logs, err := p.backend.FilterLogs(ctx, query)
+	if err != nil {
+		return fmt.Errorf("filter logs %d-%d: %w", from, to, err)
+	}
+
+	for _, log := range logs {
+		if log.Removed || p.applied(log) {
+			continue
+		}
+
+		if err := p.apply(log); err != nil {
+			return err
+		}
+	}
+
+	if _, tracked := p.hashes[to]; !tracked && to >= p.head {
+		p.hashes[to] = header.Hash()
+		p.head = to
+		p.prune()
+	}
+
+	return nil
+}
+
+// apply hands the log to the handler and tracks its block.
+func (p *Processor) apply(log types.Log) error {
+	if err := p.handler.Apply(log); err != nil {
+		return fmt.Errorf("apply block %d log %d: %w", log.BlockNumber, log.Index, err)
+	}
+	p.stats.Applied++
+
+	p.hashes[log.BlockNumber] = log.BlockHash
+	p.logs[log.BlockNumber] = append(p.logs[log.BlockNumber], log)
+	p.head = max(p.head, log.BlockNumber)
+	p.prune()
+
+	return nil
+}
+
+// rollback reverts the logs in every tracked block from the specified block
+// on, newest first, and stops tracking those blocks.
+func (p *Processor) rollback(from uint64) error {
+	numbers := p.tracked()
+
+	for i := len(numbers) - 1; i >= 0 && numbers[i] >= from; i-- {
+		number := numbers[i]
+
+		logs := p.logs[number]
+		for j := len(logs) - 1; j >= 0; j-- {
+			if err := p.handler.Revert(logs[j]); err != nil {
+				return fmt.Errorf("revert block %d log %d: %w", number, logs[j].Index, err)
+			}
+			p.stats.Reverted++
+		}
+
+		delete(p.logs, number)
+		delete(p.hashes, number)
+	}
+
+	if from <= p.head {
+		p.head = max(from, 1) - 1
+	}
+
+	return nil
+}
+
+// applied reports whether the log has been applied and is still tracked.
+func (p *Processor) applied(log types.Log) bool {
+	for _, l := range p.logs[log.BlockNumber] {
+		if l.BlockHash == log.BlockHash && l.Index == log.Index {
+			return true
+		}
+	}
+
+	return false
+}
+
+// prune stops tracking blocks that are deeper than the configured depth.
+// Their logs are considered final.
+func (p *Processor) prune() {
+	if p.head < p.cfg.Depth {
+		return
+	}
+
+	oldest := p.head - p.cfg.Depth
+	for number := range p.hashes {
+		if number < oldest {
+			delete(p.hashes, number)
+			delete(p.logs, number)
+		}
+	}
+}
+
+// tracked returns the tracked block numbers in ascending order.
+func (p *Processor) tracked() []uint64 {
+	numbers := make([]uint64, 0, len(p.hashes))
+	for number := range p.hashes {
+		numbers = append(numbers, number)
+	}
+	slices.Sort(numbers)
+
+	return numbers
+}
@@ -0,0 +1,223 @@
+package reorg_test
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/foundation/reorg"
+	geth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// itemHandler keeps every value set for a key so the test can check that
+// reverts undo exactly what was applied.
+type itemHandler struct {
+	filterer *basic.BasicFilterer
+	values   map[string][]int64
+}
+
+func (h *itemHandler) Apply(log types.Log) error {
+	ev, err := h.filterer.ParseItemSet(log)
+	if err != nil {
+		return err
+	}
+
+	h.values[ev.Key] = append(h.values[ev.Key], ev.Value.Int64())
+
+	return nil
+}
+
+func (h *itemHandler) Revert(log types.Log) error {
+	ev, err := h.filterer.ParseItemSet(log)
+	if err != nil {
+		return err
+	}
+
+	values := h.values[ev.Key]
+	if len(values) == 0 || values[len(values)-1] != ev.Value.Int64() {
+		return fmt.Errorf("revert of %s=%d out of order, have %v", ev.Key, ev.Value, values)
+	}
+
+	h.values[ev.Key] = values[:len(values)-1]
+
+	return nil
+}
+
+func TestReorg(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(1, true, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	clt, err := ethereum.NewClient(backend, backend.PrivateKeys[0])
+	if err != nil {
+		t.Fatalf("unable to create ethereum api: %s", err)
+	}
+
+	// =========================================================================
+
+	const gasLimit = 1600000
+	valueGwei := big.NewFloat(0.0)
+	gasPrice := currency.GWei2Wei(big.NewFloat(39.576))
+
+	transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+	if err != nil {
+		t.Fatalf("unable to create opts for deploy: %s", err)
+	}
+
+	contractID, tx, testBasic, err := basic.DeployBasic(transOpts, clt.Backend)
+	if err != nil {
+		t.Fatalf("unable to deploy basic: %s", err)
+	}
+
+	if _, err := clt.WaitMined(ctx, tx); err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+
+	// setItem mines a block setting the key. The nonce is read from the
+	// canonical state since the pool does not rewind when the chain is forked.
+	setItem := func(key string, value int64) *types.Receipt {
+		t.Helper()
+
+		transOpts, err := clt.NewTransactOpts(ctx, gasLimit, gasPrice, valueGwei)
+		if err != nil {
+			t.Fatalf("unable to create transaction opts for setItems: %s", err)
+		}
+
+		nonce, err := backend.NonceAt(ctx, clt.Address(), nil)
+		if err != nil {
+			t.Fatalf("unable to read nonce: %s", err)
+		}
+		transOpts.Nonce = new(big.Int).SetUint64(nonce)
+
+		tx, err := testBasic.SetItem(transOpts, key, big.NewInt(value))
+		if err != nil {
+			t.Fatalf("should be able to set item: %s", err)
+		}
+
+		receipt, err := clt.WaitMined(ctx, tx)
+		if err != nil {
+			t.Fatalf("waiting for set item: %s", err)
+		}
+
+		return receipt
+	}
+
+	// fork rewinds the chain so the next block is mined on top of the block
+	// holding the receipt.
+	fork := func(receipt *types.Receipt) {
+		t.Helper()
+
+		if err := backend.Fork(receipt.BlockHash); err != nil {
+			t.Fatalf("should be able to fork: %s", err)
+		}
+	}
+
+	filterer, err := basic.NewBasicFilterer(contractID, backend)
+	if err != nil {
+		t.Fatalf("error creating filterer: %s", err)
+	}
+
+	handler := itemHandler{
+		filterer: filterer,
+		values:   make(map[string][]int64),
+	}
+
+	query := geth.FilterQuery{
+		FromBlock: big.NewInt(0),
+		Addresses: []common.Address{contractID},
+	}
+
+	p := reorg.New(backend, query, &handler, reorg.Config{})
+
+	check := func(what string, exp ...int64) {
+		t.Helper()
+
+		if !slices.Equal(handler.values["bill"], exp) {
+			t.Fatalf("%s: wrong values for bill, got %v exp %v", what, handler.values["bill"], exp)
+		}
+	}
+
+	// =========================================================================
+
+	base := setItem("bill", 1)
+	setItem("bill", 2)
+
+	if err := p.Sync(ctx); err != nil {
+		t.Fatalf("should be able to sync: %s", err)
+	}
+	check("initial sync", 1, 2)
+
+	// The block holding bill=2 is replaced by one holding bill=9 and the
+	// chain grows past it.
+	fork(base)
+	setItem("bill", 9)
+	setItem("jill", 1)
+
+	if err := p.Sync(ctx); err != nil {
+		t.Fatalf("should be able to sync: %s", err)
+	}
+	check("sync after reorg", 1, 9)
+
+	if p.Stats().Reorgs != 1 {
+		t.Fatalf("should detect one reorg, got %d", p.Stats().Reorgs)
+	}
+
+	if len(handler.values["jill"]) != 1 {
+		t.Fatalf("should apply the rest of the canonical branch, got %v", handler.values["jill"])
+	}
+
+	// =========================================================================
+
+	// A subscription reports the logs of a replaced block as removed.
+	logs, err := backend.FilterLogs(ctx, geth.FilterQuery{
+		FromBlock: new(big.Int).Add(base.BlockNumber, big.NewInt(1)),
+		Addresses: []common.Address{contractID},
+	})
+	if err != nil {
+		t.Fatalf("should be able to filter logs: %s", err)
+	}
+
+	for i := len(logs) - 1; i >= 0; i-- {
+		removed := logs[i]
+		removed.Removed = true
+
+		if err := p.Handle(ctx, removed); err != nil {
+			t.Fatalf("should be able to handle removed log: %s", err)
+		}
+	}
+	check("removed log", 1)
+
+	for _, log := range logs {
+		if err := p.Handle(ctx, log); err != nil {
+			t.Fatalf("should be able to handle log: %s", err)
+		}
+	}
+	check("log after removal", 1, 9)
+
+	// =========================================================================
+
+	// A log from a replaced block arrives without the removal being
+	// reported first.
+	fork(base)
+	receipt := setItem("bill", 7)
+
+	if err := p.Handle(ctx, *receipt.Logs[0]); err != nil {
+		t.Fatalf("should be able to handle log: %s", err)
+	}
+	check("log from new branch", 1, 7)
+
+	if len(handler.values["jill"]) != 0 {
+		t.Fatalf("should roll back logs after the fork point, got %v", handler.values["jill"])
+	}
+}
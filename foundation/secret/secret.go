@@ -0,0 +1,189 @@
+// Package secret provides the sources programs read passphrases and API keys
+// from, so they never need to live in source code. Providers are combined
+// with Chain, which asks each one in turn until a secret is found.
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/ardanlabs/smartcontract/foundation/terminal"
+)
+
+// ErrNotFound is returned when a provider doesn't hold the secret.
+var ErrNotFound = errors.New("secret not found")
+
+// Provider returns the value of a named secret.
+type Provider interface {
+	Secret(name string) (string, error)
+}
+
+// =============================================================================
+
+// Chain asks each provider in order and returns the first secret found.
+type Chain []Provider
+
+// Secret implements Provider.
+func (c Chain) Secret(name string) (string, error) {
+	for _, p := range c {
+		value, err := p.Secret(name)
+		switch {
+		case err == nil:
+			return value, nil
+		case !errors.Is(err, ErrNotFound):
+			return "", err
+		}
+	}
+
+	return "", fmt.Errorf("%s: %w", name, ErrNotFound)
+}
+
+// =============================================================================
+
+// Static holds secrets that are already known, such as values given in a
+// config. Empty values are treated as not set.
+type Static map[string]string
+
+// Secret implements Provider.
+func (s Static) Secret(name string) (string, error) {
+	value := s[name]
+	if value == "" {
+		return "", ErrNotFound
+	}
+
+	return value, nil
+}
+
+// =============================================================================
+
+// Env reads secrets from environment variables named by upper casing the
+// secret name and adding the prefix, so "passphrase" with the prefix "BASIC_"
+// is read from BASIC_PASSPHRASE.
+type Env struct {
+	Prefix string
+
+	// LookupEnv reads the variable. When nil, os.LookupEnv is used.
+	LookupEnv func(key string) (string, bool)
+}
+
+// Secret implements Provider.
+func (e Env) Secret(name string) (string, error) {
+	lookup := e.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+
+	value, _ := lookup(e.Prefix + strings.ToUpper(name))
+	if value == "" {
+		return "", ErrNotFound
+	}
+
+	return value, nil
+}
+
+// =============================================================================
+
+// Files reads secrets from files, mapping each secret name to its path.
+type Files map[string]string
+
+// Secret implements Provider.
+func (f Files) Secret(name string) (string, error) {
+	path := f[name]
+	if path == "" {
+		return "", ErrNotFound
+	}
+
+	return ReadFile(path)
+}
+
+// ReadFile reads a secret from the file, dropping a trailing newline. The
+// file must not be accessible by the group or others.
+func ReadFile(path string) (string, error) {
+	if err := CheckPermissions(path); err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read secret: %w", err)
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// CheckPermissions fails when the file can be read or written by anyone
+// other than its owner. The check is skipped on Windows, which doesn't use
+// permission bits.
+func CheckPermissions(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("stat secret: %w", err)
+	}
+
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return fmt.Errorf("permissions %#o on %s are too open, run: chmod 600 %s", perm, path, path)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// Prompt asks for secrets on the terminal with echo turned off. Secrets are
+// only prompted for when the input is a terminal, so scripts fail instead of
+// hanging.
+type Prompt struct {
+
+	// Names limits the secrets that are prompted for. When empty every
+	// secret is prompted for.
+	Names []string
+
+	// In is the terminal to read from. When nil, os.Stdin is used.
+	In *os.File
+
+	// Out receives the prompt. When nil, os.Stderr is used.
+	Out io.Writer
+}
+
+// Secret implements Provider.
+func (p Prompt) Secret(name string) (string, error) {
+	if len(p.Names) > 0 && !slices.Contains(p.Names, name) {
+		return "", ErrNotFound
+	}
+
+	in := p.In
+	if in == nil {
+		in = os.Stdin
+	}
+
+	out := p.Out
+	if out == nil {
+		out = os.Stderr
+	}
+
+	if !terminal.IsTerminal(in) {
+		return "", ErrNotFound
+	}
+
+	fmt.Fprintf(out, "Enter %s: ", strings.ReplaceAll(name, "_", " "))
+
+	value, err := terminal.ReadPassword(in)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", name, err)
+	}
+
+	if value == "" {
+		return "", ErrNotFound
+	}
+
+	return value, nil
+}
@@ -0,0 +1,111 @@
+package secret_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ardanlabs/smartcontract/foundation/secret"
+)
+
+func TestChain(t *testing.T) {
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "passphrase")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0600); err != nil {
+		t.Fatalf("unable to write secret: %s", err)
+	}
+
+	env := map[string]string{"TEST_API_KEY": "from-env"}
+
+	chain := secret.Chain{
+		secret.Env{
+			Prefix:    "TEST_",
+			LookupEnv: func(key string) (string, bool) { v, ok := env[key]; return v, ok },
+		},
+		secret.Files{"passphrase": file},
+		secret.Static{"token": "from-static"},
+	}
+
+	tests := map[string]string{
+		"api_key":    "from-env",
+		"passphrase": "from-file",
+		"token":      "from-static",
+	}
+
+	for name, exp := range tests {
+		got, err := chain.Secret(name)
+		if err != nil {
+			t.Fatalf("should find %s: %s", name, err)
+		}
+		if got != exp {
+			t.Fatalf("wrong value for %s, got %q exp %q", name, got, exp)
+		}
+	}
+
+	if _, err := chain.Secret("missing"); !errors.Is(err, secret.ErrNotFound) {
+		t.Fatalf("should not find a missing secret, got %v", err)
+	}
+
+	// =========================================================================
+
+	// A file others can read is refused rather than skipped.
+	if err := os.Chmod(file, 0644); err != nil {
+		t.Fatalf("unable to change permissions: %s", err)
+	}
+
+	if _, err := chain.Secret("passphrase"); err == nil || errors.Is(err, secret.ErrNotFound) {
+		t.Fatalf("should refuse a file with open permissions, got %v", err)
+	}
+}
+
+func TestVault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.json")
+
+	v, err := secret.OpenVault(path, "correct horse")
+	if err != nil {
+		t.Fatalf("should be able to create vault: %s", err)
+	}
+
+	v.Set("passphrase", "123")
+	v.Set("coinmarketcap_key", "abc")
+	v.Delete("coinmarketcap_key")
+
+	if err := v.Save(); err != nil {
+		t.Fatalf("should be able to save vault: %s", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("should be able to stat vault: %s", err)
+	}
+
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Fatalf("vault should only be readable by the owner, got %#o", perm)
+	}
+
+	// =========================================================================
+
+	if _, err := secret.OpenVault(path, "wrong"); !errors.Is(err, secret.ErrWrongPassword) {
+		t.Fatalf("should refuse the wrong password, got %v", err)
+	}
+
+	vf := secret.VaultFile{
+		Path:     path,
+		Password: secret.Static{secret.VaultPassword: "correct horse"},
+	}
+
+	got, err := vf.Secret("passphrase")
+	if err != nil {
+		t.Fatalf("should read the passphrase from the vault: %s", err)
+	}
+
+	if got != "123" {
+		t.Fatalf("wrong passphrase, got %q", got)
+	}
+
+	if _, err := vf.Secret("coinmarketcap_key"); !errors.Is(err, secret.ErrNotFound) {
+		t.Fatalf("deleted secret should not be found, got %v", err)
+	}
+}
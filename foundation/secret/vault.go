@@ -0,0 +1,248 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ErrWrongPassword is returned when the vault can't be decrypted.
+var ErrWrongPassword = errors.New("wrong vault password or corrupted vault")
+
+// Set of scrypt parameters used for new vaults.
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// vaultFile represents the vault as stored on disk. The secrets are kept as
+// a JSON object sealed with AES-256-GCM under a key derived from the
+// password with scrypt.
+type vaultFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	N       int    `json:"n"`
+	R       int    `json:"r"`
+	P       int    `json:"p"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// Vault is a password protected file of secrets that needs no OS keyring.
+type Vault struct {
+	path     string
+	password string
+	salt     []byte
+	secrets  map[string]string
+}
+
+// OpenVault decrypts the vault at the path. A vault that doesn't exist yet
+// is returned empty and is created by Save.
+func OpenVault(path string, password string) (*Vault, error) {
+	if password == "" {
+		return nil, errors.New("vault password is required")
+	}
+
+	v := Vault{
+		path:     path,
+		password: password,
+		secrets:  make(map[string]string),
+	}
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return &v, nil
+	}
+
+	if err := CheckPermissions(path); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read vault: %w", err)
+	}
+
+	var vf vaultFile
+	if err := json.Unmarshal(data, &vf); err != nil {
+		return nil, fmt.Errorf("decode vault: %w", err)
+	}
+
+	if vf.Version != 1 {
+		return nil, fmt.Errorf("unsupported vault version %d", vf.Version)
+	}
+
+	gcm, err := newGCM(password, vf.Salt, vf.N, vf.R, vf.P)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := gcm.Open(nil, vf.Nonce, vf.Data, nil)
+	if err != nil {
+		return nil, ErrWrongPassword
+	}
+
+	if err := json.Unmarshal(plain, &v.secrets); err != nil {
+		return nil, fmt.Errorf("decode vault secrets: %w", err)
+	}
+	v.salt = vf.Salt
+
+	return &v, nil
+}
+
+// Secret implements Provider.
+func (v *Vault) Secret(name string) (string, error) {
+	value, exists := v.secrets[name]
+	if !exists {
+		return "", ErrNotFound
+	}
+
+	return value, nil
+}
+
+// Names returns the names of the secrets in the vault.
+func (v *Vault) Names() []string {
+	names := make([]string, 0, len(v.secrets))
+	for name := range v.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Set adds or replaces a secret. Call Save to store the change.
+func (v *Vault) Set(name string, value string) {
+	v.secrets[name] = value
+}
+
+// Delete removes a secret. Call Save to store the change.
+func (v *Vault) Delete(name string) {
+	delete(v.secrets, name)
+}
+
+// Save encrypts the vault and writes it, readable by the owner only. The
+// file is replaced atomically so a failed write never loses the vault.
+func (v *Vault) Save() error {
+	if v.salt == nil {
+		v.salt = make([]byte, 32)
+		if _, err := rand.Read(v.salt); err != nil {
+			return fmt.Errorf("generate salt: %w", err)
+		}
+	}
+
+	gcm, err := newGCM(v.password, v.salt, scryptN, scryptR, scryptP)
+	if err != nil {
+		return err
+	}
+
+	plain, err := json.Marshal(v.secrets)
+	if err != nil {
+		return fmt.Errorf("encode vault secrets: %w", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("generate nonce: %w", err)
+	}
+
+	vf := vaultFile{
+		Version: 1,
+		Salt:    v.salt,
+		N:       scryptN,
+		R:       scryptR,
+		P:       scryptP,
+		Nonce:   nonce,
+		Data:    gcm.Seal(nil, nonce, plain, nil),
+	}
+
+	data, err := json.MarshalIndent(vf, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode vault: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(v.path), 0700); err != nil {
+		return fmt.Errorf("create vault dir: %w", err)
+	}
+
+	tmp := v.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("write vault: %w", err)
+	}
+
+	if err := os.Rename(tmp, v.path); err != nil {
+		return fmt.Errorf("replace vault: %w", err)
+	}
+
+	return nil
+}
+
+// newGCM derives the key from the password and constructs the cipher.
+func newGCM(password string, salt []byte, n int, r int, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(password), salt, n, r, p, 32)
+	if err != nil {
+		return nil, fmt.Errorf("derive vault key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("new cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// =============================================================================
+
+// VaultFile provides the secrets in a vault, only opening it the first time
+// a secret is asked for so the password isn't requested unless needed.
+type VaultFile struct {
+
+	// Path to the vault. When empty or missing, no secrets are found.
+	Path string
+
+	// Password provides the vault password under the name "vault_password".
+	Password Provider
+
+	once  sync.Once
+	vault *Vault
+	err   error
+}
+
+// VaultPassword is the name the vault password is requested under.
+const VaultPassword = "vault_password"
+
+// Secret implements Provider.
+func (vf *VaultFile) Secret(name string) (string, error) {
+	if vf.Path == "" {
+		return "", ErrNotFound
+	}
+
+	if _, err := os.Stat(vf.Path); errors.Is(err, os.ErrNotExist) {
+		return "", ErrNotFound
+	}
+
+	vf.once.Do(func() {
+		var password string
+		if password, vf.err = vf.Password.Secret(VaultPassword); vf.err != nil {
+			vf.err = fmt.Errorf("open vault %s: %w", vf.Path, vf.err)
+			return
+		}
+		vf.vault, vf.err = OpenVault(vf.Path, password)
+	})
+
+	if vf.err != nil {
+		return "", vf.err
+	}
+
+	return vf.vault.Secret(name)
+}
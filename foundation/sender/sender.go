@@ -0,0 +1,377 @@
+// Package sender provides a pool of accounts that spreads transactions
+// across every key in a keystore, so writes are not limited by the
+// sequential nonce of a single account.
+package sender
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrNoAccounts is returned when a pool is constructed without any keys.
+var ErrNoAccounts = errors.New("no accounts provided")
+
+// ErrUnknownSender is returned when waiting on a transaction that was not
+// sent by an account in the pool.
+var ErrUnknownSender = errors.New("transaction not sent by the pool")
+
+// Strategy decides which account sends the next transaction.
+type Strategy int
+
+// Set of strategies for assigning transactions to accounts.
+const (
+	RoundRobin   Strategy = iota // Each account in turn.
+	LeastPending                 // The account with the fewest unmined transactions.
+)
+
+// ParseStrategy converts the name of a strategy to its value.
+func ParseStrategy(name string) (Strategy, error) {
+	switch name {
+	case "round-robin":
+		return RoundRobin, nil
+	case "least-pending":
+		return LeastPending, nil
+	}
+
+	return 0, fmt.Errorf("unknown strategy %q", name)
+}
+
+// String returns the name of the strategy.
+func (s Strategy) String() string {
+	switch s {
+	case RoundRobin:
+		return "round-robin"
+	case LeastPending:
+		return "least-pending"
+	}
+
+	return "unknown"
+}
+
+// Status represents the state of an account in the pool.
+type Status struct {
+	Address common.Address
+	Nonce   uint64 // Next nonce the pool will use, zero until first use.
+	Pending int    // Transactions sent but not yet waited on.
+	Sent    uint64 // Transactions sent since the pool was constructed.
+}
+
+// =============================================================================
+
+// account represents a single key in the pool. The mutex is held while a
+// transaction is signed and sent so nonces are never handed out twice, but
+// waiting for a transaction to be mined happens outside of it.
+type account struct {
+	mu     sync.Mutex
+	client *ethereum.Client
+	nonce  uint64
+	synced bool
+
+	// Protected by the pool mutex.
+	pending int
+	sent    uint64
+}
+
+// Pool spreads transactions across a set of accounts.
+type Pool struct {
+	backend  ethereum.Backend
+	strategy Strategy
+
+	mu       sync.Mutex
+	accounts []*account
+	byAddr   map[common.Address]*account
+	next     int
+}
+
+// LoadKeyStore decrypts every key file in the keystore directory using the
+// same pass phrase. Files are loaded in name order, which for geth key files
+// is the order the accounts were created.
+func LoadKeyStore(dir string, passPhrase string) ([]*ecdsa.PrivateKey, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read keystore: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	privateKeys := make([]*ecdsa.PrivateKey, 0, len(names))
+	for _, name := range names {
+		privateKey, err := ethereum.PrivateKeyByKeyFile(filepath.Join(dir, name), passPhrase)
+		if err != nil {
+			return nil, fmt.Errorf("key file %s: %w", name, err)
+		}
+		privateKeys = append(privateKeys, privateKey)
+	}
+
+	if len(privateKeys) == 0 {
+		return nil, ErrNoAccounts
+	}
+
+	return privateKeys, nil
+}
+
+// New constructs a pool that sends transactions from the accounts for the
+// private keys using the specified strategy.
+func New(backend ethereum.Backend, strategy Strategy, privateKeys ...*ecdsa.PrivateKey) (*Pool, error) {
+	if len(privateKeys) == 0 {
+		return nil, ErrNoAccounts
+	}
+
+	p := Pool{
+		backend:  backend,
+		strategy: strategy,
+		byAddr:   make(map[common.Address]*account),
+	}
+
+	for _, privateKey := range privateKeys {
+		clt, err := ethereum.NewClient(backend, privateKey)
+		if err != nil {
+			return nil, fmt.Errorf("new client: %w", err)
+		}
+
+		if _, exists := p.byAddr[clt.Address()]; exists {
+			continue
+		}
+
+		acc := account{
+			client: clt,
+		}
+		p.accounts = append(p.accounts, &acc)
+		p.byAddr[clt.Address()] = &acc
+	}
+
+	return &p, nil
+}
+
+// Addresses returns the addresses of the accounts in the pool.
+func (p *Pool) Addresses() []common.Address {
+	addrs := make([]common.Address, len(p.accounts))
+	for i, acc := range p.accounts {
+		addrs[i] = acc.client.Address()
+	}
+
+	return addrs
+}
+
+// Status returns the state of each account in the pool.
+func (p *Pool) Status() []Status {
+	status := make([]Status, len(p.accounts))
+
+	// An account mutex is held while sending, and sending takes the pool
+	// mutex, so the two are never held together here.
+	for i, acc := range p.accounts {
+		acc.mu.Lock()
+		status[i].Address = acc.client.Address()
+		if acc.synced {
+			status[i].Nonce = acc.nonce
+		}
+		acc.mu.Unlock()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, acc := range p.accounts {
+		status[i].Pending = acc.pending
+		status[i].Sent = acc.sent
+	}
+
+	return status
+}
+
+// Send picks an account, builds transaction options for it with the next
+// nonce for that account and calls send to build and broadcast the
+// transaction, usually by calling a generated contract binding. Sends from
+// different accounts run concurrently. If send fails, the account's nonce is
+// read again from the node before the account is used next.
+func (p *Pool) Send(ctx context.Context, gasLimit uint64, gasPrice *big.Int, valueGWei *big.Float, send func(*bind.TransactOpts) (*types.Transaction, error)) (*types.Transaction, error) {
+	acc := p.pick()
+
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+
+	tx, err := p.send(ctx, acc, 0, gasLimit, gasPrice, valueGWei, send)
+	if err != nil {
+		acc.synced = false
+		p.release(acc)
+		return nil, err
+	}
+	acc.nonce++
+
+	p.mu.Lock()
+	acc.sent++
+	p.mu.Unlock()
+
+	return tx, nil
+}
+
+// DryRun starts a dry run that builds the transactions Send would produce
+// without broadcasting them.
+func (p *Pool) DryRun() *DryRun {
+	d := DryRun{
+		pool:    p,
+		offsets: make(map[*account]uint64),
+	}
+
+	return &d
+}
+
+// WaitMined waits for a transaction sent by the pool to be mined and
+// releases its slot on the sending account.
+func (p *Pool) WaitMined(ctx context.Context, tx *types.Transaction) (*types.Receipt, error) {
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, fmt.Errorf("recovering sender: %w", err)
+	}
+
+	acc, exists := p.byAddr[from]
+	if !exists {
+		return nil, ErrUnknownSender
+	}
+
+	defer p.release(acc)
+
+	return acc.client.WaitMined(ctx, tx)
+}
+
+// =============================================================================
+
+// pick selects the account for the next transaction and counts the
+// transaction against it.
+func (p *Pool) pick() *account {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	idx := p.next % len(p.accounts)
+
+	if p.strategy == LeastPending {
+		for i := 1; i < len(p.accounts); i++ {
+			j := (p.next + i) % len(p.accounts)
+			if p.accounts[j].pending < p.accounts[idx].pending {
+				idx = j
+			}
+		}
+	}
+
+	p.next = idx + 1
+
+	acc := p.accounts[idx]
+	acc.pending++
+
+	return acc
+}
+
+// release removes a transaction from the pending count of the account.
+func (p *Pool) release(acc *account) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if acc.pending > 0 {
+		acc.pending--
+	}
+}
+
+// send builds the transaction options for the account, using the account's
+// next nonce plus the offset, and calls the send function. The account mutex
+// must be held.
+func (p *Pool) send(ctx context.Context, acc *account, offset uint64, gasLimit uint64, gasPrice *big.Int, valueGWei *big.Float, send func(*bind.TransactOpts) (*types.Transaction, error)) (*types.Transaction, error) {
+	if !acc.synced {
+		nonce, err := p.backend.PendingNonceAt(ctx, acc.client.Address())
+		if err != nil {
+			return nil, fmt.Errorf("retrieving next nonce: %w", err)
+		}
+		acc.nonce = nonce
+		acc.synced = true
+	}
+
+	if gasPrice == nil || gasPrice.Sign() == 0 {
+		var err error
+		gasPrice, err = p.backend.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("retrieving suggested gas price: %w", err)
+		}
+	}
+
+	tranOpts, err := bind.NewKeyedTransactorWithChainID(acc.client.PrivateKey(), p.backend.ChainID())
+	if err != nil {
+		return nil, fmt.Errorf("keying transaction: %w", err)
+	}
+
+	// This will convert the GWei value to Wei.
+	gwe2Wei := big.NewInt(0)
+	if valueGWei != nil {
+		big.NewFloat(0).SetPrec(1024).Mul(valueGWei, big.NewFloat(1e9)).Int(gwe2Wei)
+	}
+
+	tranOpts.Context = ctx
+	tranOpts.Nonce = new(big.Int).SetUint64(acc.nonce + offset)
+	tranOpts.Value = gwe2Wei
+	tranOpts.GasLimit = gasLimit
+	tranOpts.GasPrice = gasPrice
+
+	return send(tranOpts)
+}
+
+// =============================================================================
+
+// DryRun builds transactions through the pool without sending them. Each
+// account's nonce is advanced by an offset local to the dry run, so the
+// transactions carry the nonces Send would use and the pool's nonces are
+// the same once the dry run is discarded.
+type DryRun struct {
+	pool *Pool
+
+	mu      sync.Mutex
+	offsets map[*account]uint64
+}
+
+// Build picks an account the way Send does and calls build with transaction
+// options for it that have NoSend set, so the transaction is signed but not
+// broadcast.
+func (d *DryRun) Build(ctx context.Context, gasLimit uint64, gasPrice *big.Int, valueGWei *big.Float, build func(*bind.TransactOpts) (*types.Transaction, error)) (*types.Transaction, error) {
+	p := d.pool
+
+	acc := p.pick()
+	defer p.release(acc)
+
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+
+	d.mu.Lock()
+	offset := d.offsets[acc]
+	d.mu.Unlock()
+
+	tx, err := p.send(ctx, acc, offset, gasLimit, gasPrice, valueGWei, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		opts.NoSend = true
+		return build(opts)
+	})
+	if err != nil {
+		acc.synced = false
+		return nil, err
+	}
+
+	d.mu.Lock()
+	d.offsets[acc] = offset + 1
+	d.mu.Unlock()
+
+	return tx, nil
+}
@@ -0,0 +1,249 @@
+package sender_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/ethereum/currency"
+	"github.com/ardanlabs/smartcontract/app/basic/contract/go/basic"
+	"github.com/ardanlabs/smartcontract/foundation/sender"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const gasLimit = 1600000
+
+var gasPrice = currency.GWei2Wei(big.NewFloat(39.576))
+
+func TestRoundRobin(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(3, false, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	pool, err := sender.New(backend, sender.RoundRobin, backend.PrivateKeys...)
+	if err != nil {
+		t.Fatalf("unable to create pool: %s", err)
+	}
+
+	testBasic := deploy(t, backend, pool)
+
+	// =========================================================================
+
+	const writes = 9
+
+	var wg sync.WaitGroup
+	txs := make([]*types.Transaction, writes)
+	errs := make([]error, writes)
+
+	for i := range writes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			txs[i], errs[i] = pool.Send(ctx, gasLimit, gasPrice, big.NewFloat(0), func(opts *bind.TransactOpts) (*types.Transaction, error) {
+				return testBasic.SetItem(opts, fmt.Sprintf("key%d", i), big.NewInt(int64(i)))
+			})
+		}()
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("should be able to write through the pool: %s", err)
+		}
+	}
+
+	// All nine writes are pending at once and fit in a single block.
+	backend.Commit()
+
+	for _, tx := range txs {
+		if _, err := pool.WaitMined(ctx, tx); err != nil {
+			t.Fatalf("waiting for tx: %s", err)
+		}
+	}
+
+	for i := range writes {
+		item, err := testBasic.Items(nil, fmt.Sprintf("key%d", i))
+		if err != nil {
+			t.Fatalf("should be able to retrieve item: %s", err)
+		}
+
+		if item.Cmp(big.NewInt(int64(i))) != 0 {
+			t.Fatalf("wrong value for key%d, got %s", i, item)
+		}
+	}
+
+	// A dry run gives each account's transactions the nonces Send would use
+	// and leaves the pool's nonces alone.
+	dry := pool.DryRun()
+	nonces := make(map[common.Address][]uint64)
+
+	for range writes {
+		tx, err := dry.Build(ctx, gasLimit, gasPrice, big.NewFloat(0), func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return testBasic.SetItem(opts, "dry", big.NewInt(1))
+		})
+		if err != nil {
+			t.Fatalf("should be able to build through the pool: %s", err)
+		}
+
+		from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if err != nil {
+			t.Fatalf("unable to recover sender: %s", err)
+		}
+		nonces[from] = append(nonces[from], tx.Nonce())
+	}
+
+	for _, status := range pool.Status() {
+		list := nonces[status.Address]
+		if len(list) != 3 {
+			t.Fatalf("dry run should build three transactions for %s, got %d", status.Address, len(list))
+		}
+
+		for i, nonce := range list {
+			if nonce != status.Nonce+uint64(i) {
+				t.Fatalf("wrong dry run nonces for %s, got %v from %d", status.Address, list, status.Nonce)
+			}
+		}
+	}
+
+	// The deploy plus nine writes round robin across three accounts.
+	expSent := []uint64{4, 3, 3}
+
+	for i, status := range pool.Status() {
+		if status.Sent != expSent[i] {
+			t.Fatalf("wrong sent count for account %d, got %d exp %d", i, status.Sent, expSent[i])
+		}
+
+		if status.Pending != 0 {
+			t.Fatalf("account %d should have nothing pending, got %d", i, status.Pending)
+		}
+
+		nonce, err := backend.PendingNonceAt(ctx, status.Address)
+		if err != nil {
+			t.Fatalf("unable to read nonce: %s", err)
+		}
+
+		if status.Nonce != nonce {
+			t.Fatalf("wrong nonce for account %d, got %d exp %d", i, status.Nonce, nonce)
+		}
+	}
+}
+
+func TestLeastPending(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := ethereum.CreateSimulatedBackend(3, false, big.NewInt(100))
+	if err != nil {
+		t.Fatalf("unable to create simulated backend: %s", err)
+	}
+	defer backend.Close()
+
+	pool, err := sender.New(backend, sender.LeastPending, backend.PrivateKeys...)
+	if err != nil {
+		t.Fatalf("unable to create pool: %s", err)
+	}
+
+	testBasic := deploy(t, backend, pool)
+
+	set := func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return testBasic.SetItem(opts, "bill", big.NewInt(1_000_000))
+	}
+
+	// =========================================================================
+
+	// Leave one transaction pending on every account.
+	txs := make([]*types.Transaction, 3)
+	for i := range txs {
+		txs[i], err = pool.Send(ctx, gasLimit, gasPrice, big.NewFloat(0), set)
+		if err != nil {
+			t.Fatalf("should be able to send: %s", err)
+		}
+	}
+
+	backend.Commit()
+
+	if _, err := pool.WaitMined(ctx, txs[1]); err != nil {
+		t.Fatalf("waiting for tx: %s", err)
+	}
+
+	tx, err := pool.Send(ctx, gasLimit, gasPrice, big.NewFloat(0), set)
+	if err != nil {
+		t.Fatalf("should be able to send: %s", err)
+	}
+
+	if from(t, tx) != from(t, txs[1]) {
+		t.Fatal("should send from the account with the fewest pending transactions")
+	}
+
+	// =========================================================================
+
+	// A failed send must not leave a gap in the account's nonces.
+	failed := errors.New("failed")
+
+	_, err = pool.Send(ctx, gasLimit, gasPrice, big.NewFloat(0), func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return nil, failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("should return the send error, got %v", err)
+	}
+
+	for range 3 {
+		tx, err := pool.Send(ctx, gasLimit, gasPrice, big.NewFloat(0), set)
+		if err != nil {
+			t.Fatalf("should be able to send after a failure: %s", err)
+		}
+		backend.Commit()
+
+		if _, err := pool.WaitMined(ctx, tx); err != nil {
+			t.Fatalf("waiting for tx: %s", err)
+		}
+	}
+}
+
+// =============================================================================
+
+func deploy(t *testing.T, backend *ethereum.SimulatedBackend, pool *sender.Pool) *basic.Basic {
+	t.Helper()
+
+	ctx := context.Background()
+
+	var testBasic *basic.Basic
+
+	tx, err := pool.Send(ctx, gasLimit, gasPrice, big.NewFloat(0), func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		_, tx, contract, err := basic.DeployBasic(opts, backend)
+		testBasic = contract
+		return tx, err
+	})
+	if err != nil {
+		t.Fatalf("unable to deploy basic: %s", err)
+	}
+	backend.Commit()
+
+	if _, err := pool.WaitMined(ctx, tx); err != nil {
+		t.Fatalf("waiting for deploy: %s", err)
+	}
+
+	return testBasic
+}
+
+func from(t *testing.T, tx *types.Transaction) string {
+	t.Helper()
+
+	addr, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		t.Fatalf("unable to recover sender: %s", err)
+	}
+
+	return addr.Hex()
+}
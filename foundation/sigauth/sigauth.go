@@ -0,0 +1,416 @@
+// Package sigauth provides HTTP middleware that authenticates requests signed
+// with an Ethereum key. The client signs a canonical form of the request
+// along with a timestamp and nonce, the server recovers the signing address,
+// rejects anything it has seen before, and checks the address has the
+// permission the route requires.
+package sigauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Set of headers carrying the authentication data.
+const (
+	HeaderAddress   = "X-Auth-Address"
+	HeaderTimestamp = "X-Auth-Timestamp"
+	HeaderNonce     = "X-Auth-Nonce"
+	HeaderSignature = "X-Auth-Signature"
+)
+
+// maxNonce is the longest nonce accepted, which bounds the replay cache.
+const maxNonce = 128
+
+// Set of errors returned when a request can't be authenticated.
+var (
+	ErrMissingHeaders = errors.New("missing authentication headers")
+	ErrStale          = errors.New("request timestamp outside the allowed window")
+	ErrReplayed       = errors.New("request nonce already used")
+	ErrAddressChanged = errors.New("signature does not match the claimed address")
+	ErrForbidden      = errors.New("address does not have the required permission")
+	ErrTooManyNonces  = errors.New("too many recent requests, try again later")
+)
+
+// Permission represents an action an address is allowed to perform.
+type Permission string
+
+// Set of permissions. PermAll grants every permission.
+const (
+	PermRead  Permission = "read"
+	PermWrite Permission = "write"
+	PermAll   Permission = "*"
+)
+
+// ReadWrite requires PermRead for safe methods and PermWrite for the rest.
+func ReadWrite(r *http.Request) Permission {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return PermRead
+	default:
+		return PermWrite
+	}
+}
+
+// Payload represents the canonical form of a request that gets signed with
+// ethereum.SignAny. Clients in other languages must produce the same JSON,
+// with the fields in this order.
+type Payload struct {
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	BodyHash  string `json:"body_hash"`
+	Timestamp int64  `json:"timestamp"`
+	Nonce     string `json:"nonce"`
+}
+
+// NewPayload constructs the payload for the request. The path includes the
+// raw query and the body hash is the hex encoded SHA-256 of the body.
+func NewPayload(r *http.Request, body []byte, timestamp int64, nonce string) Payload {
+	sum := sha256.Sum256(body)
+
+	return Payload{
+		Method:    r.Method,
+		Path:      r.URL.RequestURI(),
+		BodyHash:  hex.EncodeToString(sum[:]),
+		Timestamp: timestamp,
+		Nonce:     nonce,
+	}
+}
+
+// Sign adds the authentication headers to the request using the private key.
+// The body is read and replaced so the request can still be sent.
+func Sign(r *http.Request, privateKey *ecdsa.PrivateKey) error {
+	body, err := readBody(r, -1)
+	if err != nil {
+		return err
+	}
+
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return fmt.Errorf("generate nonce: %w", err)
+	}
+
+	payload := NewPayload(r, body, time.Now().Unix(), hex.EncodeToString(nonce[:]))
+
+	signature, err := ethereum.SignAny(payload, privateKey)
+	if err != nil {
+		return fmt.Errorf("sign payload: %w", err)
+	}
+
+	address, err := ethereum.FromAddressAny(payload, signature)
+	if err != nil {
+		return fmt.Errorf("recover address: %w", err)
+	}
+
+	r.Header.Set(HeaderAddress, address)
+	r.Header.Set(HeaderTimestamp, strconv.FormatInt(payload.Timestamp, 10))
+	r.Header.Set(HeaderNonce, payload.Nonce)
+	r.Header.Set(HeaderSignature, signature)
+
+	return nil
+}
+
+// =============================================================================
+
+// Config represents the settings for the authenticator.
+type Config struct {
+
+	// Permissions maps an address to what it is allowed to do. Requests
+	// from addresses not listed are refused before their nonce is recorded,
+	// so nobody can fill the replay cache with fresh keys.
+	Permissions map[common.Address][]Permission
+
+	// MaxSkew is how far the request timestamp can be from the server's
+	// clock. Nonces are remembered for twice this long. A value of zero
+	// defaults to 5 minutes.
+	MaxSkew time.Duration
+
+	// MaxBody caps the size of a request body that will be read to verify
+	// the signature. A value of zero defaults to 1MB.
+	MaxBody int64
+
+	// MaxNonces caps the number of nonces remembered. Requests are refused
+	// while the cache is full of nonces still inside the window. A value of
+	// zero defaults to 100,000.
+	MaxNonces int
+}
+
+// Authenticator verifies signed requests and tracks the nonces it has seen.
+type Authenticator struct {
+	cfg Config
+
+	mu     sync.Mutex
+	perms  map[common.Address]map[Permission]bool
+	nonces map[string]time.Time
+	pruned time.Time
+}
+
+// New constructs an authenticator.
+func New(cfg Config) *Authenticator {
+	if cfg.MaxSkew == 0 {
+		cfg.MaxSkew = 5 * time.Minute
+	}
+
+	if cfg.MaxBody == 0 {
+		cfg.MaxBody = 1 << 20
+	}
+
+	if cfg.MaxNonces == 0 {
+		cfg.MaxNonces = 100_000
+	}
+
+	a := Authenticator{
+		cfg:    cfg,
+		perms:  make(map[common.Address]map[Permission]bool),
+		nonces: make(map[string]time.Time),
+		pruned: time.Now(),
+	}
+
+	for address, perms := range cfg.Permissions {
+		a.Grant(address, perms...)
+	}
+
+	return &a
+}
+
+// Grant adds the permissions for the address.
+func (a *Authenticator) Grant(address common.Address, perms ...Permission) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	set, exists := a.perms[address]
+	if !exists {
+		set = make(map[Permission]bool)
+		a.perms[address] = set
+	}
+
+	for _, perm := range perms {
+		set[perm] = true
+	}
+}
+
+// Revoke removes every permission for the address.
+func (a *Authenticator) Revoke(address common.Address) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.perms, address)
+}
+
+// Allowed reports whether the address has the permission.
+func (a *Authenticator) Allowed(address common.Address, perm Permission) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	set := a.perms[address]
+	return set[perm] || set[PermAll]
+}
+
+// Authenticate verifies the signature on the request and returns the address
+// that signed it. Addresses without any permission are refused with
+// ErrForbidden. The body is read and replaced so handlers can still use it.
+func (a *Authenticator) Authenticate(r *http.Request) (common.Address, error) {
+	timestamp := r.Header.Get(HeaderTimestamp)
+	nonce := r.Header.Get(HeaderNonce)
+	signature := r.Header.Get(HeaderSignature)
+
+	if timestamp == "" || nonce == "" || signature == "" {
+		return common.Address{}, ErrMissingHeaders
+	}
+
+	if len(nonce) > maxNonce {
+		return common.Address{}, fmt.Errorf("nonce longer than %d characters", maxNonce)
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("parse timestamp: %w", err)
+	}
+
+	now := time.Now()
+	if skew := now.Sub(time.Unix(ts, 0)).Abs(); skew > a.cfg.MaxSkew {
+		return common.Address{}, ErrStale
+	}
+
+	body, err := readBody(r, a.cfg.MaxBody)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	recovered, err := ethereum.FromAddressAny(NewPayload(r, body, ts, nonce), signature)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("recover address: %w", err)
+	}
+	address := common.HexToAddress(recovered)
+
+	// A signature over a different payload recovers a different address, so
+	// the claimed address turns tampering into a clear error.
+	if claimed := r.Header.Get(HeaderAddress); claimed != "" && common.HexToAddress(claimed) != address {
+		return common.Address{}, ErrAddressChanged
+	}
+
+	// The nonce is only recorded once the signature checks out and the
+	// address has a permission, so nobody can burn another address's nonces
+	// or grow the cache with keys of their own.
+	if err := a.use(address, nonce, now); err != nil {
+		return common.Address{}, err
+	}
+
+	return address, nil
+}
+
+// Middleware returns middleware that authenticates every request and checks
+// the signer has the permission returned by required.
+func (a *Authenticator) Middleware(required func(r *http.Request) Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		h := func(w http.ResponseWriter, r *http.Request) {
+			address, err := a.Authenticate(r)
+			switch {
+			case errors.Is(err, ErrForbidden):
+				respondError(w, http.StatusForbidden, err)
+				return
+			case errors.Is(err, ErrTooManyNonces):
+				respondError(w, http.StatusTooManyRequests, err)
+				return
+			case err != nil:
+				respondError(w, http.StatusUnauthorized, err)
+				return
+			}
+
+			if !a.Allowed(address, required(r)) {
+				respondError(w, http.StatusForbidden, ErrForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey, address)))
+		}
+
+		return http.HandlerFunc(h)
+	}
+}
+
+// =============================================================================
+
+// use records the nonce for the address, failing if the address has no
+// permissions, the nonce was already used or the cache is full.
+func (a *Authenticator) use(address common.Address, nonce string, now time.Time) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.perms[address]) == 0 {
+		return ErrForbidden
+	}
+
+	window := 2 * a.cfg.MaxSkew
+
+	if now.Sub(a.pruned) > a.cfg.MaxSkew || len(a.nonces) >= a.cfg.MaxNonces {
+		for key, seen := range a.nonces {
+			if now.Sub(seen) > window {
+				delete(a.nonces, key)
+			}
+		}
+		a.pruned = now
+	}
+
+	key := address.Hex() + "/" + nonce
+	if seen, exists := a.nonces[key]; exists && now.Sub(seen) <= window {
+		return ErrReplayed
+	}
+
+	if len(a.nonces) >= a.cfg.MaxNonces {
+		return ErrTooManyNonces
+	}
+	a.nonces[key] = now
+
+	return nil
+}
+
+// =============================================================================
+
+// ctxKeyType is the type of the context key for the authenticated address.
+type ctxKeyType int
+
+// ctxKey is the context key for the authenticated address.
+const ctxKey ctxKeyType = 1
+
+// FromContext returns the address that signed the request.
+func FromContext(ctx context.Context) (common.Address, bool) {
+	address, ok := ctx.Value(ctxKey).(common.Address)
+	return address, ok
+}
+
+// LoadPermissions reads a JSON file mapping addresses to permissions.
+//
+//	{"0x6327A38415C53FFb36c11db55Ea74cc9cB4976Fd": ["read", "write"]}
+func LoadPermissions(path string) (map[common.Address][]Permission, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read permissions: %w", err)
+	}
+
+	var raw map[string][]Permission
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("decode permissions: %w", err)
+	}
+
+	perms := make(map[common.Address][]Permission, len(raw))
+	for address, list := range raw {
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("invalid address %q", address)
+		}
+		perms[common.HexToAddress(address)] = list
+	}
+
+	return perms, nil
+}
+
+// =============================================================================
+
+// readBody reads the request body and replaces it so it can be read again.
+// A negative limit reads the whole body.
+func readBody(r *http.Request, limit int64) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+
+	reader := io.Reader(r.Body)
+	if limit >= 0 {
+		reader = io.LimitReader(r.Body, limit+1)
+	}
+
+	body, err := io.ReadAll(reader)
+	r.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+
+	if limit >= 0 && int64(len(body)) > limit {
+		return nil, fmt.Errorf("body larger than %d bytes", limit)
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
+
+// respondError writes the error as a JSON response.
+func respondError(w http.ResponseWriter, statusCode int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,170 @@
+package sigauth_test
+
+import (
+	"crypto/ecdsa"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/ethereum"
+	"github.com/ardanlabs/smartcontract/foundation/sigauth"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestMiddleware(t *testing.T) {
+	writer, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("unable to generate key: %s", err)
+	}
+
+	reader, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("unable to generate key: %s", err)
+	}
+
+	stranger, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("unable to generate key: %s", err)
+	}
+
+	cfg := sigauth.Config{
+		Permissions: map[common.Address][]sigauth.Permission{
+			crypto.PubkeyToAddress(writer.PublicKey): {sigauth.PermAll},
+		},
+	}
+
+	auth := sigauth.New(cfg)
+	auth.Grant(crypto.PubkeyToAddress(reader.PublicKey), sigauth.PermRead)
+
+	// The handler echoes the caller and the body it received.
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		address, ok := sigauth.FromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte(address.Hex() + " " + string(body)))
+	})
+
+	srv := httptest.NewServer(auth.Middleware(sigauth.ReadWrite)(h))
+	defer srv.Close()
+
+	// =========================================================================
+
+	req := signed(t, http.MethodPut, srv.URL+"/items/bill?x=1", `{"value": "10"}`, writer)
+
+	body := send(t, req, http.StatusOK)
+	exp := crypto.PubkeyToAddress(writer.PublicKey).Hex() + ` {"value": "10"}`
+	if body != exp {
+		t.Fatalf("wrong response, got %q exp %q", body, exp)
+	}
+
+	// Sending the exact same request again is a replay.
+	replay := signed(t, http.MethodPut, srv.URL+"/items/bill?x=1", `{"value": "10"}`, writer)
+	replay.Header = req.Header.Clone()
+	send(t, replay, http.StatusUnauthorized)
+
+	// =========================================================================
+
+	// The reader can read but not write.
+	send(t, signed(t, http.MethodGet, srv.URL+"/items/bill", "", reader), http.StatusOK)
+	send(t, signed(t, http.MethodPut, srv.URL+"/items/bill", `{"value": "11"}`, reader), http.StatusForbidden)
+
+	// An address without permissions is authenticated but can't do anything.
+	send(t, signed(t, http.MethodGet, srv.URL+"/items/bill", "", stranger), http.StatusForbidden)
+
+	// =========================================================================
+
+	// Changing the body after signing is detected.
+	tampered := signed(t, http.MethodPut, srv.URL+"/items/bill", `{"value": "12"}`, writer)
+	tampered.Body = io.NopCloser(strings.NewReader(`{"value": "13"}`))
+	tampered.ContentLength = -1
+	send(t, tampered, http.StatusUnauthorized)
+
+	// Unsigned requests are rejected.
+	unsigned, err := http.NewRequest(http.MethodGet, srv.URL+"/items/bill", nil)
+	if err != nil {
+		t.Fatalf("unable to create request: %s", err)
+	}
+	send(t, unsigned, http.StatusUnauthorized)
+
+	// So are requests signed too long ago.
+	stale, err := http.NewRequest(http.MethodGet, srv.URL+"/items/bill", nil)
+	if err != nil {
+		t.Fatalf("unable to create request: %s", err)
+	}
+
+	ts := time.Now().Add(-time.Hour).Unix()
+	payload := sigauth.NewPayload(stale, nil, ts, "stale")
+
+	signature, err := ethereum.SignAny(payload, writer)
+	if err != nil {
+		t.Fatalf("should be able to sign: %s", err)
+	}
+
+	stale.Header.Set(sigauth.HeaderTimestamp, strconv.FormatInt(ts, 10))
+	stale.Header.Set(sigauth.HeaderNonce, "stale")
+	stale.Header.Set(sigauth.HeaderSignature, signature)
+	send(t, stale, http.StatusUnauthorized)
+
+	// =========================================================================
+
+	// Addresses without permissions don't take space in the nonce cache, so
+	// the writer still gets the two nonces the cache holds.
+	cfg.MaxNonces = 2
+	limited := httptest.NewServer(sigauth.New(cfg).Middleware(sigauth.ReadWrite)(h))
+	defer limited.Close()
+
+	for range 3 {
+		send(t, signed(t, http.MethodGet, limited.URL+"/items/bill", "", stranger), http.StatusForbidden)
+	}
+
+	send(t, signed(t, http.MethodGet, limited.URL+"/items/bill", "", writer), http.StatusOK)
+	send(t, signed(t, http.MethodGet, limited.URL+"/items/bill", "", writer), http.StatusOK)
+	send(t, signed(t, http.MethodGet, limited.URL+"/items/bill", "", writer), http.StatusTooManyRequests)
+}
+
+// =============================================================================
+
+func signed(t *testing.T, method string, url string, body string, privateKey *ecdsa.PrivateKey) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unable to create request: %s", err)
+	}
+
+	if err := sigauth.Sign(req, privateKey); err != nil {
+		t.Fatalf("should be able to sign request: %s", err)
+	}
+
+	return req
+}
+
+func send(t *testing.T, req *http.Request, statusCode int) string {
+	t.Helper()
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("should be able to send request: %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("should be able to read response: %s", err)
+	}
+
+	if resp.StatusCode != statusCode {
+		t.Fatalf("wrong status code for %s %s, got %d exp %d: %s", req.Method, req.URL, resp.StatusCode, statusCode, body)
+	}
+
+	return string(body)
+}
@@ -0,0 +1,64 @@
+// Package terminal provides the small amount of terminal control the basic
+// programs need without depending on a full terminal library.
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrNotTerminal is returned when the file is not attached to a terminal.
+var ErrNotTerminal = errors.New("not a terminal")
+
+// IsTerminal reports whether the file is attached to a terminal.
+func IsTerminal(f *os.File) bool {
+	_, err := getState(int(f.Fd()))
+	return err == nil
+}
+
+// ReadPassword reads a line from the terminal with echo turned off. The
+// trailing newline is not included.
+func ReadPassword(f *os.File) (string, error) {
+	fd := int(f.Fd())
+
+	state, err := getState(fd)
+	if err != nil {
+		return "", ErrNotTerminal
+	}
+
+	if err := setState(fd, noEcho(state)); err != nil {
+		return "", fmt.Errorf("disable echo: %w", err)
+	}
+	defer setState(fd, state)
+
+	return readLine(f)
+}
+
+// readLine reads up to the end of the line one byte at a time, so nothing
+// past the line is consumed from the terminal.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	var b [1]byte
+
+	for {
+		n, err := r.Read(b[:])
+		if n == 1 {
+			switch b[0] {
+			case '\n':
+				return string(line), nil
+			case '\r':
+			default:
+				line = append(line, b[0])
+			}
+		}
+
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(line) > 0 {
+				return string(line), nil
+			}
+			return "", err
+		}
+	}
+}
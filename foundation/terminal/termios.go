@@ -0,0 +1,27 @@
+//go:build linux || darwin
+
+package terminal
+
+import "golang.org/x/sys/unix"
+
+// state holds the terminal settings so they can be restored.
+type state = unix.Termios
+
+func getState(fd int) (*state, error) {
+	return unix.IoctlGetTermios(fd, ioctlGetTermios)
+}
+
+func setState(fd int, s *state) error {
+	return unix.IoctlSetTermios(fd, ioctlSetTermios, s)
+}
+
+// noEcho returns a copy of the settings with echo off but still echoing the
+// newline, so the cursor moves on once the password is entered.
+func noEcho(s *state) *state {
+	c := *s
+	c.Lflag &^= unix.ECHO
+	c.Lflag |= unix.ICANON | unix.ISIG | unix.ECHONL
+	c.Iflag |= unix.ICRNL
+
+	return &c
+}
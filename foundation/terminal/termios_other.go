@@ -0,0 +1,20 @@
+//go:build !linux && !darwin
+
+package terminal
+
+import "errors"
+
+// state is empty on platforms where the terminal can't be controlled.
+type state struct{}
+
+func getState(fd int) (*state, error) {
+	return nil, errors.New("terminal control not supported on this platform")
+}
+
+func setState(fd int, s *state) error {
+	return errors.New("terminal control not supported on this platform")
+}
+
+func noEcho(s *state) *state {
+	return s
+}